@@ -12,9 +12,13 @@ import (
 	"yggdrasil-api-go/src/cache"
 	"yggdrasil-api-go/src/config"
 	"yggdrasil-api-go/src/handlers"
+	"yggdrasil-api-go/src/lockout"
 	"yggdrasil-api-go/src/middleware"
 	storage_factory "yggdrasil-api-go/src/storage"
+	storage "yggdrasil-api-go/src/storage/interface"
+	"yggdrasil-api-go/src/twofactor"
 	"yggdrasil-api-go/src/utils"
+	"yggdrasil-api-go/src/yggdrasil"
 
 	"github.com/gin-gonic/gin"
 )
@@ -32,6 +36,14 @@ func main() {
 
 	log.Printf("✅ Loaded config from: %s", *configPath)
 
+	// 子命令
+	if flag.Arg(0) == "reconcile" {
+		if err := runReconcileCommand(cfg, flag.Args()[1:]); err != nil {
+			log.Fatalf("Reconcile failed: %v", err)
+		}
+		return
+	}
+
 	// 确保密钥对存在（对于非BlessingSkin存储）
 	if cfg.Storage.Type != "blessing_skin" {
 		_, _, err = utils.LoadOrGenerateKeyPair(cfg.Yggdrasil.Keys.PrivateKeyPath, cfg.Yggdrasil.Keys.PublicKeyPath)
@@ -45,6 +57,7 @@ func main() {
 
 	// 设置JWT密钥
 	utils.SetJWTSecret(cfg.Auth.JWTSecret)
+	yggdrasil.SetSessionTTL(cfg.Auth.SessionTTL)
 
 	// 创建存储实例
 	storageFactory := storage_factory.NewStorageFactory()
@@ -56,8 +69,19 @@ func main() {
 
 	log.Printf("✅ Using %s storage", store.GetStorageType())
 
+	// 检查仅大小写不同的重名角色
+	reportProfileNameConflicts(store)
+
 	// 创建缓存实例
 	cacheFactory := cache.NewCacheFactory()
+	defer cacheFactory.Close()
+	cacheFactory.SetUserStorage(store)
+	for name, options := range cfg.Cache.RedisConnections {
+		cacheFactory.RegisterRedisConnection(name, options)
+	}
+	for name, options := range cfg.Cache.DatabaseConnections {
+		cacheFactory.RegisterDatabaseConnection(name, options)
+	}
 	tokenCache, err := cacheFactory.CreateTokenCache(cfg.Cache.Token.Type, cfg.Cache.Token.Options)
 	if err != nil {
 		log.Fatalf("Failed to create token cache: %v", err)
@@ -71,24 +95,63 @@ func main() {
 	log.Printf("✅ Session cache initialized: %s", cfg.Cache.Session.Type)
 
 	// 初始化用户缓存配置
+	cache.InitUserCache(cfg.Cache.User)
 	if cfg.Cache.User.Enabled {
-		cache.InitUserCache(cfg.Cache.User.Duration)
-		log.Printf("✅ User cache initialized: %v duration", cfg.Cache.User.Duration)
+		stats := cache.GlobalUserCache.GetStats()
+		log.Printf("✅ User cache initialized: %d users, %v minutes duration", stats["max_users"], stats["cache_duration_minutes"])
 	} else {
 		log.Printf("ℹ️  User cache disabled")
 	}
 
+	// 初始化响应缓存（LRU，超出容量时淘汰最久未使用的响应）
+	utils.InitResponseCache(cfg.Cache.Response)
+	if cfg.Cache.Response.Enabled {
+		stats := utils.GetCacheStats()["response_cache"].(map[string]any)
+		log.Printf("✅ Response cache initialized: %d entries, %vs duration", stats["max_entries"], stats["ttl_seconds"])
+	}
+
+	// 订阅存储数据变更，使相关缓存失效
+	subscribeCacheInvalidation(store)
+
 	// 缓存预热
 	if err := utils.WarmupCaches(cfg, store); err != nil {
 		log.Printf("⚠️  Cache warmup failed: %v", err)
 	}
 
+	// 初始化两步验证（存储不支持时无法强制两步验证，不能启动）
+	var twoFactorService *twofactor.Service
+	if cfg.Auth.TwoFactor.Enabled {
+		twoFactorService, err = twofactor.New(cfg, store)
+		if err != nil {
+			log.Fatalf("Failed to initialize two-factor authentication: %v", err)
+		}
+		log.Printf("✅ Two-factor authentication enabled (required roles: %v)", cfg.Auth.TwoFactor.RequiredRoles)
+	}
+
+	// 初始化登录失败锁定（记录保存在缓存后端中，共享后端时多个实例共用；未单独配置时使用Session缓存的配置）
+	var lockoutGuard *lockout.Guard
+	var loginAttemptCache cache.LoginAttemptCache
+	if cfg.Auth.Lockout.Enabled {
+		backend := cfg.Cache.LoginAttempts
+		if backend.Type == "" {
+			backend = cfg.Cache.Session
+		}
+		loginAttemptCache, err = cacheFactory.CreateLoginAttemptCache(backend.Type, backend.Options)
+		if err != nil {
+			log.Fatalf("Failed to create login attempt cache: %v", err)
+		}
+		defer loginAttemptCache.Close()
+		lockoutGuard = lockout.New(cfg.Auth.Lockout, loginAttemptCache, store)
+		log.Printf("✅ Account lockout enabled (login attempt cache: %s)", backend.Type)
+	}
+
 	// 创建处理器（直接传入存储和缓存）
 	metaHandler := handlers.NewMetaHandler(store, cfg)
-	authHandler := handlers.NewAuthHandler(store, tokenCache, sessionCache)
+	authHandler := handlers.NewAuthHandler(store, tokenCache, sessionCache, cfg, twoFactorService, lockoutGuard)
 	sessionHandler := handlers.NewSessionHandler(store, tokenCache, sessionCache, cfg)
-	profileHandler := handlers.NewProfileHandler(store, cfg)
+	profileHandler := handlers.NewProfileHandler(store, tokenCache, cfg)
 	textureHandler := handlers.NewTextureHandler(store)
+	userHandler := handlers.NewUserHandler(store, tokenCache)
 
 	// 设置Gin模式
 	gin.SetMode(gin.ReleaseMode)
@@ -121,6 +184,12 @@ func main() {
 		utils.RespondJSONFast(c, stats)
 	})
 
+	// 提交密码的端点共用同一个速率限制器（如果启用）
+	var authRateLimit gin.HandlerFunc
+	if cfg.Rate.Enabled {
+		authRateLimit = middleware.RateLimit(cfg.Rate.AuthInterval)
+	}
+
 	// 认证服务器端点
 	authGroup := baseGroup.Group("/authserver")
 	authGroup.Use(middleware.CheckContentType())
@@ -128,7 +197,7 @@ func main() {
 		// 需要速率限制的端点（如果启用）
 		if cfg.Rate.Enabled {
 			rateLimitedGroup := authGroup.Group("")
-			rateLimitedGroup.Use(middleware.RateLimit(cfg.Rate.AuthInterval))
+			rateLimitedGroup.Use(authRateLimit)
 			{
 				rateLimitedGroup.POST("/authenticate", authHandler.Authenticate)
 				rateLimitedGroup.POST("/signout", authHandler.Signout)
@@ -158,13 +227,41 @@ func main() {
 		apiGroup.POST("/profiles/minecraft", middleware.CheckContentType(), profileHandler.SearchMultipleProfiles)
 		apiGroup.GET("/users/profiles/minecraft/:username", profileHandler.SearchSingleProfile)
 
+		// 角色名称管理端点
+		apiGroup.GET("/user/profiles/:uuid/names", profileHandler.GetNameHistory)
+		apiGroup.POST("/user/profile/:uuid/name", middleware.CheckContentType(), profileHandler.RenameProfile)
+
+		// 用户属性端点
+		apiGroup.POST("/user/properties", middleware.CheckContentType(), userHandler.UpdateProperties)
+
+		// 两步验证端点（如果启用）
+		if twoFactorService != nil {
+			twoFactorHandler := handlers.NewTwoFactorHandler(store, tokenCache, twoFactorService, lockoutGuard)
+			twoFactorGroup := apiGroup.Group("/user/2fa")
+			twoFactorGroup.Use(middleware.CheckContentType())
+			if cfg.Rate.Enabled {
+				twoFactorGroup.Use(authRateLimit)
+			}
+			twoFactorGroup.POST("/enroll", twoFactorHandler.Enroll)
+			twoFactorGroup.POST("/confirm", twoFactorHandler.Confirm)
+			twoFactorGroup.POST("/recovery-codes", twoFactorHandler.RegenerateRecoveryCodes)
+			twoFactorGroup.POST("/disable", twoFactorHandler.Disable)
+		}
+
+		// 管理员端点：账户登录失败锁定（如果启用）
+		if lockoutGuard != nil {
+			adminHandler := handlers.NewAdminHandler(store, tokenCache, lockoutGuard)
+			apiGroup.GET("/admin/lockouts/:username", adminHandler.GetLockout)
+			apiGroup.DELETE("/admin/lockouts/:username", adminHandler.Unlock)
+		}
+
 		// 材质管理端点 (符合Yggdrasil规范)
 		apiGroup.PUT("/user/profile/:uuid/:textureType", middleware.CheckContentType(), textureHandler.UploadTexture)
 		apiGroup.DELETE("/user/profile/:uuid/:textureType", textureHandler.DeleteTexture)
 	}
 
 	// 启动清理协程
-	go startCleanupRoutines(tokenCache, sessionCache)
+	go startCleanupRoutines(tokenCache, sessionCache, loginAttemptCache)
 
 	// 启动服务器
 	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
@@ -187,8 +284,69 @@ func main() {
 	}
 }
 
-// startCleanupRoutines 启动清理协程
-func startCleanupRoutines(tokenCache cache.TokenCache, sessionCache cache.SessionCache) {
+// reportProfileNameConflicts 报告仅大小写不同的重名角色
+func reportProfileNameConflicts(store storage.Storage) {
+	conflicts, err := store.FindProfileNameConflicts()
+	if err != nil {
+		log.Printf("⚠️  Failed to check profile name conflicts: %v", err)
+		return
+	}
+
+	if len(conflicts) == 0 {
+		return
+	}
+
+	log.Printf("⚠️  Found %d case-insensitive profile name conflicts (lookups prefer exact match, then the earliest profile):", len(conflicts))
+	for _, conflict := range conflicts {
+		names := make([]string, 0, len(conflict.Profiles))
+		for _, profile := range conflict.Profiles {
+			id := profile.ID
+			if id == "" {
+				id = "unmapped"
+			}
+			names = append(names, fmt.Sprintf("%s (%s)", profile.Name, id))
+		}
+		log.Printf("   - %s: %s", conflict.Name, strings.Join(names, ", "))
+	}
+}
+
+// subscribeCacheInvalidation 订阅存储的数据变更事件（如BlessingSkin网站上更换皮肤、修改密码或封禁用户），使相关缓存失效
+func subscribeCacheInvalidation(store storage.Storage) {
+	notifier, ok := store.(storage.ChangeNotifier)
+	if !ok {
+		return
+	}
+
+	// 只有用户缓存中的用户需要检测密码、封禁等变更
+	if watcher, ok := store.(storage.UserWatcher); ok {
+		watcher.WatchUsers(cache.GlobalUserCache.UserIDs)
+	}
+
+	notifier.Subscribe(func(event storage.ChangeEvent) {
+		switch event.Type {
+		case storage.ChangeProfile, storage.ChangeTexture:
+			// 材质事件只在有角色使用该材质时带有角色信息
+			if event.UserID != "" {
+				cache.GlobalUserCache.DeleteUser(event.UserID)
+			}
+			if event.ProfileID != "" {
+				utils.InvalidateCachedResponses(utils.ProfileResponseTag(event.ProfileID))
+			}
+			if event.ProfileName != "" {
+				utils.InvalidateCachedResponses(utils.ProfileNameResponseTag(event.ProfileName))
+			}
+		case storage.ChangeUser:
+			cache.GlobalUserCache.DeleteUser(event.UserID)
+		case storage.ChangeOptions:
+			log.Printf("🔄 Options changed: %s", strings.Join(event.OptionNames, ", "))
+			utils.ClearCachedResponses()
+			handlers.ResetKeyPairCache()
+		}
+	})
+}
+
+// startCleanupRoutines 启动清理协程（loginAttemptCache为nil时不清理登录失败记录）
+func startCleanupRoutines(tokenCache cache.TokenCache, sessionCache cache.SessionCache, loginAttemptCache cache.LoginAttemptCache) {
 	ticker := time.NewTicker(5 * time.Minute) // 每5分钟清理一次
 	defer ticker.Stop()
 
@@ -205,6 +363,13 @@ func startCleanupRoutines(tokenCache cache.TokenCache, sessionCache cache.Sessio
 			log.Printf("❌ Failed to cleanup expired sessions: %v", err)
 		}
 
+		// 清理过期的登录失败记录
+		if loginAttemptCache != nil {
+			if err := loginAttemptCache.CleanupExpired(); err != nil {
+				log.Printf("❌ Failed to cleanup expired login attempts: %v", err)
+			}
+		}
+
 		log.Println("✅ Cleanup routine completed")
 	}
 }
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"yggdrasil-api-go/src/config"
+	storage_factory "yggdrasil-api-go/src/storage"
+	"yggdrasil-api-go/src/storage/blessing_skin"
+
+	"github.com/bytedance/sonic"
+)
+
+// runReconcileCommand 执行 reconcile 子命令：对账BlessingSkin角色与UUID映射
+func runReconcileCommand(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	apply := fs.Bool("apply", false, "应用修复（默认仅生成报告）")
+	pruneOrphans := fs.Bool("prune-orphans", false, "删除已删除角色的遗留映射")
+	reportPath := fs.String("report", "", "报告输出文件（默认输出到标准输出）")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if cfg.Storage.Type != "blessing_skin" {
+		return fmt.Errorf("reconcile requires blessing_skin storage, got %s", cfg.Storage.Type)
+	}
+
+	// 手动对账时不启动定时任务和变更检测
+	cfg.Storage.BlessingSkinOptions.Reconcile.Enabled = false
+	cfg.Storage.BlessingSkinOptions.ChangeFeed.Enabled = false
+
+	store, err := storage_factory.NewStorageFactory().CreateStorage(&cfg.Storage, &cfg.Texture)
+	if err != nil {
+		return fmt.Errorf("failed to create storage: %w", err)
+	}
+	defer store.Close()
+
+	bsStorage, ok := store.(*blessing_skin.Storage)
+	if !ok {
+		return fmt.Errorf("unexpected storage implementation %T", store)
+	}
+
+	report, err := bsStorage.Reconcile(blessing_skin.ReconcileOptions{
+		Apply:        *apply,
+		PruneOrphans: *pruneOrphans,
+	})
+	if err != nil {
+		return err
+	}
+
+	if *reportPath != "" {
+		if err := blessing_skin.WriteReconcileReport(report, *reportPath); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "📄 Reconcile report written to %s\n", *reportPath)
+	} else {
+		data, err := sonic.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	}
+
+	fmt.Fprintf(os.Stderr, "🔍 %d players, %d mappings, issues: %v (applied: %v)\n",
+		report.Players, report.Mappings, report.Summary, report.Apply)
+	return nil
+}
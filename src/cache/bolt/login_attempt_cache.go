@@ -0,0 +1,112 @@
+package bolt
+
+import (
+	"fmt"
+	"time"
+
+	"yggdrasil-api-go/src/yggdrasil"
+
+	"github.com/bytedance/sonic"
+	"go.etcd.io/bbolt"
+)
+
+// LoginAttemptCache bbolt登录失败记录缓存
+type LoginAttemptCache struct {
+	store *store
+}
+
+// NewLoginAttemptCache 创建bbolt登录失败记录缓存
+func NewLoginAttemptCache(options map[string]any) (*LoginAttemptCache, error) {
+	s, err := openStore(options)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginAttemptCache{store: s}, nil
+}
+
+// RecordFailure 增加失败次数（在同一个读写事务中读取并写回）
+func (c *LoginAttemptCache) RecordFailure(key string, ttl time.Duration) (*yggdrasil.LoginAttempts, error) {
+	now := time.Now()
+	var attempts yggdrasil.LoginAttempts
+
+	err := c.store.update(func(tx *bbolt.Tx) error {
+		expiresAt, payload, found := decodeRecord(tx.Bucket(loginAttemptsBucket).Get([]byte(key)))
+		if found && now.Before(expiresAt) {
+			if err := sonic.Unmarshal(payload, &attempts); err != nil {
+				return err
+			}
+		}
+		attempts.Failures++
+		attempts.LastFailure = now
+
+		payload, err := sonic.Marshal(&attempts)
+		if err != nil {
+			return err
+		}
+		return putRecord(tx, loginAttemptsBucket, loginExpiryBucket, []byte(key), now.Add(ttl), payload)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record login failure: %w", err)
+	}
+	return &attempts, nil
+}
+
+// Get 获取失败记录
+func (c *LoginAttemptCache) Get(key string) (*yggdrasil.LoginAttempts, error) {
+	var (
+		attempts  yggdrasil.LoginAttempts
+		expiresAt time.Time
+		found     bool
+	)
+	err := c.store.view(func(tx *bbolt.Tx) error {
+		var payload []byte
+		expiresAt, payload, found = decodeRecord(tx.Bucket(loginAttemptsBucket).Get([]byte(key)))
+		if !found {
+			return nil
+		}
+		return sonic.Unmarshal(payload, &attempts)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get login attempts: %w", err)
+	}
+
+	if !found || time.Now().After(expiresAt) {
+		return nil, nil
+	}
+	return &attempts, nil
+}
+
+// Reset 清除失败记录
+func (c *LoginAttemptCache) Reset(key string) error {
+	err := c.store.update(func(tx *bbolt.Tx) error {
+		_, err := deleteRecord(tx, loginAttemptsBucket, loginExpiryBucket, []byte(key))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reset login attempts: %w", err)
+	}
+	return nil
+}
+
+// CleanupExpired 按TTL索引清理过期记录
+func (c *LoginAttemptCache) CleanupExpired() error {
+	err := c.store.update(func(tx *bbolt.Tx) error {
+		_, err := sweepExpired(tx, loginAttemptsBucket, loginExpiryBucket, time.Now())
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to cleanup expired login attempts: %w", err)
+	}
+
+	return c.store.compactIfNeeded()
+}
+
+// Close 关闭缓存（最后一个使用该文件的缓存关闭时关闭数据库）
+func (c *LoginAttemptCache) Close() error {
+	return c.store.release()
+}
+
+// GetCacheType 获取缓存类型
+func (c *LoginAttemptCache) GetCacheType() string {
+	return "bolt"
+}
@@ -0,0 +1,108 @@
+package bolt
+
+import (
+	"fmt"
+	"time"
+
+	"yggdrasil-api-go/src/yggdrasil"
+
+	"github.com/bytedance/sonic"
+	"go.etcd.io/bbolt"
+)
+
+// SessionCache bbolt Session缓存
+type SessionCache struct {
+	store *store
+}
+
+// NewSessionCache 创建bbolt Session缓存
+func NewSessionCache(options map[string]any) (*SessionCache, error) {
+	s, err := openStore(options)
+	if err != nil {
+		return nil, err
+	}
+	return &SessionCache{store: s}, nil
+}
+
+// Store 存储Session（过期时间自会话创建起计算）
+func (c *SessionCache) Store(serverID string, session *yggdrasil.Session) error {
+	sessionCopy := *session
+	sessionCopy.ServerID = serverID
+
+	payload, err := sonic.Marshal(&sessionCopy)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+
+	err = c.store.update(func(tx *bbolt.Tx) error {
+		return putRecord(tx, sessionsBucket, sessionExpiryBucket, []byte(serverID), session.ExpiresAt(), payload)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store session: %w", err)
+	}
+	return nil
+}
+
+// Get 获取Session
+func (c *SessionCache) Get(serverID string) (*yggdrasil.Session, error) {
+	var (
+		session   yggdrasil.Session
+		expiresAt time.Time
+		found     bool
+	)
+	err := c.store.view(func(tx *bbolt.Tx) error {
+		var payload []byte
+		expiresAt, payload, found = decodeRecord(tx.Bucket(sessionsBucket).Get([]byte(serverID)))
+		if !found {
+			return nil
+		}
+		return sonic.Unmarshal(payload, &session)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	if !found {
+		return nil, yggdrasil.ErrSessionNotFound
+	}
+	if time.Now().After(expiresAt) {
+		return nil, fmt.Errorf("%w: session expired", yggdrasil.ErrSessionNotFound)
+	}
+
+	return &session, nil
+}
+
+// Delete 删除Session
+func (c *SessionCache) Delete(serverID string) error {
+	err := c.store.update(func(tx *bbolt.Tx) error {
+		_, err := deleteRecord(tx, sessionsBucket, sessionExpiryBucket, []byte(serverID))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// CleanupExpired 按TTL索引清理过期Session，空闲空间过多时压缩数据库
+func (c *SessionCache) CleanupExpired() error {
+	err := c.store.update(func(tx *bbolt.Tx) error {
+		_, err := sweepExpired(tx, sessionsBucket, sessionExpiryBucket, time.Now())
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to cleanup expired sessions: %w", err)
+	}
+
+	return c.store.compactIfNeeded()
+}
+
+// Close 关闭缓存（最后一个使用该文件的缓存关闭时关闭数据库）
+func (c *SessionCache) Close() error {
+	return c.store.release()
+}
+
+// GetCacheType 获取缓存类型
+func (c *SessionCache) GetCacheType() string {
+	return "bolt"
+}
@@ -0,0 +1,288 @@
+// Package bolt 基于bbolt的嵌入式持久化缓存实现（单机部署无需Redis或MySQL，重启后Token仍然有效）
+package bolt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// 存储桶
+var (
+	tokensBucket        = []byte("tokens")         // 用户ID\x00TokenID -> 记录（同一用户的Token键相邻，按前缀遍历即为用户索引）
+	tokenExpiryBucket   = []byte("token_expiry")   // 过期时间+键 -> 空（TTL清理索引）
+	revokedBucket       = []byte("revoked")        // TokenID -> JWT过期时间
+	sessionsBucket      = []byte("sessions")       // 服务器ID -> 记录
+	sessionExpiryBucket = []byte("session_expiry") // 过期时间+键 -> 空（TTL清理索引）
+	loginAttemptsBucket = []byte("login_attempts") // 登录失败记录键 -> 记录
+	loginExpiryBucket   = []byte("login_expiry")   // 过期时间+键 -> 空（TTL清理索引）
+)
+
+// 默认配置
+const (
+	defaultPath             = "storage/framework/cache/yggdrasil.db"
+	defaultCompactThreshold = 0.5             // 空闲空间占文件大小的比例达到该值时压缩
+	compactMinSize          = 4 * 1024 * 1024 // 文件小于该大小时不压缩
+	openTimeout             = time.Second     // 等待文件锁的时间（文件被其他进程打开时）
+)
+
+// store 共享的bbolt数据库（同一文件的Token缓存和Session缓存共用一个实例）
+type store struct {
+	path             string
+	db               *bbolt.DB
+	compactThreshold float64
+	refs             int
+	mu               sync.RWMutex // 压缩时替换db需要写锁
+}
+
+var (
+	stores   = make(map[string]*store) // 绝对路径 -> 数据库
+	storesMu sync.Mutex
+)
+
+// openStore 打开（或复用已打开的）数据库
+func openStore(options map[string]any) (*store, error) {
+	path := defaultPath
+	if p, ok := options["path"].(string); ok && p != "" {
+		path = p
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bolt cache path: %w", err)
+	}
+
+	storesMu.Lock()
+	defer storesMu.Unlock()
+
+	if s, exists := stores[path]; exists {
+		s.refs++
+		return s, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create bolt cache directory: %w", err)
+	}
+
+	db, err := openDB(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &store{
+		path:             path,
+		db:               db,
+		compactThreshold: defaultCompactThreshold,
+		refs:             1,
+	}
+	switch threshold := options["compact_threshold"].(type) {
+	case float64:
+		s.compactThreshold = threshold
+	case int:
+		s.compactThreshold = float64(threshold)
+	}
+
+	stores[path] = s
+	return s, nil
+}
+
+// openDB 打开数据库文件并创建存储桶
+func openDB(path string) (*bbolt.DB, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bolt cache: %w", err)
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range [][]byte{tokensBucket, tokenExpiryBucket, revokedBucket, sessionsBucket, sessionExpiryBucket, loginAttemptsBucket, loginExpiryBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create bolt cache buckets: %w", err)
+	}
+
+	return db, nil
+}
+
+// release 释放引用，最后一个引用释放时关闭数据库
+func (s *store) release() error {
+	storesMu.Lock()
+	defer storesMu.Unlock()
+
+	s.refs--
+	if s.refs > 0 {
+		return nil
+	}
+	delete(stores, s.path)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.db.Close()
+}
+
+// update 执行读写事务
+func (s *store) update(fn func(tx *bbolt.Tx) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.db.Update(fn)
+}
+
+// view 执行只读事务
+func (s *store) view(fn func(tx *bbolt.Tx) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.db.View(fn)
+}
+
+// compactIfNeeded 空闲空间比例达到阈值时压缩数据库（bbolt删除数据后不会缩小文件）
+func (s *store) compactIfNeeded() error {
+	if s.compactThreshold <= 0 {
+		return nil
+	}
+
+	s.mu.RLock()
+	info, err := os.Stat(s.path)
+	free := s.db.Stats().FreeAlloc
+	s.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to stat bolt cache: %w", err)
+	}
+
+	if info.Size() < compactMinSize || float64(free) < float64(info.Size())*s.compactThreshold {
+		return nil
+	}
+	return s.compact()
+}
+
+// compact 将数据复制到新文件后替换原文件
+func (s *store) compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tmpPath := s.path + ".compact"
+	os.Remove(tmpPath)
+
+	dst, err := bbolt.Open(tmpPath, 0600, &bbolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return fmt.Errorf("failed to create compacted bolt cache: %w", err)
+	}
+	if err := bbolt.Compact(dst, s.db, 0); err != nil {
+		dst.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to compact bolt cache: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to compact bolt cache: %w", err)
+	}
+
+	// 替换原文件并重新打开
+	if err := s.db.Close(); err != nil {
+		return fmt.Errorf("failed to close bolt cache: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		os.Remove(tmpPath)
+		err = fmt.Errorf("failed to replace bolt cache: %w", err)
+		if db, openErr := openDB(s.path); openErr == nil {
+			s.db = db
+		}
+		return err
+	}
+
+	db, err := openDB(s.path)
+	if err != nil {
+		return err
+	}
+	s.db = db
+	return nil
+}
+
+// 记录格式：8字节过期时间（Unix纳秒，大端序）+ JSON数据
+
+// encodeRecord 编码记录
+func encodeRecord(expiresAt time.Time, payload []byte) []byte {
+	record := make([]byte, 8+len(payload))
+	binary.BigEndian.PutUint64(record, uint64(expiresAt.UnixNano()))
+	copy(record[8:], payload)
+	return record
+}
+
+// decodeRecord 解码记录
+func decodeRecord(record []byte) (time.Time, []byte, bool) {
+	if len(record) < 8 {
+		return time.Time{}, nil, false
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(record))), record[8:], true
+}
+
+// expiryKey 生成TTL索引键（过期时间在前，按时间顺序排列）
+func expiryKey(expiresAt time.Time, key []byte) []byte {
+	indexKey := make([]byte, 8+len(key))
+	binary.BigEndian.PutUint64(indexKey, uint64(expiresAt.UnixNano()))
+	copy(indexKey[8:], key)
+	return indexKey
+}
+
+// putRecord 写入记录并更新TTL索引
+func putRecord(tx *bbolt.Tx, bucket, index, key []byte, expiresAt time.Time, payload []byte) error {
+	if _, err := deleteRecord(tx, bucket, index, key); err != nil {
+		return err
+	}
+	if err := tx.Bucket(bucket).Put(key, encodeRecord(expiresAt, payload)); err != nil {
+		return err
+	}
+	return tx.Bucket(index).Put(expiryKey(expiresAt, key), nil)
+}
+
+// deleteRecord 删除记录及其TTL索引，返回被删除的记录（不存在时为nil）
+func deleteRecord(tx *bbolt.Tx, bucket, index, key []byte) ([]byte, error) {
+	b := tx.Bucket(bucket)
+	record := b.Get(key)
+	if record == nil {
+		return nil, nil
+	}
+	record = bytes.Clone(record) // 数据仅在事务内有效，删除后不能再引用
+
+	if expiresAt, _, ok := decodeRecord(record); ok {
+		if err := tx.Bucket(index).Delete(expiryKey(expiresAt, key)); err != nil {
+			return nil, err
+		}
+	}
+	return record, b.Delete(key)
+}
+
+// sweepExpired 按TTL索引删除所有已过期的记录，返回被删除的键
+func sweepExpired(tx *bbolt.Tx, bucket, index []byte, now time.Time) ([][]byte, error) {
+	b := tx.Bucket(bucket)
+	idx := tx.Bucket(index)
+	limit := uint64(now.UnixNano())
+
+	var expired [][]byte
+	c := idx.Cursor()
+	for k, _ := c.First(); k != nil && len(k) >= 8 && binary.BigEndian.Uint64(k) <= limit; k, _ = c.First() {
+		key := bytes.Clone(k[8:])
+		indexed := binary.BigEndian.Uint64(k)
+		if err := c.Delete(); err != nil {
+			return nil, err
+		}
+
+		// 仅删除过期时间与索引一致的记录
+		if expiresAt, _, ok := decodeRecord(b.Get(key)); ok && uint64(expiresAt.UnixNano()) == indexed {
+			if err := b.Delete(key); err != nil {
+				return nil, err
+			}
+			expired = append(expired, key)
+		}
+	}
+	return expired, nil
+}
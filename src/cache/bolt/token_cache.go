@@ -0,0 +1,360 @@
+package bolt
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"yggdrasil-api-go/src/cache/revocation"
+	"yggdrasil-api-go/src/utils"
+	"yggdrasil-api-go/src/yggdrasil"
+
+	"github.com/bytedance/sonic"
+	"go.etcd.io/bbolt"
+)
+
+// TokenCache bbolt Token缓存
+type TokenCache struct {
+	store *store
+
+	revocations revocation.Notifier
+	loadOnce    sync.Once
+}
+
+// NewTokenCache 创建bbolt Token缓存
+func NewTokenCache(options map[string]any) (*TokenCache, error) {
+	s, err := openStore(options)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenCache{store: s}, nil
+}
+
+// Store 存储Token
+func (c *TokenCache) Store(token *yggdrasil.Token) error {
+	_, err := c.StoreWithLimit(token, 0)
+	return err
+}
+
+// StoreWithLimit 存储Token并撤销该用户最旧的Token，使有效Token数不超过limit（在同一事务中完成）
+func (c *TokenCache) StoreWithLimit(token *yggdrasil.Token, limit int) (int, error) {
+	// 第一步：验证JWT并提取信息
+	claims, err := utils.ValidateJWT(token.AccessToken)
+	if err != nil {
+		return 0, fmt.Errorf("invalid JWT token: %w", err)
+	}
+
+	// 创建简化的Token对象（只存储JWT中没有的信息）
+	cacheToken := &yggdrasil.Token{
+		AccessToken:      token.AccessToken, // 保留完整的AccessToken用于列出用户Token
+		ClientToken:      token.ClientToken,
+		ProfileID:        claims.ProfileID,
+		Owner:            claims.UserID,
+		CreatedAt:        token.CreatedAt,
+		ExpiresAt:        token.ExpiresAt,
+		RefreshExpiresAt: token.RefreshExpiresAt,
+	}
+
+	// 暂时失效的Token仍需保留至刷新期限
+	deadline := token.RefreshDeadline()
+	if !time.Now().Before(deadline) {
+		return 0, fmt.Errorf("token already expired")
+	}
+
+	payload, err := sonic.Marshal(cacheToken)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal token: %w", err)
+	}
+
+	var evicted []revocation.Entry
+	err = c.store.update(func(tx *bbolt.Tx) error {
+		key := tokenKey(claims.UserID, claims.TokenID)
+		if err := putRecord(tx, tokensBucket, tokenExpiryBucket, key, deadline, payload); err != nil {
+			return err
+		}
+
+		if limit <= 0 {
+			return nil
+		}
+
+		var err error
+		if evicted, err = evictOldest(tx, claims.UserID, claims.TokenID, limit); err != nil {
+			return err
+		}
+		return recordRevocations(tx, evicted)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to store token: %w", err)
+	}
+
+	c.revocations.Notify(evicted)
+	return len(evicted), nil
+}
+
+// Get 获取Token（先验证JWT，再查询缓存）
+func (c *TokenCache) Get(accessToken string) (*yggdrasil.Token, error) {
+	claims, err := utils.ValidateJWT(accessToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid JWT token: %w", yggdrasil.ErrTokenNotFound, err)
+	}
+
+	var token *yggdrasil.Token
+	err = c.store.view(func(tx *bbolt.Tx) error {
+		token = decodeToken(tx.Bucket(tokensBucket).Get(tokenKey(claims.UserID, claims.TokenID)))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token: %w", err)
+	}
+	if token == nil || !token.IsRefreshable() {
+		return nil, yggdrasil.ErrTokenNotFound
+	}
+
+	// 构建Token对象（结合JWT信息和缓存信息）
+	return &yggdrasil.Token{
+		AccessToken:      accessToken,
+		ClientToken:      token.ClientToken,
+		ProfileID:        claims.ProfileID,
+		Owner:            claims.UserID,
+		CreatedAt:        token.CreatedAt,
+		ExpiresAt:        token.ExpiresAt,
+		RefreshExpiresAt: token.RefreshExpiresAt,
+	}, nil
+}
+
+// Delete 删除Token
+func (c *TokenCache) Delete(accessToken string) error {
+	claims, err := utils.ValidateJWT(accessToken)
+	if err != nil {
+		// JWT无效，无需删除（兼容性）
+		return nil
+	}
+
+	revoked := []revocation.Entry{revocation.EntryFromClaims(claims)}
+	err = c.store.update(func(tx *bbolt.Tx) error {
+		if _, err := deleteRecord(tx, tokensBucket, tokenExpiryBucket, tokenKey(claims.UserID, claims.TokenID)); err != nil {
+			return err
+		}
+		return recordRevocations(tx, revoked)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete token: %w", err)
+	}
+
+	c.revocations.Notify(revoked)
+	return nil
+}
+
+// GetUserTokens 获取用户的所有Token（按用户键前缀遍历）
+func (c *TokenCache) GetUserTokens(userID string) ([]*yggdrasil.Token, error) {
+	tokens := []*yggdrasil.Token{}
+	err := c.store.view(func(tx *bbolt.Tx) error {
+		prefix := userPrefix(userID)
+		cursor := tx.Bucket(tokensBucket).Cursor()
+		for k, v := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = cursor.Next() {
+			if token := decodeToken(v); token != nil && token.IsRefreshable() {
+				tokens = append(tokens, token)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// DeleteUserTokens 删除用户的所有Token
+func (c *TokenCache) DeleteUserTokens(userID string) error {
+	var revoked []revocation.Entry
+	err := c.store.update(func(tx *bbolt.Tx) error {
+		keys, tokens := userTokens(tx, userID)
+		for i, key := range keys {
+			if _, err := deleteRecord(tx, tokensBucket, tokenExpiryBucket, key); err != nil {
+				return err
+			}
+			if tokens[i] != nil && tokens[i].IsRefreshable() {
+				revoked = append(revoked, revocation.Entry{TokenID: tokenIDFromKey(key), ExpiresAt: tokens[i].RefreshDeadline()})
+			}
+		}
+		return recordRevocations(tx, revoked)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete user tokens: %w", err)
+	}
+
+	c.revocations.Notify(revoked)
+	return nil
+}
+
+// GetUserTokenCount 获取用户Token数量（仅统计仍然有效的Token）
+func (c *TokenCache) GetUserTokenCount(userID string) (int, error) {
+	tokens, err := c.GetUserTokens(userID)
+	if err != nil {
+		return 0, err
+	}
+	return len(tokens), nil
+}
+
+// CleanupExpired 按TTL索引清理过期Token和撤销记录，空闲空间过多时压缩数据库
+func (c *TokenCache) CleanupExpired() error {
+	now := time.Now()
+	err := c.store.update(func(tx *bbolt.Tx) error {
+		if _, err := sweepExpired(tx, tokensBucket, tokenExpiryBucket, now); err != nil {
+			return err
+		}
+
+		// 清理JWT已过期的撤销记录
+		bucket := tx.Bucket(revokedBucket)
+		var expired [][]byte
+		bucket.ForEach(func(k, v []byte) error {
+			if expiresAt, _, ok := decodeRecord(v); !ok || !now.Before(expiresAt) {
+				expired = append(expired, bytes.Clone(k))
+			}
+			return nil
+		})
+		for _, key := range expired {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to cleanup expired tokens: %w", err)
+	}
+
+	return c.store.compactIfNeeded()
+}
+
+// SubscribeRevocations 订阅Token撤销事件（首次订阅时加载持久化的撤销记录，重启后被撤销的令牌仍无法进入服务器）
+func (c *TokenCache) SubscribeRevocations(handler func([]revocation.Entry)) {
+	c.revocations.Subscribe(handler)
+	c.loadOnce.Do(c.loadRevocations)
+}
+
+// loadRevocations 加载仍未过期的撤销记录
+func (c *TokenCache) loadRevocations() {
+	now := time.Now()
+	var entries []revocation.Entry
+	err := c.store.view(func(tx *bbolt.Tx) error {
+		return tx.Bucket(revokedBucket).ForEach(func(k, v []byte) error {
+			if expiresAt, _, ok := decodeRecord(v); ok && now.Before(expiresAt) {
+				entries = append(entries, revocation.Entry{TokenID: string(k), ExpiresAt: expiresAt})
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		fmt.Printf("⚠️  Failed to load revoked tokens: %v\n", err)
+	}
+	c.revocations.Notify(entries)
+}
+
+// Close 关闭缓存（最后一个使用该文件的缓存关闭时关闭数据库）
+func (c *TokenCache) Close() error {
+	return c.store.release()
+}
+
+// GetCacheType 获取缓存类型
+func (c *TokenCache) GetCacheType() string {
+	return "bolt"
+}
+
+// evictOldest 撤销用户最旧的Token（不包括keepID），使有效Token数不超过limit，同时删除已失效的Token
+func evictOldest(tx *bbolt.Tx, userID, keepID string, limit int) ([]revocation.Entry, error) {
+	keys, tokens := userTokens(tx, userID)
+
+	type liveToken struct {
+		key   []byte
+		token *yggdrasil.Token
+	}
+	var live []liveToken
+	for i, key := range keys {
+		if tokenIDFromKey(key) == keepID {
+			continue
+		}
+		if tokens[i] == nil || !tokens[i].IsRefreshable() {
+			if _, err := deleteRecord(tx, tokensBucket, tokenExpiryBucket, key); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		live = append(live, liveToken{key: key, token: tokens[i]})
+	}
+
+	excess := len(live) - (limit - 1)
+	if excess <= 0 {
+		return nil, nil
+	}
+
+	// 按创建时间升序排列，撤销最旧的Token
+	slices.SortStableFunc(live, func(a, b liveToken) int {
+		return a.token.CreatedAt.Compare(b.token.CreatedAt)
+	})
+	evicted := make([]revocation.Entry, 0, excess)
+	for _, victim := range live[:excess] {
+		if _, err := deleteRecord(tx, tokensBucket, tokenExpiryBucket, victim.key); err != nil {
+			return nil, err
+		}
+		evicted = append(evicted, revocation.Entry{TokenID: tokenIDFromKey(victim.key), ExpiresAt: victim.token.RefreshDeadline()})
+	}
+	return evicted, nil
+}
+
+// recordRevocations 持久化撤销记录
+func recordRevocations(tx *bbolt.Tx, entries []revocation.Entry) error {
+	bucket := tx.Bucket(revokedBucket)
+	for _, entry := range entries {
+		if err := bucket.Put([]byte(entry.TokenID), encodeRecord(entry.ExpiresAt, nil)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// userTokens 获取用户的所有Token键和Token（无法解析的Token为nil）
+func userTokens(tx *bbolt.Tx, userID string) ([][]byte, []*yggdrasil.Token) {
+	var keys [][]byte
+	var tokens []*yggdrasil.Token
+
+	prefix := userPrefix(userID)
+	cursor := tx.Bucket(tokensBucket).Cursor()
+	for k, v := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = cursor.Next() {
+		keys = append(keys, bytes.Clone(k))
+		tokens = append(tokens, decodeToken(v))
+	}
+	return keys, tokens
+}
+
+// decodeToken 解码Token记录（记录不存在或无法解析时返回nil）
+func decodeToken(record []byte) *yggdrasil.Token {
+	_, payload, ok := decodeRecord(record)
+	if !ok {
+		return nil
+	}
+
+	var token yggdrasil.Token
+	if err := sonic.Unmarshal(payload, &token); err != nil {
+		return nil
+	}
+	return &token
+}
+
+// userPrefix 用户Token键前缀（用户ID\x00）
+func userPrefix(userID string) []byte {
+	return append([]byte(userID), 0)
+}
+
+// tokenKey 生成Token键（用户ID\x00TokenID）
+func tokenKey(userID, tokenID string) []byte {
+	return append(userPrefix(userID), tokenID...)
+}
+
+// tokenIDFromKey 从Token键中提取TokenID
+func tokenIDFromKey(key []byte) string {
+	_, tokenID, _ := bytes.Cut(key, []byte{0})
+	return string(tokenID)
+}
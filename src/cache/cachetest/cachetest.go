@@ -0,0 +1,140 @@
+// Package cachetest 缓存后端一致性测试套件
+// 对任意TokenCache/SessionCache/LoginAttemptCache实现运行同一组行为测试（过期、删除、按用户列出、并发），
+// 通过 RunStandalone 在独立程序中运行（见 test/cache_conformance），不依赖 testing 包
+package cachetest
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"yggdrasil-api-go/src/cache"
+	"yggdrasil-api-go/src/utils"
+)
+
+// JWTSecret 套件使用的JWT密钥（运行前自动设置）
+const JWTSecret = "yggdrasil-cachetest-secret-key-32chars-minimum"
+
+// T 测试报告接口（*testing.T 满足该接口）
+type T interface {
+	Helper()
+	Errorf(format string, args ...any)
+	Fatalf(format string, args ...any)
+}
+
+// Suite 待测缓存后端
+type Suite struct {
+	Name            string                             // 后端名称
+	NewTokenCache   func() (cache.TokenCache, error)   // 创建全新（空）的Token缓存实例，为nil时跳过Token用例
+	NewSessionCache func() (cache.SessionCache, error) // 创建全新（空）的Session缓存实例，为nil时跳过Session用例
+	FastForward     func(d time.Duration)              // 可选：真实等待后额外推进后端的虚拟时钟（如miniredis）
+
+	// NewLoginAttemptCache 创建全新（空）的登录失败记录缓存实例，为nil时跳过LoginAttempt用例
+	NewLoginAttemptCache func() (cache.LoginAttemptCache, error)
+
+	// NewPeerTokenCache 可选：创建与最近一次NewTokenCache共享同一后端的另一个实例（用于多实例用例）
+	NewPeerTokenCache func() (cache.TokenCache, error)
+}
+
+// Case 测试用例
+type Case struct {
+	Name string
+	Run  func(t T, s *Suite)
+}
+
+// Cases 获取适用于后端的全部用例
+func (s *Suite) Cases() []Case {
+	var cases []Case
+	if s.NewTokenCache != nil {
+		cases = append(cases, TokenCases()...)
+	}
+	if s.NewSessionCache != nil {
+		cases = append(cases, SessionCases()...)
+	}
+	if s.NewLoginAttemptCache != nil {
+		cases = append(cases, LoginAttemptCases()...)
+	}
+	return cases
+}
+
+// RunStandalone 在独立程序中运行套件，输出每个用例的结果，返回失败用例数
+func RunStandalone(s *Suite, w io.Writer) int {
+	setupJWTSecret()
+
+	failed := 0
+	for _, c := range s.Cases() {
+		start := time.Now()
+		errs := runCase(c, s)
+		elapsed := time.Since(start).Round(time.Millisecond)
+
+		if len(errs) == 0 {
+			fmt.Fprintf(w, "✅ %s/%s (%v)\n", s.Name, c.Name, elapsed)
+			continue
+		}
+
+		failed++
+		fmt.Fprintf(w, "❌ %s/%s (%v)\n", s.Name, c.Name, elapsed)
+		for _, err := range errs {
+			fmt.Fprintf(w, "    %s\n", strings.ReplaceAll(err, "\n", "\n    "))
+		}
+	}
+	return failed
+}
+
+var jwtSecretOnce sync.Once
+
+// setupJWTSecret 设置套件使用的JWT密钥
+func setupJWTSecret() {
+	jwtSecretOnce.Do(func() {
+		utils.SetJWTSecret(JWTSecret)
+	})
+}
+
+// errFatal Fatalf中止用例的标记
+var errFatal = fmt.Errorf("cachetest: fatal")
+
+// recorder 独立运行时的测试报告实现
+type recorder struct {
+	errors []string
+	mu     sync.Mutex
+}
+
+// Helper 无操作（满足T接口）
+func (r *recorder) Helper() {}
+
+// Errorf 记录失败
+func (r *recorder) Errorf(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+// Fatalf 记录失败并中止用例（只能在用例协程中调用）
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.Errorf(format, args...)
+	panic(errFatal)
+}
+
+// runCase 运行单个用例并收集失败信息
+func runCase(c Case, s *Suite) (errs []string) {
+	r := &recorder{}
+	defer func() {
+		if p := recover(); p != nil && p != errFatal {
+			r.Errorf("panic: %v", p)
+		}
+		errs = r.errors
+	}()
+
+	c.Run(r, s)
+	return nil
+}
+
+// wait 等待一段时间（同时推进后端的虚拟时钟）
+func wait(s *Suite, d time.Duration) {
+	time.Sleep(d)
+	if s.FastForward != nil {
+		s.FastForward(d)
+	}
+}
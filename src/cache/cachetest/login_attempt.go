@@ -0,0 +1,170 @@
+// Package cachetest 登录失败记录缓存用例
+package cachetest
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"yggdrasil-api-go/src/cache"
+)
+
+// LoginAttemptCases 登录失败记录缓存的全部用例
+func LoginAttemptCases() []Case {
+	return []Case{
+		{Name: "LoginAttempt/RecordAndGet", Run: testLoginAttemptRecordAndGet},
+		{Name: "LoginAttempt/Reset", Run: testLoginAttemptReset},
+		{Name: "LoginAttempt/Expiry", Run: testLoginAttemptExpiry},
+		{Name: "LoginAttempt/Concurrency", Run: testLoginAttemptConcurrency},
+	}
+}
+
+// newLoginAttemptCache 创建待测登录失败记录缓存
+func newLoginAttemptCache(t T, s *Suite) cache.LoginAttemptCache {
+	t.Helper()
+	c, err := s.NewLoginAttemptCache()
+	if err != nil {
+		t.Fatalf("failed to create login attempt cache: %v", err)
+	}
+	return c
+}
+
+// checkFailures 检查失败次数（want为0时期望没有记录）
+func checkFailures(t T, c cache.LoginAttemptCache, key string, want int) {
+	t.Helper()
+
+	attempts, err := c.Get(key)
+	switch {
+	case err != nil:
+		t.Errorf("Get(%s) failed: %v", key, err)
+	case want == 0 && attempts != nil:
+		t.Errorf("Get(%s) = %d failures, want no record", key, attempts.Failures)
+	case want > 0 && attempts == nil:
+		t.Errorf("Get(%s) returned no record, want %d failures", key, want)
+	case want > 0 && attempts.Failures != want:
+		t.Errorf("Get(%s) = %d failures, want %d", key, attempts.Failures, want)
+	}
+}
+
+// testLoginAttemptRecordAndGet 每次失败计数加一并更新最近失败时间
+func testLoginAttemptRecordAndGet(t T, s *Suite) {
+	c := newLoginAttemptCache(t, s)
+	defer c.Close()
+
+	checkFailures(t, c, "alice@example.com", 0)
+
+	for i := 1; i <= 3; i++ {
+		before := time.Now()
+		attempts, err := c.RecordFailure("alice@example.com", time.Minute)
+		if err != nil {
+			t.Fatalf("RecordFailure failed: %v", err)
+		}
+		if attempts.Failures != i {
+			t.Errorf("RecordFailure #%d returned %d failures", i, attempts.Failures)
+		}
+		if !sameTime(attempts.LastFailure, before) {
+			t.Errorf("RecordFailure #%d: LastFailure = %v, want about %v", i, attempts.LastFailure, before)
+		}
+	}
+
+	checkFailures(t, c, "alice@example.com", 3)
+	checkFailures(t, c, "bob@example.com", 0)
+
+	got, err := c.Get("alice@example.com")
+	if err == nil && got != nil && !sameTime(got.LastFailure, time.Now()) {
+		t.Errorf("Get: LastFailure = %v, want about now", got.LastFailure)
+	}
+}
+
+// testLoginAttemptReset 清除记录后重新从1开始计数
+func testLoginAttemptReset(t T, s *Suite) {
+	c := newLoginAttemptCache(t, s)
+	defer c.Close()
+
+	for _, key := range []string{"alice@example.com", "alice@example.com", "bob@example.com"} {
+		if _, err := c.RecordFailure(key, time.Minute); err != nil {
+			t.Fatalf("RecordFailure failed: %v", err)
+		}
+	}
+
+	if err := c.Reset("alice@example.com"); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	checkFailures(t, c, "alice@example.com", 0)
+	checkFailures(t, c, "bob@example.com", 1)
+
+	attempts, err := c.RecordFailure("alice@example.com", time.Minute)
+	if err != nil {
+		t.Fatalf("RecordFailure failed: %v", err)
+	}
+	if attempts.Failures != 1 {
+		t.Errorf("RecordFailure after Reset returned %d failures, want 1", attempts.Failures)
+	}
+
+	if err := c.Reset("missing"); err != nil {
+		t.Errorf("Reset of an unknown key failed: %v", err)
+	}
+}
+
+// testLoginAttemptExpiry 记录在ttl后过期，过期后重新从1开始计数
+func testLoginAttemptExpiry(t T, s *Suite) {
+	c := newLoginAttemptCache(t, s)
+	defer c.Close()
+
+	// 文件缓存的过期时间只精确到秒
+	for range 2 {
+		if _, err := c.RecordFailure("expiring", time.Second); err != nil {
+			t.Fatalf("RecordFailure failed: %v", err)
+		}
+	}
+	if _, err := c.RecordFailure("fresh", time.Minute); err != nil {
+		t.Fatalf("RecordFailure failed: %v", err)
+	}
+	checkFailures(t, c, "expiring", 2)
+
+	wait(s, 2500*time.Millisecond)
+
+	checkFailures(t, c, "expiring", 0)
+	if err := c.CleanupExpired(); err != nil {
+		t.Errorf("CleanupExpired failed: %v", err)
+	}
+	checkFailures(t, c, "fresh", 1)
+
+	attempts, err := c.RecordFailure("expiring", time.Minute)
+	if err != nil {
+		t.Fatalf("RecordFailure failed: %v", err)
+	}
+	if attempts.Failures != 1 {
+		t.Errorf("RecordFailure after expiry returned %d failures, want 1", attempts.Failures)
+	}
+}
+
+// testLoginAttemptConcurrency 并发记录的失败不会丢失
+func testLoginAttemptConcurrency(t T, s *Suite) {
+	c := newLoginAttemptCache(t, s)
+	defer c.Close()
+
+	const workers, perWorker = 8, 10
+
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range perWorker {
+				if _, err := c.RecordFailure("shared", time.Minute); err != nil {
+					t.Errorf("concurrent RecordFailure failed: %v", err)
+				}
+				if _, err := c.RecordFailure(fmt.Sprintf("worker-%d", w), time.Minute); err != nil {
+					t.Errorf("concurrent RecordFailure failed: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	checkFailures(t, c, "shared", workers*perWorker)
+	for w := range workers {
+		checkFailures(t, c, fmt.Sprintf("worker-%d", w), perWorker)
+	}
+}
@@ -0,0 +1,239 @@
+// Package cachetest Session缓存用例
+package cachetest
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"yggdrasil-api-go/src/cache"
+	"yggdrasil-api-go/src/utils"
+	"yggdrasil-api-go/src/yggdrasil"
+)
+
+// SessionCases Session缓存的全部用例
+func SessionCases() []Case {
+	return []Case{
+		{Name: "Session/StoreAndGet", Run: testSessionStoreAndGet},
+		{Name: "Session/GetUnknown", Run: testSessionGetUnknown},
+		{Name: "Session/Overwrite", Run: testSessionOverwrite},
+		{Name: "Session/Delete", Run: testSessionDelete},
+		{Name: "Session/Expiry", Run: testSessionExpiry},
+		{Name: "Session/ConfiguredTTL", Run: testSessionConfiguredTTL},
+		{Name: "Session/Concurrency", Run: testSessionConcurrency},
+	}
+}
+
+// newSessionCache 创建待测Session缓存
+func newSessionCache(t T, s *Suite) cache.SessionCache {
+	t.Helper()
+	c, err := s.NewSessionCache()
+	if err != nil {
+		t.Fatalf("failed to create session cache: %v", err)
+	}
+	return c
+}
+
+// newSession 生成Session
+func newSession(serverID string, createdAt time.Time) *yggdrasil.Session {
+	return &yggdrasil.Session{
+		ServerID:    serverID,
+		AccessToken: utils.GenerateRandomUUID(),
+		TokenID:     utils.GenerateRandomUUID(),
+		ProfileID:   utils.GenerateRandomUUID(),
+		ClientIP:    "127.0.0.1",
+		UserAgent:   "Minecraft Launcher/2.4 (cachetest)",
+		CreatedAt:   createdAt,
+	}
+}
+
+// checkSession 获取Session并比较各字段
+func checkSession(t T, c cache.SessionCache, want *yggdrasil.Session) {
+	t.Helper()
+
+	got, err := c.Get(want.ServerID)
+	if err != nil {
+		t.Errorf("Get(%s) failed: %v", want.ServerID, err)
+		return
+	}
+
+	if got.ServerID != want.ServerID {
+		t.Errorf("ServerID = %q, want %q", got.ServerID, want.ServerID)
+	}
+	if got.AccessToken != want.AccessToken {
+		t.Errorf("AccessToken = %q, want %q", got.AccessToken, want.AccessToken)
+	}
+	if got.ProfileID != want.ProfileID {
+		t.Errorf("ProfileID = %q, want %q", got.ProfileID, want.ProfileID)
+	}
+	if got.TokenID != want.TokenID {
+		t.Errorf("TokenID = %q, want %q", got.TokenID, want.TokenID)
+	}
+	if got.ClientIP != want.ClientIP {
+		t.Errorf("ClientIP = %q, want %q", got.ClientIP, want.ClientIP)
+	}
+	if got.UserAgent != want.UserAgent {
+		t.Errorf("UserAgent = %q, want %q", got.UserAgent, want.UserAgent)
+	}
+	if !sameTime(got.CreatedAt, want.CreatedAt) {
+		t.Errorf("CreatedAt = %v, want %v", got.CreatedAt, want.CreatedAt)
+	}
+}
+
+// testSessionStoreAndGet 存储后可以取回所有字段
+func testSessionStoreAndGet(t T, s *Suite) {
+	c := newSessionCache(t, s)
+	defer c.Close()
+
+	session := newSession("server-1", time.Now())
+	if err := c.Store(session.ServerID, session); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	checkSession(t, c, session)
+}
+
+// testSessionGetUnknown 获取不存在的Session返回ErrSessionNotFound
+func testSessionGetUnknown(t T, s *Suite) {
+	c := newSessionCache(t, s)
+	defer c.Close()
+
+	if _, err := c.Get("missing"); !errors.Is(err, yggdrasil.ErrSessionNotFound) {
+		t.Errorf("Get of a session that was never stored: got %v, want ErrSessionNotFound", err)
+	}
+}
+
+// testSessionOverwrite 同一服务器ID重复存储时以最后一次为准
+func testSessionOverwrite(t T, s *Suite) {
+	c := newSessionCache(t, s)
+	defer c.Close()
+
+	first := newSession("server-1", time.Now())
+	second := newSession("server-1", time.Now())
+	for _, session := range []*yggdrasil.Session{first, second} {
+		if err := c.Store(session.ServerID, session); err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+	}
+	checkSession(t, c, second)
+}
+
+// testSessionDelete 删除Session
+func testSessionDelete(t T, s *Suite) {
+	c := newSessionCache(t, s)
+	defer c.Close()
+
+	deleted := newSession("server-1", time.Now())
+	kept := newSession("server-2", time.Now())
+	for _, session := range []*yggdrasil.Session{deleted, kept} {
+		if err := c.Store(session.ServerID, session); err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+	}
+
+	if err := c.Delete(deleted.ServerID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := c.Get(deleted.ServerID); err == nil {
+		t.Errorf("Get succeeded after Delete")
+	}
+	checkSession(t, c, kept)
+
+	if err := c.Delete("missing"); err != nil {
+		t.Errorf("Delete of an unknown session failed: %v", err)
+	}
+}
+
+// testSessionExpiry Session自创建起SessionTTL后过期
+func testSessionExpiry(t T, s *Suite) {
+	c := newSessionCache(t, s)
+	defer c.Close()
+
+	expiring := newSession("server-1", time.Now().Add(-yggdrasil.SessionTTL()+time.Second))
+	fresh := newSession("server-2", time.Now())
+	for _, session := range []*yggdrasil.Session{expiring, fresh} {
+		if err := c.Store(session.ServerID, session); err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+	}
+	checkSession(t, c, expiring)
+
+	wait(s, 2500*time.Millisecond)
+
+	if _, err := c.Get(expiring.ServerID); err == nil {
+		t.Errorf("Get of an expired session succeeded")
+	}
+	if err := c.CleanupExpired(); err != nil {
+		t.Errorf("CleanupExpired failed: %v", err)
+	}
+	checkSession(t, c, fresh)
+}
+
+// testSessionConfiguredTTL 后端按配置的会话有效期（yggdrasil.SetSessionTTL）保存Session
+func testSessionConfiguredTTL(t T, s *Suite) {
+	previous := yggdrasil.SessionTTL()
+	yggdrasil.SetSessionTTL(2 * time.Minute)
+	defer yggdrasil.SetSessionTTL(previous)
+
+	c := newSessionCache(t, s)
+	defer c.Close()
+
+	// 超过默认的30秒但仍在配置的有效期内
+	session := newSession("server-1", time.Now().Add(-time.Minute))
+	if err := c.Store(session.ServerID, session); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	checkSession(t, c, session)
+
+	got, err := c.Get(session.ServerID)
+	if err == nil && !got.IsValid() {
+		t.Errorf("session is not valid within the configured TTL")
+	}
+}
+
+// testSessionConcurrency 并发存储、读取和删除
+func testSessionConcurrency(t T, s *Suite) {
+	c := newSessionCache(t, s)
+	defer c.Close()
+
+	const workers, perWorker = 8, 10
+
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perWorker {
+				session := newSession(fmt.Sprintf("server-%d-%d", w, i), time.Now())
+				if err := c.Store(session.ServerID, session); err != nil {
+					t.Errorf("concurrent Store failed: %v", err)
+					continue
+				}
+				got, err := c.Get(session.ServerID)
+				if err != nil {
+					t.Errorf("concurrent Get failed: %v", err)
+				} else if got.AccessToken != session.AccessToken {
+					t.Errorf("concurrent Get returned another session")
+				}
+				if i%2 == 0 {
+					if err := c.Delete(session.ServerID); err != nil {
+						t.Errorf("concurrent Delete failed: %v", err)
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for w := range workers {
+		for i := range perWorker {
+			_, err := c.Get(fmt.Sprintf("server-%d-%d", w, i))
+			if i%2 == 0 && err == nil {
+				t.Errorf("session server-%d-%d still readable after Delete", w, i)
+			}
+			if i%2 == 1 && err != nil {
+				t.Errorf("session server-%d-%d lost: %v", w, i, err)
+			}
+		}
+	}
+}
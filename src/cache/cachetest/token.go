@@ -0,0 +1,685 @@
+// Package cachetest Token缓存用例
+package cachetest
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"yggdrasil-api-go/src/cache"
+	"yggdrasil-api-go/src/cache/revocation"
+	"yggdrasil-api-go/src/utils"
+	"yggdrasil-api-go/src/yggdrasil"
+)
+
+// TokenCases Token缓存的全部用例
+func TokenCases() []Case {
+	return []Case{
+		{Name: "Token/StoreAndGet", Run: testTokenStoreAndGet},
+		{Name: "Token/GetUnknown", Run: testTokenGetUnknown},
+		{Name: "Token/Delete", Run: testTokenDelete},
+		{Name: "Token/UserListing", Run: testTokenUserListing},
+		{Name: "Token/DeleteUserTokens", Run: testTokenDeleteUserTokens},
+		{Name: "Token/Expiry", Run: testTokenExpiry},
+		{Name: "Token/StoreExpired", Run: testTokenStoreExpired},
+		{Name: "Token/RefreshWindow", Run: testTokenRefreshWindow},
+		{Name: "Token/Concurrency", Run: testTokenConcurrency},
+		{Name: "Token/Limit", Run: testTokenLimit},
+		{Name: "Token/LimitConcurrency", Run: testTokenLimitConcurrency},
+		{Name: "Token/Revocations", Run: testTokenRevocations},
+		{Name: "Token/SharedRevocations", Run: testTokenSharedRevocations},
+		{Name: "Token/PeerInvalidation", Run: testTokenPeerInvalidation},
+	}
+}
+
+// newTokenCache 创建待测Token缓存
+func newTokenCache(t T, s *Suite) cache.TokenCache {
+	t.Helper()
+	c, err := s.NewTokenCache()
+	if err != nil {
+		t.Fatalf("failed to create token cache: %v", err)
+	}
+	return c
+}
+
+// newToken 生成没有刷新窗口的Token（JWT与ExpiresAt使用相同的有效期）
+func newToken(t T, userID, profileID string, ttl time.Duration) *yggdrasil.Token {
+	t.Helper()
+	return newRefreshableToken(t, userID, profileID, ttl, ttl)
+}
+
+// newRefreshableToken 生成Token（ttl后暂时失效，refreshTTL后彻底失效）
+func newRefreshableToken(t T, userID, profileID string, ttl, refreshTTL time.Duration) *yggdrasil.Token {
+	t.Helper()
+	accessToken, err := utils.GenerateJWT(userID, profileID, ttl, refreshTTL)
+	if err != nil {
+		t.Fatalf("failed to generate JWT: %v", err)
+	}
+
+	now := time.Now()
+	return &yggdrasil.Token{
+		AccessToken:      accessToken,
+		ClientToken:      utils.GenerateRandomUUID(),
+		ProfileID:        profileID,
+		Owner:            userID,
+		CreatedAt:        now,
+		ExpiresAt:        now.Add(ttl),
+		RefreshExpiresAt: now.Add(refreshTTL),
+	}
+}
+
+// storeToken 存储Token，失败时中止用例
+func storeToken(t T, c cache.TokenCache, token *yggdrasil.Token) {
+	t.Helper()
+	if err := c.Store(token); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+}
+
+// sameTime 比较时间（允许1秒误差，部分后端只保存到秒）
+func sameTime(a, b time.Time) bool {
+	d := a.Sub(b)
+	return d > -time.Second && d < time.Second
+}
+
+// checkToken 比较Token的各字段
+func checkToken(t T, label string, got, want *yggdrasil.Token) {
+	t.Helper()
+	if got.AccessToken != want.AccessToken {
+		t.Errorf("%s: AccessToken mismatch", label)
+	}
+	if got.ClientToken != want.ClientToken {
+		t.Errorf("%s: ClientToken = %q, want %q", label, got.ClientToken, want.ClientToken)
+	}
+	if got.ProfileID != want.ProfileID {
+		t.Errorf("%s: ProfileID = %q, want %q", label, got.ProfileID, want.ProfileID)
+	}
+	if got.Owner != want.Owner {
+		t.Errorf("%s: Owner = %q, want %q", label, got.Owner, want.Owner)
+	}
+	if !sameTime(got.CreatedAt, want.CreatedAt) {
+		t.Errorf("%s: CreatedAt = %v, want %v", label, got.CreatedAt, want.CreatedAt)
+	}
+	if !sameTime(got.ExpiresAt, want.ExpiresAt) {
+		t.Errorf("%s: ExpiresAt = %v, want %v", label, got.ExpiresAt, want.ExpiresAt)
+	}
+	if !sameTime(got.RefreshDeadline(), want.RefreshDeadline()) {
+		t.Errorf("%s: RefreshDeadline = %v, want %v", label, got.RefreshDeadline(), want.RefreshDeadline())
+	}
+}
+
+// checkUserTokens 检查用户Token列表与数量
+func checkUserTokens(t T, c cache.TokenCache, userID string, want ...*yggdrasil.Token) {
+	t.Helper()
+
+	tokens, err := c.GetUserTokens(userID)
+	if err != nil {
+		t.Errorf("GetUserTokens(%s) failed: %v", userID, err)
+		return
+	}
+
+	wanted := make(map[string]*yggdrasil.Token, len(want))
+	for _, token := range want {
+		wanted[token.AccessToken] = token
+	}
+
+	seen := make(map[string]bool, len(tokens))
+	for _, token := range tokens {
+		expected, ok := wanted[token.AccessToken]
+		switch {
+		case !ok:
+			t.Errorf("GetUserTokens(%s) returned unexpected token (owner %q, profile %q)", userID, token.Owner, token.ProfileID)
+		case seen[token.AccessToken]:
+			t.Errorf("GetUserTokens(%s) returned a token twice", userID)
+		default:
+			checkToken(t, "GetUserTokens("+userID+")", token, expected)
+		}
+		seen[token.AccessToken] = true
+	}
+	if len(seen) != len(wanted) {
+		t.Errorf("GetUserTokens(%s) returned %d tokens, want %d", userID, len(tokens), len(wanted))
+	}
+
+	count, err := c.GetUserTokenCount(userID)
+	if err != nil {
+		t.Errorf("GetUserTokenCount(%s) failed: %v", userID, err)
+	} else if count != len(want) {
+		t.Errorf("GetUserTokenCount(%s) = %d, want %d", userID, count, len(want))
+	}
+}
+
+// testTokenStoreAndGet 存储后可以取回所有字段（ProfileID和Owner以JWT声明为准）
+func testTokenStoreAndGet(t T, s *Suite) {
+	c := newTokenCache(t, s)
+	defer c.Close()
+
+	token := newToken(t, "1", "profile-1", time.Hour)
+	storeToken(t, c, token)
+
+	got, err := c.Get(token.AccessToken)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	checkToken(t, "Get", got, token)
+
+	// 存储时传入的ProfileID与JWT不一致时，以JWT为准
+	mismatched := newToken(t, "1", "profile-jwt", time.Hour)
+	mismatched.ProfileID = "profile-other"
+	storeToken(t, c, mismatched)
+
+	got, err = c.Get(mismatched.AccessToken)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if got.ProfileID != "profile-jwt" {
+		t.Errorf("Get: ProfileID = %q, want the JWT claim %q", got.ProfileID, "profile-jwt")
+	}
+
+	mismatched.ProfileID = "profile-jwt"
+	checkUserTokens(t, c, "1", token, mismatched)
+}
+
+// testTokenGetUnknown 获取未存储或无效的Token返回ErrTokenNotFound
+func testTokenGetUnknown(t T, s *Suite) {
+	c := newTokenCache(t, s)
+	defer c.Close()
+
+	token := newToken(t, "1", "", time.Hour)
+	if _, err := c.Get(token.AccessToken); !errors.Is(err, yggdrasil.ErrTokenNotFound) {
+		t.Errorf("Get of a token that was never stored: got %v, want ErrTokenNotFound", err)
+	}
+	if _, err := c.Get("not-a-jwt"); !errors.Is(err, yggdrasil.ErrTokenNotFound) {
+		t.Errorf("Get of a malformed token: got %v, want ErrTokenNotFound", err)
+	}
+}
+
+// testTokenDelete 删除单个Token
+func testTokenDelete(t T, s *Suite) {
+	c := newTokenCache(t, s)
+	defer c.Close()
+
+	deleted := newToken(t, "1", "", time.Hour)
+	kept := newToken(t, "1", "", time.Hour)
+	storeToken(t, c, deleted)
+	storeToken(t, c, kept)
+
+	if err := c.Delete(deleted.AccessToken); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := c.Get(deleted.AccessToken); err == nil {
+		t.Errorf("Get succeeded after Delete")
+	}
+	if _, err := c.Get(kept.AccessToken); err != nil {
+		t.Errorf("Delete removed another token: %v", err)
+	}
+	checkUserTokens(t, c, "1", kept)
+
+	// 重复删除和删除无效Token不报错
+	if err := c.Delete(deleted.AccessToken); err != nil {
+		t.Errorf("second Delete failed: %v", err)
+	}
+	if err := c.Delete("not-a-jwt"); err != nil {
+		t.Errorf("Delete of a malformed token failed: %v", err)
+	}
+}
+
+// testTokenUserListing 按用户列出Token
+func testTokenUserListing(t T, s *Suite) {
+	c := newTokenCache(t, s)
+	defer c.Close()
+
+	var userTokens []*yggdrasil.Token
+	for i := range 3 {
+		token := newToken(t, "1", fmt.Sprintf("profile-%d", i), time.Hour)
+		storeToken(t, c, token)
+		userTokens = append(userTokens, token)
+	}
+	other := newToken(t, "2", "", time.Hour)
+	storeToken(t, c, other)
+
+	// 重复存储同一Token不会重复计数
+	storeToken(t, c, userTokens[0])
+
+	checkUserTokens(t, c, "1", userTokens...)
+	checkUserTokens(t, c, "2", other)
+	checkUserTokens(t, c, "3")
+}
+
+// testTokenDeleteUserTokens 删除用户的所有Token
+func testTokenDeleteUserTokens(t T, s *Suite) {
+	c := newTokenCache(t, s)
+	defer c.Close()
+
+	var userTokens []*yggdrasil.Token
+	for range 3 {
+		token := newToken(t, "1", "", time.Hour)
+		storeToken(t, c, token)
+		userTokens = append(userTokens, token)
+	}
+	other := newToken(t, "2", "", time.Hour)
+	storeToken(t, c, other)
+
+	if err := c.DeleteUserTokens("1"); err != nil {
+		t.Fatalf("DeleteUserTokens failed: %v", err)
+	}
+	for i, token := range userTokens {
+		if _, err := c.Get(token.AccessToken); err == nil {
+			t.Errorf("token %d still readable after DeleteUserTokens", i)
+		}
+	}
+	checkUserTokens(t, c, "1")
+	checkUserTokens(t, c, "2", other)
+
+	if err := c.DeleteUserTokens("3"); err != nil {
+		t.Errorf("DeleteUserTokens of an unknown user failed: %v", err)
+	}
+}
+
+// testTokenExpiry 过期的Token不可获取、不被列出
+func testTokenExpiry(t T, s *Suite) {
+	c := newTokenCache(t, s)
+	defer c.Close()
+
+	shortLived := newToken(t, "1", "", time.Second)
+	longLived := newToken(t, "1", "", time.Hour)
+	storeToken(t, c, shortLived)
+	storeToken(t, c, longLived)
+	checkUserTokens(t, c, "1", shortLived, longLived)
+
+	wait(s, 2500*time.Millisecond)
+
+	if _, err := c.Get(shortLived.AccessToken); err == nil {
+		t.Errorf("Get of an expired token succeeded")
+	}
+	checkUserTokens(t, c, "1", longLived)
+
+	if err := c.CleanupExpired(); err != nil {
+		t.Errorf("CleanupExpired failed: %v", err)
+	}
+	if _, err := c.Get(longLived.AccessToken); err != nil {
+		t.Errorf("CleanupExpired removed a valid token: %v", err)
+	}
+	checkUserTokens(t, c, "1", longLived)
+}
+
+// testTokenStoreExpired 已过期的Token不可存储（或存储后不可见）
+func testTokenStoreExpired(t T, s *Suite) {
+	c := newTokenCache(t, s)
+	defer c.Close()
+
+	token := newToken(t, "1", "", -time.Minute)
+	_ = c.Store(token)
+
+	if _, err := c.Get(token.AccessToken); err == nil {
+		t.Errorf("Get of an already expired token succeeded")
+	}
+	checkUserTokens(t, c, "1")
+}
+
+// testTokenRefreshWindow 暂时失效的Token在刷新期限前仍可读取和列出，之后彻底失效
+func testTokenRefreshWindow(t T, s *Suite) {
+	c := newTokenCache(t, s)
+	defer c.Close()
+
+	token := newRefreshableToken(t, "1", "profile-1", time.Second, 3*time.Second)
+	storeToken(t, c, token)
+
+	wait(s, 1500*time.Millisecond)
+
+	got, err := c.Get(token.AccessToken)
+	if err != nil {
+		t.Fatalf("Get of a temporarily invalid token failed: %v", err)
+	}
+	checkToken(t, "Get", got, token)
+	if got.IsValid() {
+		t.Errorf("temporarily invalid token reported as valid")
+	}
+	if !got.IsRefreshable() {
+		t.Errorf("temporarily invalid token reported as not refreshable")
+	}
+	checkUserTokens(t, c, "1", token)
+
+	wait(s, 2500*time.Millisecond)
+
+	if _, err := c.Get(token.AccessToken); err == nil {
+		t.Errorf("Get succeeded after the refresh window")
+	}
+	if err := c.CleanupExpired(); err != nil {
+		t.Errorf("CleanupExpired failed: %v", err)
+	}
+	checkUserTokens(t, c, "1")
+}
+
+// testTokenConcurrency 并发存储、读取和删除
+func testTokenConcurrency(t T, s *Suite) {
+	c := newTokenCache(t, s)
+	defer c.Close()
+
+	const workers, perWorker = 8, 10
+
+	tokens := make([]*yggdrasil.Token, workers*perWorker)
+	for i := range tokens {
+		tokens[i] = newToken(t, "1", fmt.Sprintf("profile-%d", i), time.Hour)
+	}
+
+	// 并发存储
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, token := range tokens[w*perWorker : (w+1)*perWorker] {
+				if err := c.Store(token); err != nil {
+					t.Errorf("concurrent Store failed: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	checkUserTokens(t, c, "1", tokens...)
+
+	// 并发删除一半，同时读取另一半
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := w * perWorker; i < (w+1)*perWorker; i++ {
+				if i%2 == 0 {
+					if err := c.Delete(tokens[i].AccessToken); err != nil {
+						t.Errorf("concurrent Delete failed: %v", err)
+					}
+				} else if _, err := c.Get(tokens[i].AccessToken); err != nil {
+					t.Errorf("concurrent Get failed: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	var remaining []*yggdrasil.Token
+	for i, token := range tokens {
+		if i%2 == 1 {
+			remaining = append(remaining, token)
+		}
+	}
+	checkUserTokens(t, c, "1", remaining...)
+}
+
+// testTokenLimit 超出数量限制时按CreatedAt撤销最旧的Token（新存储的Token总是保留）
+func testTokenLimit(t T, s *Suite) {
+	c := newTokenCache(t, s)
+	defer c.Close()
+
+	const limit = 3
+
+	// tokens[i]创建于(5-i)分钟前，按打乱的顺序存储
+	tokens := make([]*yggdrasil.Token, 5)
+	for i := range tokens {
+		tokens[i] = newToken(t, "1", fmt.Sprintf("profile-%d", i), time.Hour)
+		tokens[i].CreatedAt = time.Now().Add(-time.Duration(5-i) * time.Minute)
+	}
+	other := newToken(t, "2", "profile-other", time.Hour)
+	storeToken(t, c, other)
+
+	steps := []struct {
+		index   int
+		evicted int
+	}{
+		{2, 0}, {0, 0}, {4, 0},
+		{1, 1}, // 撤销tokens[0]
+		{3, 1}, // 撤销tokens[1]（新存储的Token即使较旧也保留）
+	}
+	for _, step := range steps {
+		evicted, err := c.StoreWithLimit(tokens[step.index], limit)
+		if err != nil {
+			t.Fatalf("StoreWithLimit(tokens[%d]) failed: %v", step.index, err)
+		}
+		if evicted != step.evicted {
+			t.Errorf("StoreWithLimit(tokens[%d]) evicted %d tokens, want %d", step.index, evicted, step.evicted)
+		}
+	}
+
+	checkUserTokens(t, c, "1", tokens[2], tokens[3], tokens[4])
+	for _, token := range tokens[:2] {
+		if _, err := c.Get(token.AccessToken); err == nil {
+			t.Errorf("Get succeeded for an evicted token")
+		}
+	}
+	checkUserTokens(t, c, "2", other)
+
+	// 不限制时不撤销
+	extra := newToken(t, "1", "profile-extra", time.Hour)
+	if evicted, err := c.StoreWithLimit(extra, 0); err != nil || evicted != 0 {
+		t.Errorf("StoreWithLimit(limit=0) = %d, %v, want 0, nil", evicted, err)
+	}
+	checkUserTokens(t, c, "1", tokens[2], tokens[3], tokens[4], extra)
+}
+
+// testTokenLimitConcurrency 并发登录时Token数量不超过限制，撤销总数与超出数量一致
+func testTokenLimitConcurrency(t T, s *Suite) {
+	c := newTokenCache(t, s)
+	defer c.Close()
+
+	const workers, perWorker, limit = 8, 5, 5
+
+	var evictedTotal atomic.Int64
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perWorker {
+				token := newToken(t, "1", fmt.Sprintf("profile-%d-%d", w, i), time.Hour)
+				evicted, err := c.StoreWithLimit(token, limit)
+				if err != nil {
+					t.Errorf("concurrent StoreWithLimit failed: %v", err)
+					continue
+				}
+				evictedTotal.Add(int64(evicted))
+			}
+		}()
+	}
+	wg.Wait()
+
+	tokens, err := c.GetUserTokens("1")
+	if err != nil {
+		t.Fatalf("GetUserTokens failed: %v", err)
+	}
+	if len(tokens) != limit {
+		t.Errorf("user has %d tokens after concurrent logins, want %d", len(tokens), limit)
+	}
+	if count, err := c.GetUserTokenCount("1"); err != nil || count != len(tokens) {
+		t.Errorf("GetUserTokenCount = %d, %v, want %d", count, err, len(tokens))
+	}
+	if got, want := evictedTotal.Load(), int64(workers*perWorker-limit); got != want {
+		t.Errorf("evicted %d tokens in total, want %d", got, want)
+	}
+	for _, token := range tokens {
+		if _, err := c.Get(token.AccessToken); err != nil {
+			t.Errorf("remaining token is not readable: %v", err)
+		}
+	}
+}
+
+// revocationRecorder 记录收到的撤销事件
+type revocationRecorder struct {
+	entries map[string]time.Time
+	mu      sync.Mutex
+}
+
+// subscribeRevocations 订阅缓存的撤销事件
+func subscribeRevocations(c cache.TokenCache) *revocationRecorder {
+	r := &revocationRecorder{entries: make(map[string]time.Time)}
+	c.SubscribeRevocations(func(entries []revocation.Entry) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for _, entry := range entries {
+			r.entries[entry.TokenID] = entry.ExpiresAt
+		}
+	})
+	return r
+}
+
+// has 检查是否收到了Token的撤销事件（最多等待timeout）
+func (r *revocationRecorder) has(t T, token *yggdrasil.Token, timeout time.Duration) bool {
+	t.Helper()
+	claims, err := utils.ValidateJWT(token.AccessToken)
+	if err != nil {
+		t.Fatalf("failed to parse JWT: %v", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		r.mu.Lock()
+		expiresAt, ok := r.entries[claims.TokenID]
+		r.mu.Unlock()
+		if ok {
+			if !sameTime(expiresAt, token.RefreshDeadline()) {
+				t.Errorf("revocation expiry = %v, want %v", expiresAt, token.RefreshDeadline())
+			}
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// testTokenRevocations Delete、DeleteUserTokens和数量限制淘汰立即产生撤销事件
+func testTokenRevocations(t T, s *Suite) {
+	c := newTokenCache(t, s)
+	defer c.Close()
+
+	r := subscribeRevocations(c)
+
+	deleted := newToken(t, "1", "profile-1", time.Hour)
+	evicted := newToken(t, "1", "profile-2", time.Hour)
+	evicted.CreatedAt = time.Now().Add(-time.Minute)
+	kept := newToken(t, "1", "profile-3", time.Hour)
+	signedOut := newToken(t, "2", "profile-4", time.Hour)
+	for _, token := range []*yggdrasil.Token{deleted, evicted, kept, signedOut} {
+		storeToken(t, c, token)
+	}
+
+	if err := c.Delete(deleted.AccessToken); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if !r.has(t, deleted, 0) {
+		t.Errorf("Delete did not publish a revocation")
+	}
+
+	if _, err := c.StoreWithLimit(newToken(t, "1", "profile-5", time.Hour), 2); err != nil {
+		t.Fatalf("StoreWithLimit failed: %v", err)
+	}
+	if !r.has(t, evicted, 0) {
+		t.Errorf("StoreWithLimit eviction did not publish a revocation")
+	}
+	if r.has(t, kept, 0) {
+		t.Errorf("StoreWithLimit published a revocation for a kept token")
+	}
+
+	if err := c.DeleteUserTokens("2"); err != nil {
+		t.Fatalf("DeleteUserTokens failed: %v", err)
+	}
+	if !r.has(t, signedOut, 0) {
+		t.Errorf("DeleteUserTokens did not publish a revocation")
+	}
+}
+
+// testTokenSharedRevocations 共享后端的实例能收到其他实例产生的撤销事件（包括订阅前产生的）
+func testTokenSharedRevocations(t T, s *Suite) {
+	if s.NewPeerTokenCache == nil {
+		return
+	}
+
+	c := newTokenCache(t, s)
+	defer c.Close()
+
+	before := newToken(t, "1", "profile-1", time.Hour)
+	after := newToken(t, "1", "profile-2", time.Hour)
+	storeToken(t, c, before)
+	storeToken(t, c, after)
+
+	if err := c.Delete(before.AccessToken); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	peer, err := s.NewPeerTokenCache()
+	if err != nil {
+		t.Fatalf("failed to create peer token cache: %v", err)
+	}
+	defer peer.Close()
+
+	r := subscribeRevocations(peer)
+	if !r.has(t, before, 0) {
+		t.Errorf("peer did not load a revocation made before it subscribed")
+	}
+
+	if err := c.Delete(after.AccessToken); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if !r.has(t, after, 3*time.Second) {
+		t.Errorf("peer did not receive a revocation made by another instance")
+	}
+}
+
+// testTokenPeerInvalidation 一个实例删除的Token在有限时间内无法从其他实例读取（包括已读取过该Token的实例）
+func testTokenPeerInvalidation(t T, s *Suite) {
+	if s.NewPeerTokenCache == nil {
+		return
+	}
+
+	c := newTokenCache(t, s)
+	defer c.Close()
+
+	peer, err := s.NewPeerTokenCache()
+	if err != nil {
+		t.Fatalf("failed to create peer token cache: %v", err)
+	}
+	defer peer.Close()
+
+	deleted := newToken(t, "1", "profile-1", time.Hour)
+	signedOut := newToken(t, "2", "profile-2", time.Hour)
+	storeToken(t, c, deleted)
+	storeToken(t, c, signedOut)
+
+	// 先在peer上读取，使分级缓存的L1持有这些Token
+	for _, token := range []*yggdrasil.Token{deleted, signedOut} {
+		if _, err := peer.Get(token.AccessToken); err != nil {
+			t.Fatalf("peer Get failed: %v", err)
+		}
+	}
+
+	if err := c.Delete(deleted.AccessToken); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := c.DeleteUserTokens("2"); err != nil {
+		t.Fatalf("DeleteUserTokens failed: %v", err)
+	}
+
+	for _, token := range []*yggdrasil.Token{deleted, signedOut} {
+		if !eventually(3*time.Second, func() bool {
+			_, err := peer.Get(token.AccessToken)
+			return err != nil
+		}) {
+			t.Errorf("peer still returns token %s deleted by another instance", token.Owner)
+		}
+	}
+}
+
+// eventually 在timeout内轮询直到cond成立
+func eventually(timeout time.Duration, cond func() bool) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if cond() {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
@@ -0,0 +1,163 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// 支持的数据库驱动
+const (
+	driverSQLite   = "sqlite"
+	driverMySQL    = "mysql"
+	driverPostgres = "postgres"
+)
+
+// defaultCleanupBatchSize 清理过期记录时每批删除的行数
+const defaultCleanupBatchSize = 1000
+
+// Connection 数据库连接（可通过Registry在Token缓存和Session缓存之间共享连接池）
+type Connection struct {
+	db     *gorm.DB
+	driver string
+}
+
+// Connect 根据选项创建数据库连接
+// 选项：dsn、driver（sqlite、mysql、postgres，未指定时根据DSN推断）、debug、
+// max_open_conns、max_idle_conns、conn_max_lifetime、conn_max_idle_time
+func Connect(options map[string]any) (*Connection, error) {
+	dsn, ok := options["dsn"].(string)
+	if !ok || dsn == "" {
+		return nil, fmt.Errorf("database DSN is required")
+	}
+
+	driver, _ := options["driver"].(string)
+	if driver == "" {
+		driver = detectDriver(dsn)
+	}
+
+	var dialector gorm.Dialector
+	switch driver {
+	case driverSQLite:
+		dialector = sqlite.Open(dsn)
+	case driverMySQL:
+		dialector = mysql.Open(dsn)
+	case driverPostgres, "postgresql", "pgsql":
+		driver = driverPostgres
+		dialector = postgres.Open(dsn)
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %s", driver)
+	}
+
+	// 获取debug配置，默认为false
+	debug, _ := options["debug"].(bool)
+	logLevel := logger.Silent
+	if debug {
+		logLevel = logger.Info
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger: logger.Default.LogMode(logLevel),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	if err := configurePool(db, options); err != nil {
+		if sqlDB, dbErr := db.DB(); dbErr == nil {
+			sqlDB.Close()
+		}
+		return nil, err
+	}
+
+	return &Connection{db: db, driver: driver}, nil
+}
+
+// detectDriver 根据DSN推断数据库驱动（兼容未指定driver的旧配置）
+func detectDriver(dsn string) string {
+	switch {
+	case strings.HasPrefix(dsn, "file:") || strings.HasSuffix(dsn, ".db"):
+		return driverSQLite
+	case strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") ||
+		strings.HasPrefix(dsn, "host=") || strings.Contains(dsn, " dbname="):
+		return driverPostgres
+	default:
+		return driverMySQL
+	}
+}
+
+// configurePool 设置连接池参数（未设置的选项保持database/sql的默认值）
+func configurePool(db *gorm.DB, options map[string]any) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database handle: %w", err)
+	}
+
+	if n, ok := options["max_open_conns"].(int); ok {
+		sqlDB.SetMaxOpenConns(n)
+	}
+	if n, ok := options["max_idle_conns"].(int); ok {
+		sqlDB.SetMaxIdleConns(n)
+	}
+
+	lifetime, err := durationOption(options, "conn_max_lifetime")
+	if err != nil {
+		return err
+	}
+	if lifetime > 0 {
+		sqlDB.SetConnMaxLifetime(lifetime)
+	}
+
+	idleTime, err := durationOption(options, "conn_max_idle_time")
+	if err != nil {
+		return err
+	}
+	if idleTime > 0 {
+		sqlDB.SetConnMaxIdleTime(idleTime)
+	}
+	return nil
+}
+
+// durationOption 读取时长选项（time.Duration或"30m"形式的字符串）
+func durationOption(options map[string]any, name string) (time.Duration, error) {
+	switch v := options[name].(type) {
+	case time.Duration:
+		return v, nil
+	case string:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s: %w", name, err)
+		}
+		return d, nil
+	default:
+		return 0, nil
+	}
+}
+
+// cleanupBatchSize 读取清理批大小选项
+func cleanupBatchSize(options map[string]any) int {
+	if n, ok := options["cleanup_batch_size"].(int); ok && n > 0 {
+		return n
+	}
+	return defaultCleanupBatchSize
+}
+
+// Driver 获取数据库驱动名称
+func (c *Connection) Driver() string {
+	return c.driver
+}
+
+// Close 关闭连接
+func (c *Connection) Close() error {
+	sqlDB, err := c.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
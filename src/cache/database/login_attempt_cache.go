@@ -0,0 +1,173 @@
+// Package database 数据库登录失败记录缓存实现
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"yggdrasil-api-go/src/yggdrasil"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CacheLoginAttempt 数据库缓存登录失败记录表结构
+type CacheLoginAttempt struct {
+	AttemptKey  string    `gorm:"primaryKey;column:attempt_key;size:255"`
+	Failures    int       `gorm:"column:failures;not null;default:0"`
+	LastFailure time.Time `gorm:"column:last_failure;not null"`
+	ExpiresAt   time.Time `gorm:"index;column:expires_at;not null"`
+
+	// 用于动态表名
+	tablePrefix string `gorm:"-"`
+}
+
+// TableName 指定表名（支持前缀）
+func (a CacheLoginAttempt) TableName() string {
+	if a.tablePrefix != "" {
+		return a.tablePrefix + "login_attempts"
+	}
+	return "cache_login_attempts"
+}
+
+// LoginAttemptCache 数据库登录失败记录缓存（多个实例共用）
+type LoginAttemptCache struct {
+	db        *gorm.DB
+	tableName string
+	owned     bool // 连接由缓存自身创建（共享连接由Registry负责关闭）
+	batchSize int  // 清理过期记录时每批删除的行数
+}
+
+// NewLoginAttemptCache 创建数据库登录失败记录缓存（连接选项见Connect）
+func NewLoginAttemptCache(options map[string]any) (*LoginAttemptCache, error) {
+	conn, err := Connect(options)
+	if err != nil {
+		return nil, err
+	}
+	cache, err := newLoginAttemptCache(conn, options, true)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return cache, nil
+}
+
+// NewLoginAttemptCacheWithConnection 使用共享连接创建数据库登录失败记录缓存
+func NewLoginAttemptCacheWithConnection(conn *Connection, options map[string]any) (*LoginAttemptCache, error) {
+	return newLoginAttemptCache(conn, options, false)
+}
+
+// newLoginAttemptCache 创建数据库登录失败记录缓存并迁移表结构
+func newLoginAttemptCache(conn *Connection, options map[string]any, owned bool) (*LoginAttemptCache, error) {
+	tablePrefix, _ := options["table_prefix"].(string)
+	tableName := CacheLoginAttempt{tablePrefix: tablePrefix}.TableName()
+
+	if err := conn.db.Table(tableName).AutoMigrate(&CacheLoginAttempt{}); err != nil {
+		return nil, fmt.Errorf("failed to migrate %s table: %w", tableName, err)
+	}
+
+	return &LoginAttemptCache{
+		db:        conn.db,
+		tableName: tableName,
+		owned:     owned,
+		batchSize: cleanupBatchSize(options),
+	}, nil
+}
+
+// RecordFailure 增加失败次数
+// 先插入（已存在时忽略）再用单条UPDATE递增，并发的失败都会被计入；已过期的记录从1开始计数
+func (c *LoginAttemptCache) RecordFailure(key string, ttl time.Duration) (*yggdrasil.LoginAttempts, error) {
+	now := time.Now()
+	record := &CacheLoginAttempt{
+		AttemptKey:  key,
+		LastFailure: now,
+		ExpiresAt:   now,
+	}
+
+	err := c.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table(c.tableName).Clauses(clause.OnConflict{DoNothing: true}).Create(record).Error; err != nil {
+			return err
+		}
+		err := tx.Table(c.tableName).Where("attempt_key = ?", key).Updates(map[string]any{
+			"failures":     gorm.Expr("CASE WHEN expires_at > ? THEN failures + 1 ELSE 1 END", now),
+			"last_failure": now,
+			"expires_at":   now.Add(ttl),
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Table(c.tableName).Where("attempt_key = ?", key).First(record).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record login failure: %w", err)
+	}
+
+	return &yggdrasil.LoginAttempts{
+		Failures:    record.Failures,
+		LastFailure: record.LastFailure,
+	}, nil
+}
+
+// Get 获取失败记录
+func (c *LoginAttemptCache) Get(key string) (*yggdrasil.LoginAttempts, error) {
+	var record CacheLoginAttempt
+	result := c.db.Table(c.tableName).Where("attempt_key = ? AND expires_at > ?", key, time.Now()).Limit(1).Find(&record)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get login attempts: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &yggdrasil.LoginAttempts{
+		Failures:    record.Failures,
+		LastFailure: record.LastFailure,
+	}, nil
+}
+
+// Reset 清除失败记录
+func (c *LoginAttemptCache) Reset(key string) error {
+	if err := c.db.Table(c.tableName).Where("attempt_key = ?", key).Delete(&CacheLoginAttempt{}).Error; err != nil {
+		return fmt.Errorf("failed to reset login attempts: %w", err)
+	}
+	return nil
+}
+
+// CleanupExpired 清理过期记录（按expires_at索引分批删除）
+func (c *LoginAttemptCache) CleanupExpired() error {
+	now := time.Now()
+	for {
+		var keys []string
+		if err := c.db.Table(c.tableName).Where("expires_at <= ?", now).
+			Order("expires_at").Limit(c.batchSize).Pluck("attempt_key", &keys).Error; err != nil {
+			return fmt.Errorf("failed to cleanup expired login attempts: %w", err)
+		}
+		if len(keys) == 0 {
+			return nil
+		}
+		// 再次检查过期时间，避免删除查询之后重新记录的失败
+		if err := c.db.Table(c.tableName).Where("attempt_key IN ? AND expires_at <= ?", keys, now).
+			Delete(&CacheLoginAttempt{}).Error; err != nil {
+			return fmt.Errorf("failed to cleanup expired login attempts: %w", err)
+		}
+		if len(keys) < c.batchSize {
+			return nil
+		}
+	}
+}
+
+// Close 关闭缓存连接
+func (c *LoginAttemptCache) Close() error {
+	if c.db != nil && c.owned {
+		sqlDB, err := c.db.DB()
+		if err == nil {
+			sqlDB.Close()
+		}
+	}
+	return nil
+}
+
+// GetCacheType 获取缓存类型
+func (c *LoginAttemptCache) GetCacheType() string {
+	return "database"
+}
@@ -3,16 +3,12 @@ package database
 
 import (
 	"fmt"
-	"strings"
 	"sync"
 	"time"
 
 	"yggdrasil-api-go/src/yggdrasil"
 
-	"gorm.io/driver/mysql"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
 // CacheSession 数据库缓存Session表结构（优化设计）
@@ -24,6 +20,8 @@ type CacheSession struct {
 	ClientIP    string `gorm:"size:45;column:client_ip;not null" json:"client_ip"`                          // 客户端IP
 	AccessToken string `gorm:"size:512;column:access_token;not null;default:''" json:"access_token"` // AccessToken（验证用）
 	ProfileID   string `gorm:"size:50;column:profile_id;not null;default:''" json:"profile_id"`                         // 角色ID（冗余字段，暂不使用）
+	TokenID     string `gorm:"size:50;column:token_id;not null;default:''" json:"token_id"`     // 令牌ID（JWT.yggt）
+	UserAgent   string `gorm:"size:255;column:user_agent;not null;default:''" json:"user_agent"` // 客户端User-Agent
 
 	// 时间信息
 	CreatedAt time.Time `gorm:"column:created_at;not null" json:"created_at"`
@@ -45,52 +43,41 @@ func (cs CacheSession) TableName() string {
 type SessionCache struct {
 	db          *gorm.DB
 	tablePrefix string
+	owned       bool // 连接由缓存自身创建（共享连接由Registry负责关闭）
+	batchSize   int  // 清理过期记录时每批删除的行数
 	mu          sync.RWMutex
 }
 
-// NewSessionCache 创建数据库Session缓存
+// NewSessionCache 创建数据库Session缓存（连接选项见Connect）
 func NewSessionCache(options map[string]any) (*SessionCache, error) {
-	dsn, ok := options["dsn"].(string)
-	if !ok || dsn == "" {
-		return nil, fmt.Errorf("database DSN is required")
+	conn, err := Connect(options)
+	if err != nil {
+		return nil, err
 	}
-
-	// 获取表前缀
-	tablePrefix, _ := options["table_prefix"].(string)
-
-	// 获取debug配置，默认为false
-	debug, _ := options["debug"].(bool)
-
-	// 确定日志级别
-	logLevel := logger.Silent
-	if debug {
-		logLevel = logger.Info
+	cache, err := newSessionCache(conn, options, true)
+	if err != nil {
+		conn.Close()
+		return nil, err
 	}
+	return cache, nil
+}
 
-	// 根据DSN自动选择数据库驱动
-	var db *gorm.DB
-	var err error
-
-	if strings.HasPrefix(dsn, "file:") || strings.HasSuffix(dsn, ".db") {
-		// SQLite数据库
-		db, err = gorm.Open(sqlite.Open(dsn), &gorm.Config{
-			Logger: logger.Default.LogMode(logLevel),
-		})
-	} else {
-		// MySQL数据库
-		db, err = gorm.Open(mysql.Open(dsn), &gorm.Config{
-			Logger: logger.Default.LogMode(logLevel),
-		})
-	}
+// NewSessionCacheWithConnection 使用共享连接创建数据库Session缓存
+func NewSessionCacheWithConnection(conn *Connection, options map[string]any) (*SessionCache, error) {
+	return newSessionCache(conn, options, false)
+}
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to database: %w", err)
-	}
+// newSessionCache 创建数据库Session缓存并迁移表结构
+func newSessionCache(conn *Connection, options map[string]any, owned bool) (*SessionCache, error) {
+	// 获取表前缀
+	tablePrefix, _ := options["table_prefix"].(string)
 
-	// 创建SessionCache实例
+	db := conn.db
 	cache := &SessionCache{
 		db:          db,
 		tablePrefix: tablePrefix,
+		owned:       owned,
+		batchSize:   cleanupBatchSize(options),
 	}
 
 	// 创建带前缀的表结构实例用于迁移
@@ -118,15 +105,17 @@ func (c *SessionCache) Store(serverID string, session *yggdrasil.Session) error
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	// Session默认30秒过期（与Yggdrasil标准一致）
-	expiresAt := time.Now().Add(30 * time.Second)
+	// Session自创建起SessionTTL后过期
+	expiresAt := session.ExpiresAt()
 
 	// 存储到数据库（只存储必要信息，不存储AccessToken和ProfileID）
 	cacheSession := c.newCacheSession()
 	cacheSession.ServerID = serverID
 	cacheSession.AccessToken = session.AccessToken
 	cacheSession.ProfileID = session.ProfileID
+	cacheSession.TokenID = session.TokenID
 	cacheSession.ClientIP = session.ClientIP
+	cacheSession.UserAgent = session.UserAgent
 	cacheSession.CreatedAt = session.CreatedAt
 	cacheSession.ExpiresAt = expiresAt
 
@@ -148,7 +137,7 @@ func (c *SessionCache) Get(serverID string) (*yggdrasil.Session, error) {
 	result := c.db.Table(cacheSession.TableName()).Where("server_id = ? AND expires_at > ?", serverID, time.Now()).First(cacheSession)
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("session not found")
+			return nil, yggdrasil.ErrSessionNotFound
 		}
 		return nil, fmt.Errorf("failed to get session: %w", result.Error)
 	}
@@ -157,8 +146,10 @@ func (c *SessionCache) Get(serverID string) (*yggdrasil.Session, error) {
 	session := &yggdrasil.Session{
 		ServerID:    cacheSession.ServerID,
 		AccessToken: cacheSession.AccessToken,
+		TokenID:     cacheSession.TokenID,
 		ProfileID:   cacheSession.ProfileID,
 		ClientIP:    cacheSession.ClientIP,
+		UserAgent:   cacheSession.UserAgent,
 		CreatedAt:   cacheSession.CreatedAt,
 	}
 
@@ -179,18 +170,29 @@ func (c *SessionCache) Delete(serverID string) error {
 	return nil
 }
 
-// CleanupExpired 清理过期Session
+// CleanupExpired 清理过期Session（按expires_at索引分批删除，每批是独立的短语句）
 func (c *SessionCache) CleanupExpired() error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	cacheSession := c.newCacheSession()
-	result := c.db.Table(cacheSession.TableName()).Where("expires_at <= ?", time.Now()).Delete(cacheSession)
-	if result.Error != nil {
-		return fmt.Errorf("failed to cleanup expired sessions: %w", result.Error)
+	now := time.Now()
+
+	tableName := c.newCacheSession().TableName()
+	for {
+		var serverIDs []string
+		if err := c.db.Table(tableName).Where("expires_at <= ?", now).
+			Order("expires_at").Limit(c.batchSize).Pluck("server_id", &serverIDs).Error; err != nil {
+			return fmt.Errorf("failed to cleanup expired sessions: %w", err)
+		}
+		if len(serverIDs) == 0 {
+			return nil
+		}
+		// 再次检查过期时间，避免删除查询之后被重新写入的Session
+		if err := c.db.Table(tableName).Where("server_id IN ? AND expires_at <= ?", serverIDs, now).
+			Delete(&CacheSession{}).Error; err != nil {
+			return fmt.Errorf("failed to cleanup expired sessions: %w", err)
+		}
+		if len(serverIDs) < c.batchSize {
+			return nil
+		}
 	}
-
-	return nil
 }
 
 // Close 关闭缓存连接
@@ -198,7 +200,7 @@ func (c *SessionCache) Close() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if c.db != nil {
+	if c.db != nil && c.owned {
 		sqlDB, err := c.db.DB()
 		if err == nil {
 			sqlDB.Close()
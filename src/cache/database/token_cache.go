@@ -3,17 +3,15 @@ package database
 
 import (
 	"fmt"
-	"strings"
 	"sync"
 	"time"
 
+	"yggdrasil-api-go/src/cache/revocation"
 	"yggdrasil-api-go/src/utils"
 	"yggdrasil-api-go/src/yggdrasil"
 
-	"gorm.io/driver/mysql"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
+	"gorm.io/gorm/clause"
 )
 
 // CacheToken 数据库缓存Token表结构（优化设计）
@@ -23,18 +21,28 @@ type CacheToken struct {
 	TokenID string `gorm:"primaryKey;column:token_id;size:50" json:"token_id"` // TokenID（JWT.yggt）
 
 	// Token信息
+	AccessToken string `gorm:"column:access_token;type:text" json:"access_token"`  // 完整的AccessToken（用于列出用户Token）
 	ClientToken string `gorm:"column:client_token;size:255" json:"client_token"` // ClientToken（验证用）
     ProfileID   string `gorm:"column:profile_id;size:50" json:"profile_id"`   // ProfileID（从JWT中提取）
 
 	// 时间信息
-	CreatedAt time.Time `gorm:"column:created_at;not null" json:"created_at"`
-	ExpiresAt time.Time `gorm:"index;column:expires_at;not null" json:"expires_at"`
-	UpdatedAt time.Time `gorm:"column:updated_at;not null" json:"updated_at"`
+	CreatedAt  time.Time  `gorm:"column:created_at;not null" json:"created_at"`
+	ExpiresAt  time.Time  `gorm:"index;column:expires_at;not null" json:"expires_at"` // 彻底失效时间（刷新期限）
+	ValidUntil *time.Time `gorm:"column:valid_until" json:"valid_until"`              // 有效期限（之后暂时失效，为空时与expires_at相同）
+	UpdatedAt  time.Time  `gorm:"column:updated_at;not null" json:"updated_at"`
 
 	// 用于动态表名
 	tablePrefix string `gorm:"-"`
 }
 
+// expiry 获取Token的有效期限和刷新期限
+func (ct CacheToken) expiry() (expiresAt, refreshExpiresAt time.Time) {
+	if ct.ValidUntil != nil {
+		return *ct.ValidUntil, ct.ExpiresAt
+	}
+	return ct.ExpiresAt, ct.ExpiresAt
+}
+
 // TableName 指定表名（支持前缀）
 func (ct CacheToken) TableName() string {
 	if ct.tablePrefix != "" {
@@ -43,56 +51,72 @@ func (ct CacheToken) TableName() string {
 	return "cache_tokens"
 }
 
+// RevokedToken 已撤销Token表结构（供共享同一数据库的实例同步撤销事件）
+type RevokedToken struct {
+	ID        uint64    `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	TokenID   string    `gorm:"column:token_id;size:50;not null" json:"token_id"`   // TokenID（JWT.yggt）
+	ExpiresAt time.Time `gorm:"index;column:expires_at;not null" json:"expires_at"` // JWT过期时间（之后可删除记录）
+	CreatedAt time.Time `gorm:"column:created_at;not null" json:"created_at"`
+
+	// 用于动态表名
+	tablePrefix string `gorm:"-"`
+}
+
+// TableName 指定表名（支持前缀）
+func (rt RevokedToken) TableName() string {
+	if rt.tablePrefix != "" {
+		return rt.tablePrefix + "revoked_tokens"
+	}
+	return "cache_revoked_tokens"
+}
+
+// revocationPollInterval 轮询其他实例撤销记录的间隔
+const revocationPollInterval = time.Second
+
 // TokenCache 数据库Token缓存
 type TokenCache struct {
 	db          *gorm.DB
 	tablePrefix string
+	owned       bool // 连接由缓存自身创建（共享连接由Registry负责关闭）
+	batchSize   int  // 清理过期记录时每批删除的行数
 	mu          sync.RWMutex
+
+	revocations      revocation.Notifier
+	listenOnce       sync.Once
+	stopPolling      chan struct{} // 停止轮询撤销记录
+	revokedWatermark uint64        // 已处理的最大撤销记录ID
 }
 
-// NewTokenCache 创建数据库Token缓存
+// NewTokenCache 创建数据库Token缓存（连接选项见Connect）
 func NewTokenCache(options map[string]any) (*TokenCache, error) {
-	dsn, ok := options["dsn"].(string)
-	if !ok || dsn == "" {
-		return nil, fmt.Errorf("database DSN is required")
+	conn, err := Connect(options)
+	if err != nil {
+		return nil, err
 	}
-
-	// 获取表前缀
-	tablePrefix, _ := options["table_prefix"].(string)
-
-	// 获取debug配置，默认为false
-	debug, _ := options["debug"].(bool)
-
-	// 确定日志级别
-	logLevel := logger.Silent
-	if debug {
-		logLevel = logger.Info
+	cache, err := newTokenCache(conn, options, true)
+	if err != nil {
+		conn.Close()
+		return nil, err
 	}
+	return cache, nil
+}
 
-	// 根据DSN自动选择数据库驱动
-	var db *gorm.DB
-	var err error
-
-	if strings.HasPrefix(dsn, "file:") || strings.HasSuffix(dsn, ".db") {
-		// SQLite数据库
-		db, err = gorm.Open(sqlite.Open(dsn), &gorm.Config{
-			Logger: logger.Default.LogMode(logLevel),
-		})
-	} else {
-		// MySQL数据库
-		db, err = gorm.Open(mysql.Open(dsn), &gorm.Config{
-			Logger: logger.Default.LogMode(logLevel),
-		})
-	}
+// NewTokenCacheWithConnection 使用共享连接创建数据库Token缓存
+func NewTokenCacheWithConnection(conn *Connection, options map[string]any) (*TokenCache, error) {
+	return newTokenCache(conn, options, false)
+}
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to database: %w", err)
-	}
+// newTokenCache 创建数据库Token缓存并迁移表结构
+func newTokenCache(conn *Connection, options map[string]any, owned bool) (*TokenCache, error) {
+	// 获取表前缀
+	tablePrefix, _ := options["table_prefix"].(string)
 
-	// 创建TokenCache实例
+	db := conn.db
 	cache := &TokenCache{
 		db:          db,
 		tablePrefix: tablePrefix,
+		owned:       owned,
+		batchSize:   cleanupBatchSize(options),
 	}
 
 	// 创建带前缀的表结构实例用于迁移
@@ -104,6 +128,11 @@ func NewTokenCache(options map[string]any) (*TokenCache, error) {
 		return nil, fmt.Errorf("failed to migrate %s table: %w", tableName, err)
 	}
 
+	revokedTableName := cache.newRevokedToken().TableName()
+	if err := db.Table(revokedTableName).AutoMigrate(&RevokedToken{}); err != nil {
+		return nil, fmt.Errorf("failed to migrate %s table: %w", revokedTableName, err)
+	}
+
 	// 注释：不启动内部清理，使用全局清理例程
 	// cache.startCleanup()
 
@@ -115,35 +144,136 @@ func (c *TokenCache) newCacheToken() *CacheToken {
 	return &CacheToken{tablePrefix: c.tablePrefix}
 }
 
+// newRevokedToken 创建带表前缀的RevokedToken实例
+func (c *TokenCache) newRevokedToken() *RevokedToken {
+	return &RevokedToken{tablePrefix: c.tablePrefix}
+}
+
 // Store 存储Token（优化版：先验证JWT，提取信息）
 func (c *TokenCache) Store(token *yggdrasil.Token) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	_, err := c.StoreWithLimit(token, 0)
+	return err
+}
 
+// StoreWithLimit 存储Token并撤销该用户最旧的Token，使有效Token数不超过limit
+// 在事务中锁定该用户的Token行，多个实例共享同一数据库时并发登录也不会超出限制
+func (c *TokenCache) StoreWithLimit(token *yggdrasil.Token, limit int) (int, error) {
 	// 第一步：验证JWT并提取信息
 	claims, err := utils.ValidateJWT(token.AccessToken)
 	if err != nil {
-		return fmt.Errorf("invalid JWT token: %w", err)
+		return 0, fmt.Errorf("invalid JWT token: %w", err)
 	}
 
 	// 存储到数据库（只存储JWT中没有的信息）
 	cacheToken := c.newCacheToken()
 	cacheToken.UserID = claims.UserID   // 从JWT中获取用户ID
 	cacheToken.TokenID = claims.TokenID // 从JWT中获取TokenID
+	cacheToken.AccessToken = token.AccessToken
 	cacheToken.ClientToken = token.ClientToken
-	cacheToken.ProfileID = token.ProfileID
+	cacheToken.ProfileID = claims.ProfileID // 从JWT中获取ProfileID
 	cacheToken.CreatedAt = token.CreatedAt
-	cacheToken.ExpiresAt = token.ExpiresAt
+	cacheToken.ExpiresAt = token.RefreshDeadline() // 暂时失效的Token仍需保留至刷新期限
+	cacheToken.ValidUntil = &token.ExpiresAt
 	cacheToken.UpdatedAt = time.Now()
 
 	// 使用Table()方法明确指定表名进行Save操作
 	tableName := cacheToken.TableName()
-	result := c.db.Table(tableName).Save(cacheToken)
-	if result.Error != nil {
-		return fmt.Errorf("failed to store token: %w", result.Error)
+	if limit <= 0 {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if err := c.db.Table(tableName).Save(cacheToken).Error; err != nil {
+			return 0, fmt.Errorf("failed to store token: %w", err)
+		}
+		return 0, nil
 	}
 
-	return nil
+	// 并发事务发生死锁或锁冲突时重试（每次尝试单独加锁，退避期间不阻塞其他读写）
+	var evicted []revocation.Entry
+	for attempt := range maxStoreRetries {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * 10 * time.Millisecond)
+		}
+		c.mu.Lock()
+		evicted, err = c.storeAndEvict(cacheToken, limit)
+		c.mu.Unlock()
+		if err == nil {
+			c.revocations.Notify(evicted)
+			return len(evicted), nil
+		}
+	}
+	return 0, fmt.Errorf("failed to store token: %w", err)
+}
+
+// maxStoreRetries 存储事务失败时的最大重试次数
+const maxStoreRetries = 5
+
+// storeAndEvict 在一个事务中存储Token并删除该用户已过期的和超出限制的最旧Token，返回被撤销的Token
+func (c *TokenCache) storeAndEvict(cacheToken *CacheToken, limit int) ([]revocation.Entry, error) {
+	tableName := cacheToken.TableName()
+	var evicted []revocation.Entry
+
+	err := c.db.Transaction(func(tx *gorm.DB) error {
+		// 锁定该用户的所有Token行（SQLite不支持行锁，由数据库级写锁保证串行）
+		var existing []CacheToken
+		if err := tx.Table(tableName).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("token_id", "created_at", "expires_at").
+			Where("user_id = ?", cacheToken.UserID).
+			Order("created_at").Find(&existing).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Table(tableName).Save(cacheToken).Error; err != nil {
+			return err
+		}
+
+		// 按创建时间升序收集需要删除的Token
+		now := time.Now()
+		var remove []string
+		var live []CacheToken
+		for _, ct := range existing {
+			switch {
+			case ct.TokenID == cacheToken.TokenID:
+			case !ct.ExpiresAt.After(now):
+				remove = append(remove, ct.TokenID)
+			default:
+				live = append(live, ct)
+			}
+		}
+
+		excess := max(len(live)-(limit-1), 0)
+		evicted = make([]revocation.Entry, 0, excess)
+		for _, ct := range live[:excess] {
+			remove = append(remove, ct.TokenID)
+			evicted = append(evicted, revocation.Entry{TokenID: ct.TokenID, ExpiresAt: ct.ExpiresAt})
+		}
+		if len(remove) == 0 {
+			return nil
+		}
+
+		if err := tx.Table(tableName).Where("user_id = ? AND token_id IN ?", cacheToken.UserID, remove).
+			Delete(&CacheToken{}).Error; err != nil {
+			return err
+		}
+		return c.recordRevocations(tx, evicted)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return evicted, nil
+}
+
+// recordRevocations 在事务中写入撤销记录（供其他实例轮询）
+func (c *TokenCache) recordRevocations(tx *gorm.DB, entries []revocation.Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	records := make([]RevokedToken, len(entries))
+	for i, entry := range entries {
+		records[i] = RevokedToken{TokenID: entry.TokenID, ExpiresAt: entry.ExpiresAt, CreatedAt: now}
+	}
+	return tx.Table(c.newRevokedToken().TableName()).Create(&records).Error
 }
 
 // Get 获取Token（优化版：先验证JWT，按需查询数据库）
@@ -151,7 +281,7 @@ func (c *TokenCache) Get(accessToken string) (*yggdrasil.Token, error) {
 	// 第一步：验证JWT（本地计算，极快）
 	claims, err := utils.ValidateJWT(accessToken)
 	if err != nil {
-		return nil, fmt.Errorf("invalid JWT token: %w", err)
+		return nil, fmt.Errorf("%w: invalid JWT token: %w", yggdrasil.ErrTokenNotFound, err)
 	}
 
 	// 第二步：从数据库获取ClientToken等额外信息
@@ -163,19 +293,21 @@ func (c *TokenCache) Get(accessToken string) (*yggdrasil.Token, error) {
 		claims.UserID, claims.TokenID, time.Now()).First(cacheToken)
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("token not found in cache")
+			return nil, yggdrasil.ErrTokenNotFound
 		}
 		return nil, fmt.Errorf("failed to get token: %w", result.Error)
 	}
 
 	// 构建Token对象（结合JWT信息和数据库信息）
+	expiresAt, refreshExpiresAt := cacheToken.expiry()
 	token := &yggdrasil.Token{
-		AccessToken: accessToken,
-		ClientToken: cacheToken.ClientToken,
-		ProfileID:   claims.ProfileID,
-		Owner:       claims.UserID, // 注意：这里应该是用户ID，不是邮箱
-		CreatedAt:   cacheToken.CreatedAt,
-		ExpiresAt:   cacheToken.ExpiresAt,
+		AccessToken:      accessToken,
+		ClientToken:      cacheToken.ClientToken,
+		ProfileID:        claims.ProfileID,
+		Owner:            claims.UserID, // 注意：这里应该是用户ID，不是邮箱
+		CreatedAt:        cacheToken.CreatedAt,
+		ExpiresAt:        expiresAt,
+		RefreshExpiresAt: refreshExpiresAt,
 	}
 
 	return token, nil
@@ -193,13 +325,20 @@ func (c *TokenCache) Delete(accessToken string) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	cacheToken := c.newCacheToken()
-	result := c.db.Table(cacheToken.TableName()).Where("user_id = ? AND token_id = ?",
-		claims.UserID, claims.TokenID).Delete(cacheToken)
-	if result.Error != nil {
-		return fmt.Errorf("failed to delete token: %w", result.Error)
+	revoked := []revocation.Entry{revocation.EntryFromClaims(claims)}
+	err = c.db.Transaction(func(tx *gorm.DB) error {
+		cacheToken := c.newCacheToken()
+		if err := tx.Table(cacheToken.TableName()).Where("user_id = ? AND token_id = ?",
+			claims.UserID, claims.TokenID).Delete(cacheToken).Error; err != nil {
+			return err
+		}
+		return c.recordRevocations(tx, revoked)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete token: %w", err)
 	}
 
+	c.revocations.Notify(revoked)
 	return nil
 }
 
@@ -215,17 +354,17 @@ func (c *TokenCache) GetUserTokens(userID string) ([]*yggdrasil.Token, error) {
 		return nil, fmt.Errorf("failed to get user tokens: %w", result.Error)
 	}
 
-	var tokens []*yggdrasil.Token
+	tokens := make([]*yggdrasil.Token, 0, len(cacheTokens))
 	for _, ct := range cacheTokens {
-		// 由于我们没有存储完整的AccessToken，这里需要重新构建
-		// 但实际上，GetUserTokens主要用于删除用户的所有token，不需要完整的Token对象
+		expiresAt, refreshExpiresAt := ct.expiry()
 		token := &yggdrasil.Token{
-			AccessToken: "", // 不需要完整的AccessToken
-			ClientToken: ct.ClientToken,
-			ProfileID:   ct.ProfileID,
-			Owner:       ct.UserID,
-			CreatedAt:   ct.CreatedAt,
-			ExpiresAt:   ct.ExpiresAt,
+			AccessToken:      ct.AccessToken,
+			ClientToken:      ct.ClientToken,
+			ProfileID:        ct.ProfileID,
+			Owner:            ct.UserID,
+			CreatedAt:        ct.CreatedAt,
+			ExpiresAt:        expiresAt,
+			RefreshExpiresAt: refreshExpiresAt,
 		}
 		tokens = append(tokens, token)
 	}
@@ -238,12 +377,29 @@ func (c *TokenCache) DeleteUserTokens(userID string) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	cacheToken := c.newCacheToken()
-	result := c.db.Table(cacheToken.TableName()).Where("user_id = ?", userID).Delete(&CacheToken{})
-	if result.Error != nil {
-		return fmt.Errorf("failed to delete user tokens: %w", result.Error)
+	var revoked []revocation.Entry
+	err := c.db.Transaction(func(tx *gorm.DB) error {
+		tableName := c.newCacheToken().TableName()
+
+		var existing []CacheToken
+		if err := tx.Table(tableName).Select("token_id", "expires_at").
+			Where("user_id = ? AND expires_at > ?", userID, time.Now()).Find(&existing).Error; err != nil {
+			return err
+		}
+		for _, ct := range existing {
+			revoked = append(revoked, revocation.Entry{TokenID: ct.TokenID, ExpiresAt: ct.ExpiresAt})
+		}
+
+		if err := tx.Table(tableName).Where("user_id = ?", userID).Delete(&CacheToken{}).Error; err != nil {
+			return err
+		}
+		return c.recordRevocations(tx, revoked)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete user tokens: %w", err)
 	}
 
+	c.revocations.Notify(revoked)
 	return nil
 }
 
@@ -257,25 +413,118 @@ func (c *TokenCache) GetUserTokenCount(userID string) (int, error) {
 }
 
 // CleanupExpired 清理过期Token
+// 按expires_at索引分批查出过期行的主键再按主键删除，每批是独立的短语句，清理大量记录时不会长时间锁表
 func (c *TokenCache) CleanupExpired() error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	now := time.Now()
+
+	tableName := c.newCacheToken().TableName()
+	for {
+		var expired []CacheToken
+		if err := c.db.Table(tableName).Select("user_id", "token_id").
+			Where("expires_at <= ?", now).Order("expires_at").Limit(c.batchSize).
+			Find(&expired).Error; err != nil {
+			return fmt.Errorf("failed to cleanup expired tokens: %w", err)
+		}
+		if len(expired) == 0 {
+			break
+		}
 
-	cacheToken := c.newCacheToken()
-	result := c.db.Table(cacheToken.TableName()).Where("expires_at <= ?", time.Now()).Delete(cacheToken)
-	if result.Error != nil {
-		return fmt.Errorf("failed to cleanup expired tokens: %w", result.Error)
+		keys := make([][]any, len(expired))
+		for i, ct := range expired {
+			keys[i] = []any{ct.UserID, ct.TokenID}
+		}
+		// 再次检查过期时间，避免删除查询之后被重新写入的Token
+		if err := c.db.Table(tableName).Where("(user_id, token_id) IN ? AND expires_at <= ?", keys, now).
+			Delete(&CacheToken{}).Error; err != nil {
+			return fmt.Errorf("failed to cleanup expired tokens: %w", err)
+		}
+		if len(expired) < c.batchSize {
+			break
+		}
+	}
+
+	// 清理JWT已过期的撤销记录
+	revokedTableName := c.newRevokedToken().TableName()
+	for {
+		var ids []uint64
+		if err := c.db.Table(revokedTableName).Where("expires_at <= ?", now).
+			Order("expires_at").Limit(c.batchSize).Pluck("id", &ids).Error; err != nil {
+			return fmt.Errorf("failed to cleanup revoked tokens: %w", err)
+		}
+		if len(ids) == 0 {
+			break
+		}
+		if err := c.db.Table(revokedTableName).Where("id IN ?", ids).Delete(&RevokedToken{}).Error; err != nil {
+			return fmt.Errorf("failed to cleanup revoked tokens: %w", err)
+		}
+		if len(ids) < c.batchSize {
+			break
+		}
 	}
 
 	return nil
 }
 
+// SubscribeRevocations 订阅Token撤销事件（首次订阅时加载现有撤销记录并开始轮询其他实例的撤销记录）
+func (c *TokenCache) SubscribeRevocations(handler func([]revocation.Entry)) {
+	c.revocations.Subscribe(handler)
+	c.listenOnce.Do(func() {
+		c.stopPolling = make(chan struct{})
+		c.pollRevocations(true)
+		go c.revocationLoop(c.stopPolling)
+	})
+}
+
+// revocationLoop 定期轮询新的撤销记录
+func (c *TokenCache) revocationLoop(stop <-chan struct{}) {
+	ticker := time.NewTicker(revocationPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.pollRevocations(false)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// pollRevocations 读取水位线之后的撤销记录（initial为true时加载所有未过期的记录）
+func (c *TokenCache) pollRevocations(initial bool) {
+	query := c.db.Table(c.newRevokedToken().TableName()).Where("id > ?", c.revokedWatermark)
+	if initial {
+		query = query.Where("expires_at > ?", time.Now())
+	}
+
+	var records []RevokedToken
+	if err := query.Order("id").Find(&records).Error; err != nil {
+		fmt.Printf("⚠️  Failed to poll revoked tokens: %v\n", err)
+		return
+	}
+	if len(records) == 0 {
+		return
+	}
+
+	entries := make([]revocation.Entry, len(records))
+	for i, record := range records {
+		entries[i] = revocation.Entry{TokenID: record.TokenID, ExpiresAt: record.ExpiresAt}
+	}
+	c.revokedWatermark = records[len(records)-1].ID
+	c.revocations.Notify(entries)
+}
+
 // Close 关闭缓存连接
 func (c *TokenCache) Close() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if c.db != nil {
+	if c.stopPolling != nil {
+		close(c.stopPolling)
+		c.stopPolling = nil
+	}
+
+	if c.db != nil && c.owned {
 		sqlDB, err := c.db.DB()
 		if err == nil {
 			sqlDB.Close()
@@ -2,55 +2,253 @@
 package cache
 
 import (
+	"errors"
 	"fmt"
 
+	"yggdrasil-api-go/src/cache/bolt"
 	"yggdrasil-api-go/src/cache/database"
 	"yggdrasil-api-go/src/cache/file"
+	"yggdrasil-api-go/src/cache/interop"
 	"yggdrasil-api-go/src/cache/memory"
 	"yggdrasil-api-go/src/cache/redis"
+	storage "yggdrasil-api-go/src/storage/interface"
 )
 
 // DefaultCacheFactory 默认缓存工厂
-type DefaultCacheFactory struct{}
+type DefaultCacheFactory struct {
+	redisConnections    *redis.Registry     // 命名Redis连接（redis缓存选项connection引用）
+	databaseConnections *database.Registry  // 命名数据库连接（database缓存选项connection引用）
+	users               storage.UserStorage // 用户存储（插件互通模式需要按邮箱查找令牌所有者）
+}
 
 // NewCacheFactory 创建缓存工厂
 func NewCacheFactory() CacheFactory {
-	return &DefaultCacheFactory{}
+	return &DefaultCacheFactory{
+		redisConnections:    redis.NewRegistry(),
+		databaseConnections: database.NewRegistry(),
+	}
+}
+
+// RegisterRedisConnection 注册命名Redis连接
+func (f *DefaultCacheFactory) RegisterRedisConnection(name string, options map[string]any) {
+	f.redisConnections.Register(name, options)
+}
+
+// RegisterDatabaseConnection 注册命名数据库连接
+func (f *DefaultCacheFactory) RegisterDatabaseConnection(name string, options map[string]any) {
+	f.databaseConnections.Register(name, options)
+}
+
+// SetUserStorage 设置用户存储
+func (f *DefaultCacheFactory) SetUserStorage(users storage.UserStorage) {
+	f.users = users
+}
+
+// Close 关闭共享连接
+func (f *DefaultCacheFactory) Close() error {
+	return errors.Join(f.redisConnections.Close(), f.databaseConnections.Close())
+}
+
+// databaseConnection 获取缓存选项引用的命名数据库连接（未引用时返回nil）
+func (f *DefaultCacheFactory) databaseConnection(options map[string]any) (*database.Connection, error) {
+	name, _ := options["connection"].(string)
+	if name == "" {
+		return nil, nil
+	}
+	return f.databaseConnections.Get(name)
+}
+
+// redisConnection 获取缓存选项引用的命名Redis连接（未引用时返回nil）
+func (f *DefaultCacheFactory) redisConnection(options map[string]any) (*redis.Connection, error) {
+	name, _ := options["connection"].(string)
+	if name == "" {
+		return nil, nil
+	}
+	return f.redisConnections.Get(name)
 }
 
-// CreateTokenCache 创建Token缓存实例
+// CreateTokenCache 创建Token缓存实例（选项l1_size大于0时在后端前增加进程内L1缓存）
+// 后端和L1都会记录操作统计，分别以后端类型（如redis）和l1+后端类型标识
 func (f *DefaultCacheFactory) CreateTokenCache(cacheType string, options map[string]any) (TokenCache, error) {
+	backend, err := f.createTokenCache(cacheType, options)
+	if err != nil {
+		return nil, err
+	}
+	tokenCache := TokenCache(NewInstrumentedTokenCache(backend))
+
+	if size, _ := options["l1_size"].(int); size > 0 {
+		tiered, err := NewTieredTokenCache(tokenCache, options)
+		if err != nil {
+			tokenCache.Close()
+			return nil, err
+		}
+		return NewInstrumentedTokenCache(tiered), nil
+	}
+	return tokenCache, nil
+}
+
+// createTokenCache 创建Token缓存后端
+func (f *DefaultCacheFactory) createTokenCache(cacheType string, options map[string]any) (TokenCache, error) {
+	if interopEnabled(options) {
+		store, err := f.createInteropStore(cacheType, options)
+		if err != nil {
+			return nil, err
+		}
+		tokenCache, err := interop.NewTokenCache(store, f.users, options)
+		if err != nil {
+			store.Close()
+			return nil, err
+		}
+		return tokenCache, nil
+	}
+
 	switch cacheType {
 	case "memory":
 		return memory.NewTokenCache(options)
 	case "redis":
+		conn, err := f.redisConnection(options)
+		if err != nil {
+			return nil, err
+		}
+		if conn != nil {
+			return redis.NewTokenCacheWithConnection(conn, options)
+		}
 		return redis.NewTokenCache(options)
 	case "file":
 		return file.NewTokenCache(options)
 	case "database":
+		conn, err := f.databaseConnection(options)
+		if err != nil {
+			return nil, err
+		}
+		if conn != nil {
+			return database.NewTokenCacheWithConnection(conn, options)
+		}
 		return database.NewTokenCache(options)
+	case "bolt":
+		return bolt.NewTokenCache(options)
 	default:
 		return nil, fmt.Errorf("unsupported token cache type: %s", cacheType)
 	}
 }
 
-// CreateSessionCache 创建Session缓存实例
+// CreateSessionCache 创建Session缓存实例（记录操作统计）
 func (f *DefaultCacheFactory) CreateSessionCache(cacheType string, options map[string]any) (SessionCache, error) {
+	sessionCache, err := f.createSessionCache(cacheType, options)
+	if err != nil {
+		return nil, err
+	}
+	return NewInstrumentedSessionCache(sessionCache), nil
+}
+
+// createSessionCache 创建Session缓存后端
+func (f *DefaultCacheFactory) createSessionCache(cacheType string, options map[string]any) (SessionCache, error) {
+	if interopEnabled(options) {
+		store, err := f.createInteropStore(cacheType, options)
+		if err != nil {
+			return nil, err
+		}
+		sessionCache, err := interop.NewSessionCache(store, options)
+		if err != nil {
+			store.Close()
+			return nil, err
+		}
+		return sessionCache, nil
+	}
+
 	switch cacheType {
 	case "memory":
 		return memory.NewSessionCache(options)
 	case "redis":
+		conn, err := f.redisConnection(options)
+		if err != nil {
+			return nil, err
+		}
+		if conn != nil {
+			return redis.NewSessionCacheWithConnection(conn, options)
+		}
 		return redis.NewSessionCache(options)
 	case "file":
 		return file.NewSessionCache(options)
 	case "database":
+		conn, err := f.databaseConnection(options)
+		if err != nil {
+			return nil, err
+		}
+		if conn != nil {
+			return database.NewSessionCacheWithConnection(conn, options)
+		}
 		return database.NewSessionCache(options)
+	case "bolt":
+		return bolt.NewSessionCache(options)
 	default:
 		return nil, fmt.Errorf("unsupported session cache type: %s", cacheType)
 	}
 }
 
+// CreateLoginAttemptCache 创建登录失败记录缓存实例
+// 插件互通模式只影响Token和Session的格式，登录失败记录始终使用本服务自己的格式
+func (f *DefaultCacheFactory) CreateLoginAttemptCache(cacheType string, options map[string]any) (LoginAttemptCache, error) {
+	switch cacheType {
+	case "memory":
+		return memory.NewLoginAttemptCache(options)
+	case "redis":
+		conn, err := f.redisConnection(options)
+		if err != nil {
+			return nil, err
+		}
+		if conn != nil {
+			return redis.NewLoginAttemptCacheWithConnection(conn, options)
+		}
+		return redis.NewLoginAttemptCache(options)
+	case "file":
+		return file.NewLoginAttemptCache(options)
+	case "database":
+		conn, err := f.databaseConnection(options)
+		if err != nil {
+			return nil, err
+		}
+		if conn != nil {
+			return database.NewLoginAttemptCacheWithConnection(conn, options)
+		}
+		return database.NewLoginAttemptCache(options)
+	case "bolt":
+		return bolt.NewLoginAttemptCache(options)
+	default:
+		return nil, fmt.Errorf("unsupported login attempt cache type: %s", cacheType)
+	}
+}
+
+// createInteropStore 创建与BlessingSkin插件互通的Laravel缓存存储（仅支持redis和file）
+func (f *DefaultCacheFactory) createInteropStore(cacheType string, options map[string]any) (interop.Store, error) {
+	if mode, _ := options["interop"].(string); mode != "blessingskin" {
+		return nil, fmt.Errorf("unsupported cache interop mode: %s", mode)
+	}
+
+	switch cacheType {
+	case "redis":
+		conn, err := f.redisConnection(options)
+		if err != nil {
+			return nil, err
+		}
+		if conn != nil {
+			return redis.NewLaravelStoreWithConnection(conn, options)
+		}
+		return redis.NewLaravelStore(options)
+	case "file":
+		return file.NewLaravelStore(options)
+	default:
+		return nil, fmt.Errorf("cache type %s does not support interop mode", cacheType)
+	}
+}
+
+// interopEnabled 缓存选项是否启用了插件互通模式
+func interopEnabled(options map[string]any) bool {
+	mode, _ := options["interop"].(string)
+	return mode != ""
+}
+
 // GetSupportedTypes 获取支持的缓存类型
 func (f *DefaultCacheFactory) GetSupportedTypes() []string {
-	return []string{"memory", "redis", "file", "database"}
+	return []string{"memory", "redis", "file", "database", "bolt"}
 }
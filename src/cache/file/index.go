@@ -0,0 +1,362 @@
+package file
+
+import (
+	"bufio"
+	"container/list"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// indexJournalName 过期索引日志文件名（位于缓存目录下，与data目录同级）
+// 每次写入追加一行"+ {hash} {过期时间戳} {文件大小} {是否固定} {引号包裹的缓存键}"，
+// 每次删除追加一行"- {hash}"，清理时重写为当前条目的快照
+const indexJournalName = "yggdrasil-index.journal"
+
+// indexEntry 缓存文件的索引条目
+type indexEntry struct {
+	hash      string
+	key       string // 缓存键（重建索引时从文件中发现的条目为空）
+	expiresAt int64  // Unix秒
+	size      int64
+	pinned    bool // 不参与LRU淘汰（如用户Token列表）
+}
+
+// CleanupStats 缓存清理统计
+type CleanupStats struct {
+	Expired int   // 本次清理删除的过期条目数
+	Evicted int   // 上次清理以来因容量限制淘汰的条目数
+	Pruned  int   // 从用户Token列表中移除的失效TokenID数
+	Entries int   // 清理后的条目数
+	Bytes   int64 // 清理后的文件总大小
+}
+
+// String 格式化统计信息
+func (s CleanupStats) String() string {
+	return fmt.Sprintf("%d expired, %d evicted, %d stale list entries pruned, %d entries (%d bytes)",
+		s.Expired, s.Evicted, s.Pruned, s.Entries, s.Bytes)
+}
+
+// fileIndex 缓存目录的索引（同一目录的Token缓存和Session缓存共用一个实例）
+// 在内存中维护每个缓存文件的过期时间、大小和最近使用顺序，清理和容量淘汰都无需解析文件
+type fileIndex struct {
+	dir        string
+	entries    map[string]*list.Element // hash -> LRU元素
+	lru        *list.List               // 最近使用的在前
+	bytes      int64
+	maxEntries int
+	maxBytes   int64
+	evicted    int // 上次清理以来淘汰的条目数
+	journal    *os.File
+	caches     map[*LaravelFileCache]struct{} // 使用该索引的缓存（接收淘汰通知）
+	refs       int
+	mu         sync.Mutex
+}
+
+var (
+	indexes   = make(map[string]*fileIndex) // 绝对路径 -> 索引
+	indexesMu sync.Mutex
+)
+
+// openIndex 打开（或复用已打开的）缓存目录索引
+func openIndex(cacheDir string, maxEntries int, maxBytes int64) (*fileIndex, error) {
+	dir, err := filepath.Abs(cacheDir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cache directory: %w", err)
+	}
+
+	indexesMu.Lock()
+	defer indexesMu.Unlock()
+
+	if idx, exists := indexes[dir]; exists {
+		idx.refs++
+		if (maxEntries > 0 && maxEntries != idx.maxEntries) || (maxBytes > 0 && maxBytes != idx.maxBytes) {
+			fmt.Printf("⚠️  File cache %s is shared; using the limits of the first cache that opened it (max_entries=%d, max_bytes=%d)\n", dir, idx.maxEntries, idx.maxBytes)
+		}
+		return idx, nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	idx := &fileIndex{
+		dir:        dir,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		caches:     make(map[*LaravelFileCache]struct{}),
+		refs:       1,
+	}
+	if err := idx.load(); err != nil {
+		return nil, err
+	}
+
+	indexes[dir] = idx
+	return idx, nil
+}
+
+// release 释放引用，最后一个引用释放时写入索引快照
+func (idx *fileIndex) release(cache *LaravelFileCache) error {
+	indexesMu.Lock()
+	defer indexesMu.Unlock()
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	delete(idx.caches, cache)
+	idx.refs--
+	if idx.refs > 0 {
+		return nil
+	}
+	delete(indexes, idx.dir)
+
+	err := idx.compactJournal()
+	if idx.journal != nil {
+		idx.journal.Close()
+		idx.journal = nil
+	}
+	return err
+}
+
+// load 回放索引日志，再扫描data目录补全日志中缺少的文件（只有这些文件需要解析）
+func (idx *fileIndex) load() error {
+	if f, err := os.Open(filepath.Join(idx.dir, indexJournalName)); err == nil {
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			idx.replay(scanner.Text())
+		}
+		f.Close()
+	}
+
+	onDisk := make(map[string]bool)
+	filepath.WalkDir(filepath.Join(idx.dir, "data"), func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || len(d.Name()) != 32 {
+			return nil // 忽略错误和非缓存文件
+		}
+		hash := d.Name()
+		onDisk[hash] = true
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if element, exists := idx.entries[hash]; exists {
+			idx.resize(element.Value.(*indexEntry), info.Size())
+			return nil
+		}
+
+		// 日志中没有记录的文件（如索引丢失）需要解析过期时间
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		if _, expiresAt, err := parseLaravelCache(string(data)); err == nil {
+			idx.add(&indexEntry{hash: hash, expiresAt: expiresAt, size: info.Size()})
+		}
+		return nil
+	})
+
+	// 移除文件已不存在的条目
+	for hash, element := range idx.entries {
+		if !onDisk[hash] {
+			idx.remove(element)
+		}
+	}
+
+	return idx.compactJournal()
+}
+
+// replay 回放一行索引日志
+func (idx *fileIndex) replay(line string) {
+	op, rest, _ := strings.Cut(line, " ")
+	switch op {
+	case "+":
+		fields := strings.SplitN(rest, " ", 5)
+		if len(fields) != 5 {
+			return
+		}
+		expiresAt, err1 := strconv.ParseInt(fields[1], 10, 64)
+		size, err2 := strconv.ParseInt(fields[2], 10, 64)
+		key, err3 := strconv.Unquote(fields[4])
+		if err1 != nil || err2 != nil || err3 != nil {
+			return
+		}
+		idx.add(&indexEntry{hash: fields[0], key: key, expiresAt: expiresAt, size: size, pinned: fields[3] == "1"})
+	case "-":
+		if element, exists := idx.entries[rest]; exists {
+			idx.remove(element)
+		}
+	}
+}
+
+// compactJournal 将索引日志重写为当前条目的快照（调用方需持有锁或尚未共享索引）
+func (idx *fileIndex) compactJournal() error {
+	path := filepath.Join(idx.dir, indexJournalName)
+	tmpPath := path + ".tmp"
+
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to write cache index: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	// 从最久未使用的条目开始写入，回放后保持LRU顺序
+	for element := idx.lru.Back(); element != nil; element = element.Prev() {
+		writeAddLine(w, element.Value.(*indexEntry))
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write cache index: %w", err)
+	}
+	f.Close()
+
+	if idx.journal != nil {
+		idx.journal.Close()
+		idx.journal = nil
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace cache index: %w", err)
+	}
+
+	idx.journal, err = os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open cache index: %w", err)
+	}
+	return nil
+}
+
+// writeAddLine 写入条目记录
+func writeAddLine(w interface{ WriteString(string) (int, error) }, entry *indexEntry) {
+	pinned := "0"
+	if entry.pinned {
+		pinned = "1"
+	}
+	w.WriteString("+ " + entry.hash + " " + strconv.FormatInt(entry.expiresAt, 10) + " " +
+		strconv.FormatInt(entry.size, 10) + " " + pinned + " " + strconv.Quote(entry.key) + "\n")
+}
+
+// append 追加索引日志（写入失败不影响缓存本身，下次启动时会扫描补全）
+func (idx *fileIndex) append(line func(w *strings.Builder)) {
+	if idx.journal == nil {
+		return
+	}
+	var b strings.Builder
+	line(&b)
+	idx.journal.WriteString(b.String())
+}
+
+// put 记录写入的缓存文件，超出容量时淘汰条目，返回被淘汰的条目（调用方需持有锁）
+func (idx *fileIndex) put(entry *indexEntry) []*indexEntry {
+	if element, exists := idx.entries[entry.hash]; exists {
+		idx.remove(element)
+	}
+	idx.add(entry)
+	idx.append(func(w *strings.Builder) { writeAddLine(w, entry) })
+
+	if !idx.overLimit() {
+		return nil
+	}
+
+	// 先删除已过期的条目，仍超出时按LRU淘汰（跳过固定条目和刚写入的条目）
+	idx.removeExpired(time.Now().Unix())
+	var evicted []*indexEntry
+	for element := idx.lru.Back(); element != nil && idx.overLimit(); {
+		prev := element.Prev()
+		victim := element.Value.(*indexEntry)
+		if !victim.pinned && victim != entry {
+			idx.forget(element)
+			evicted = append(evicted, victim)
+			idx.evicted++
+		}
+		element = prev
+	}
+	return evicted
+}
+
+// touch 标记条目为最近使用（调用方需持有锁）
+func (idx *fileIndex) touch(hash string) {
+	if element, exists := idx.entries[hash]; exists {
+		idx.lru.MoveToFront(element)
+	}
+}
+
+// delete 移除条目并记录日志（调用方需持有锁）
+func (idx *fileIndex) delete(hash string) {
+	if element, exists := idx.entries[hash]; exists {
+		idx.forget(element)
+	}
+}
+
+// forget 删除条目的缓存文件并记录日志（调用方需持有锁）
+func (idx *fileIndex) forget(element *list.Element) {
+	entry := element.Value.(*indexEntry)
+	os.Remove(idx.path(entry.hash))
+	idx.remove(element)
+	idx.append(func(w *strings.Builder) { w.WriteString("- " + entry.hash + "\n") })
+}
+
+// removeExpired 删除所有已过期的条目（调用方需持有锁）
+func (idx *fileIndex) removeExpired(now int64) []*indexEntry {
+	var expired []*indexEntry
+	for element := idx.lru.Back(); element != nil; {
+		prev := element.Prev()
+		if entry := element.Value.(*indexEntry); now > entry.expiresAt {
+			idx.forget(element)
+			expired = append(expired, entry)
+		}
+		element = prev
+	}
+	return expired
+}
+
+// keys 获取指定前缀的缓存键（调用方需持有锁）
+func (idx *fileIndex) keys(prefix string) []string {
+	var keys []string
+	for element := idx.lru.Front(); element != nil; element = element.Next() {
+		if key := element.Value.(*indexEntry).key; key != "" && strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// overLimit 条目数或总大小是否超出限制
+func (idx *fileIndex) overLimit() bool {
+	return (idx.maxEntries > 0 && idx.lru.Len() > idx.maxEntries) || (idx.maxBytes > 0 && idx.bytes > idx.maxBytes)
+}
+
+// add 添加条目
+func (idx *fileIndex) add(entry *indexEntry) {
+	if element, exists := idx.entries[entry.hash]; exists {
+		idx.remove(element)
+	}
+	idx.entries[entry.hash] = idx.lru.PushFront(entry)
+	idx.bytes += entry.size
+}
+
+// remove 移除条目（不删除文件）
+func (idx *fileIndex) remove(element *list.Element) {
+	entry := element.Value.(*indexEntry)
+	idx.lru.Remove(element)
+	delete(idx.entries, entry.hash)
+	idx.bytes -= entry.size
+}
+
+// resize 更新条目大小
+func (idx *fileIndex) resize(entry *indexEntry, size int64) {
+	idx.bytes += size - entry.size
+	entry.size = size
+}
+
+// path 获取缓存文件路径
+func (idx *fileIndex) path(hash string) string {
+	return filepath.Join(idx.dir, "data", hash[0:2], hash[2:4], hash)
+}
@@ -4,6 +4,7 @@ package file
 import (
 	"crypto/md5"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -11,37 +12,83 @@ import (
 	"strings"
 	"time"
 
+	"yggdrasil-api-go/src/yggdrasil"
+
 	"github.com/trim21/go-phpserialize"
 )
 
+// errCacheMiss 缓存文件不存在或已过期
+var errCacheMiss = errors.New("cache not found")
+
 // LaravelCacheEntry Laravel缓存条目格式（PHP序列化）
 // Laravel缓存文件格式：{serialized_data}i:{expiration_timestamp};
 // 例如：s:10:"test_value"i:1744686812;
 
 // LaravelFileCache Laravel文件缓存兼容实现
+// 同一目录的缓存共用一个索引（过期时间、大小和LRU顺序），设置容量限制时写入后按LRU淘汰
 type LaravelFileCache struct {
-	cacheDir string
+	index   *fileIndex
+	onEvict func(key string, expiresAt time.Time) // 条目因容量限制被淘汰时调用（持有索引锁，不应回调缓存）
 }
 
 // NewLaravelFileCache 创建Laravel兼容的文件缓存
-func NewLaravelFileCache(cacheDir string) *LaravelFileCache {
-	return &LaravelFileCache{
-		cacheDir: cacheDir,
+// 选项：cache_dir、max_entries（最大条目数）、max_bytes（文件总大小上限），超出时按LRU淘汰，0为不限制
+func NewLaravelFileCache(options map[string]any) (*LaravelFileCache, error) {
+	cacheDir := "storage/framework/cache"
+	if dir, ok := options["cache_dir"].(string); ok && dir != "" {
+		cacheDir = dir
+	}
+	maxEntries, _ := options["max_entries"].(int)
+	var maxBytes int64
+	switch v := options["max_bytes"].(type) {
+	case int:
+		maxBytes = int64(v)
+	case int64:
+		maxBytes = v
+	}
+
+	index, err := openIndex(cacheDir, maxEntries, maxBytes)
+	if err != nil {
+		return nil, err
 	}
+
+	c := &LaravelFileCache{index: index}
+	index.mu.Lock()
+	index.caches[c] = struct{}{}
+	index.mu.Unlock()
+	return c, nil
+}
+
+// OnEvict 设置条目因容量限制被淘汰时的回调
+func (c *LaravelFileCache) OnEvict(handler func(key string, expiresAt time.Time)) {
+	c.index.mu.Lock()
+	defer c.index.mu.Unlock()
+	c.onEvict = handler
 }
 
 // GetCacheFilePath 获取缓存文件路径（Laravel兼容）
 func (c *LaravelFileCache) GetCacheFilePath(key string) string {
-	// Laravel使用MD5哈希作为文件名
-	hash := md5.Sum([]byte(key))
-	hashStr := hex.EncodeToString(hash[:])
+	return c.index.path(cacheKeyHash(key))
+}
 
-	// Laravel的文件缓存路径格式：cache/data/{hash[0:2]}/{hash[2:4]}/{hash}
-	return filepath.Join(c.cacheDir, "data", hashStr[0:2], hashStr[2:4], hashStr)
+// cacheKeyHash 缓存键的文件名（Laravel使用MD5哈希作为文件名）
+func cacheKeyHash(key string) string {
+	hash := md5.Sum([]byte(key))
+	return hex.EncodeToString(hash[:])
 }
 
 // Store 存储数据到Laravel兼容的缓存文件
 func (c *LaravelFileCache) Store(key string, data any, ttl time.Duration) error {
+	return c.store(key, data, ttl, false)
+}
+
+// StorePinned 存储不参与LRU淘汰的数据（如用户Token列表，淘汰后无法再找到其中的Token）
+func (c *LaravelFileCache) StorePinned(key string, data any, ttl time.Duration) error {
+	return c.store(key, data, ttl, true)
+}
+
+// store 写入缓存文件并更新索引
+func (c *LaravelFileCache) store(key string, data any, ttl time.Duration, pinned bool) error {
 	// 使用PHP序列化库序列化数据
 	serializedData, err := phpserialize.Marshal(data)
 	if err != nil {
@@ -53,8 +100,11 @@ func (c *LaravelFileCache) Store(key string, data any, ttl time.Duration) error
 	expiresAt := time.Now().Add(ttl).Unix()
 	cacheContent := fmt.Sprintf("%si:%d;", string(serializedData), expiresAt)
 
-	// 获取文件路径
-	filePath := c.GetCacheFilePath(key)
+	hash := cacheKeyHash(key)
+	filePath := c.index.path(hash)
+
+	c.index.mu.Lock()
+	defer c.index.mu.Unlock()
 
 	// 创建目录
 	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
@@ -66,25 +116,57 @@ func (c *LaravelFileCache) Store(key string, data any, ttl time.Duration) error
 		return fmt.Errorf("failed to write cache file: %w", err)
 	}
 
+	evicted := c.index.put(&indexEntry{
+		hash:      hash,
+		key:       key,
+		expiresAt: expiresAt,
+		size:      int64(len(cacheContent)),
+		pinned:    pinned,
+	})
+	for _, entry := range evicted {
+		if entry.key == "" {
+			continue
+		}
+		for cache := range c.index.caches {
+			if cache.onEvict != nil {
+				cache.onEvict(entry.key, time.Unix(entry.expiresAt, 0))
+			}
+		}
+	}
+
 	return nil
 }
 
-// Get 从Laravel兼容的缓存文件获取数据
+// Get 从Laravel兼容的缓存文件获取数据（标记为最近使用）
 func (c *LaravelFileCache) Get(key string, target any) error {
-	filePath := c.GetCacheFilePath(key)
+	return c.get(key, target, true)
+}
+
+// Peek 获取数据但不改变LRU顺序（用于维护性读取，如检查列表中的Token是否仍然有效）
+func (c *LaravelFileCache) Peek(key string, target any) error {
+	return c.get(key, target, false)
+}
+
+// get 读取缓存文件
+func (c *LaravelFileCache) get(key string, target any, touch bool) error {
+	hash := cacheKeyHash(key)
+	filePath := c.index.path(hash)
+
+	c.index.mu.Lock()
+	defer c.index.mu.Unlock()
 
 	// 读取文件
 	data, err := os.ReadFile(filePath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return fmt.Errorf("cache not found")
+			c.index.delete(hash)
+			return errCacheMiss
 		}
 		return fmt.Errorf("failed to read cache file: %w", err)
 	}
 
 	// 解析Laravel缓存格式：{php_serialized_data}i:{expiration_timestamp};
-	content := string(data)
-	serializedData, expiresAt, err := c.ParseLaravelCache(content)
+	serializedData, expiresAt, err := parseLaravelCache(string(data))
 	if err != nil {
 		return fmt.Errorf("failed to parse Laravel cache: %w", err)
 	}
@@ -93,7 +175,8 @@ func (c *LaravelFileCache) Get(key string, target any) error {
 	if time.Now().Unix() > expiresAt {
 		// 删除过期文件
 		os.Remove(filePath)
-		return fmt.Errorf("cache expired")
+		c.index.delete(hash)
+		return fmt.Errorf("%w: cache expired", errCacheMiss)
 	}
 
 	// 使用PHP序列化库反序列化数据
@@ -101,51 +184,134 @@ func (c *LaravelFileCache) Get(key string, target any) error {
 		return fmt.Errorf("failed to unserialize cached data: %w", err)
 	}
 
+	if touch {
+		c.index.touch(hash)
+	}
 	return nil
 }
 
 // Delete 删除Laravel兼容的缓存文件
 func (c *LaravelFileCache) Delete(key string) error {
-	filePath := c.GetCacheFilePath(key)
-	err := os.Remove(filePath)
+	hash := cacheKeyHash(key)
+
+	c.index.mu.Lock()
+	defer c.index.mu.Unlock()
+
+	c.index.delete(hash)
+	err := os.Remove(c.index.path(hash))
 	if err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("failed to delete cache file: %w", err)
 	}
 	return nil
 }
 
-// CleanupExpired 清理过期的缓存文件
-func (c *LaravelFileCache) CleanupExpired() error {
-	return filepath.Walk(c.cacheDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil // 忽略错误，继续处理
-		}
+// Keys 获取指定前缀的缓存键（仅包含索引中记录了缓存键的条目）
+func (c *LaravelFileCache) Keys(prefix string) []string {
+	c.index.mu.Lock()
+	defer c.index.mu.Unlock()
+	return c.index.keys(prefix)
+}
 
-		// 跳过目录
-		if info.IsDir() {
-			return nil
-		}
+// Usage 获取当前条目数和文件总大小
+func (c *LaravelFileCache) Usage() (int, int64) {
+	c.index.mu.Lock()
+	defer c.index.mu.Unlock()
+	return c.index.lru.Len(), c.index.bytes
+}
 
-		// 读取文件
-		data, err := os.ReadFile(path)
-		if err != nil {
-			return nil // 忽略读取错误
-		}
+// CleanupExpired 按索引删除过期的缓存文件（无需读取文件），并将索引日志重写为快照
+func (c *LaravelFileCache) CleanupExpired() (CleanupStats, error) {
+	c.index.mu.Lock()
+	defer c.index.mu.Unlock()
 
-		// 解析Laravel缓存格式
-		content := string(data)
-		_, expiresAt, err := c.ParseLaravelCache(content)
-		if err != nil {
-			return nil // 忽略解析错误
-		}
+	stats := CleanupStats{
+		Expired: len(c.index.removeExpired(time.Now().Unix())),
+		Evicted: c.index.evicted,
+	}
+	c.index.evicted = 0
+	stats.Entries = c.index.lru.Len()
+	stats.Bytes = c.index.bytes
 
-		// 检查是否过期
-		if time.Now().Unix() > expiresAt {
-			os.Remove(path) // 删除过期文件
-		}
+	return stats, c.index.compactJournal()
+}
 
-		return nil
-	})
+// Close 释放索引（最后一个使用该目录的缓存关闭时写入索引快照）
+func (c *LaravelFileCache) Close() error {
+	return c.index.release(c)
+}
+
+// cachedToken 缓存文件中的Token记录（PHP序列化不支持time.Time，时间以Unix毫秒保存）
+type cachedToken struct {
+	AccessToken      string `php:"accessToken"`
+	ClientToken      string `php:"clientToken"`
+	ProfileID        string `php:"profileId"`
+	Owner            string `php:"owner"`
+	CreatedAt        int64  `php:"createdAt"`
+	ExpiresAt        int64  `php:"expiresAt"`
+	RefreshExpiresAt int64  `php:"refreshExpiresAt"`
+}
+
+// newCachedToken 转换为缓存记录
+func newCachedToken(token *yggdrasil.Token) *cachedToken {
+	return &cachedToken{
+		AccessToken:      token.AccessToken,
+		ClientToken:      token.ClientToken,
+		ProfileID:        token.ProfileID,
+		Owner:            token.Owner,
+		CreatedAt:        token.CreatedAt.UnixMilli(),
+		ExpiresAt:        token.ExpiresAt.UnixMilli(),
+		RefreshExpiresAt: token.RefreshDeadline().UnixMilli(),
+	}
+}
+
+// toToken 转换为Token
+func (t *cachedToken) toToken() *yggdrasil.Token {
+	return &yggdrasil.Token{
+		AccessToken:      t.AccessToken,
+		ClientToken:      t.ClientToken,
+		ProfileID:        t.ProfileID,
+		Owner:            t.Owner,
+		CreatedAt:        time.UnixMilli(t.CreatedAt),
+		ExpiresAt:        time.UnixMilli(t.ExpiresAt),
+		RefreshExpiresAt: time.UnixMilli(t.RefreshExpiresAt),
+	}
+}
+
+// cachedSession 缓存文件中的Session记录（时间以Unix毫秒保存）
+type cachedSession struct {
+	ServerID    string `php:"serverId"`
+	AccessToken string `php:"accessToken"`
+	TokenID     string `php:"tokenId"`
+	ProfileID   string `php:"profileId"`
+	ClientIP    string `php:"clientIp"`
+	UserAgent   string `php:"userAgent"`
+	CreatedAt   int64  `php:"createdAt"`
+}
+
+// newCachedSession 转换为缓存记录
+func newCachedSession(session *yggdrasil.Session) *cachedSession {
+	return &cachedSession{
+		ServerID:    session.ServerID,
+		AccessToken: session.AccessToken,
+		TokenID:     session.TokenID,
+		ProfileID:   session.ProfileID,
+		ClientIP:    session.ClientIP,
+		UserAgent:   session.UserAgent,
+		CreatedAt:   session.CreatedAt.UnixMilli(),
+	}
+}
+
+// toSession 转换为Session
+func (s *cachedSession) toSession() *yggdrasil.Session {
+	return &yggdrasil.Session{
+		ServerID:    s.ServerID,
+		AccessToken: s.AccessToken,
+		TokenID:     s.TokenID,
+		ProfileID:   s.ProfileID,
+		ClientIP:    s.ClientIP,
+		UserAgent:   s.UserAgent,
+		CreatedAt:   time.UnixMilli(s.CreatedAt),
+	}
 }
 
 // generateYggdrasilTokenKey 生成Yggdrasil Token缓存键（与BlessingSkin兼容）
@@ -165,6 +331,11 @@ func generateYggdrasilSessionKey(serverID string) string {
 
 // ParseLaravelCache 解析Laravel缓存格式
 func (c *LaravelFileCache) ParseLaravelCache(content string) (string, int64, error) {
+	return parseLaravelCache(content)
+}
+
+// parseLaravelCache 解析Laravel缓存格式
+func parseLaravelCache(content string) (string, int64, error) {
 	// Laravel缓存格式：{php_serialized_data}i:{expiration_timestamp};
 	// 例如：s:10:"test_value"i:1744686812; 或者 i:9999999999;i:1744686812;
 
@@ -0,0 +1,128 @@
+package file
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// laravelForever Laravel FileStore永不过期的条目使用的过期时间戳
+const laravelForever = 9999999999
+
+// LaravelStore Laravel FileStore原生格式的缓存存储（供BlessingSkin插件互通模式使用）
+// 文件路径为{cache_dir}/data/{sha1[0:2]}/{sha1[2:4]}/{sha1}，内容为10位过期时间戳加Laravel序列化后的值
+type LaravelStore struct {
+	dir string
+}
+
+// NewLaravelStore 创建Laravel FileStore格式的存储
+func NewLaravelStore(options map[string]any) (*LaravelStore, error) {
+	cacheDir := "storage/framework/cache"
+	if dir, ok := options["cache_dir"].(string); ok && dir != "" {
+		cacheDir = dir
+	}
+	return &LaravelStore{dir: filepath.Join(cacheDir, "data")}, nil
+}
+
+// path 获取缓存文件路径（与Laravel FileStore一致）
+func (s *LaravelStore) path(key string) string {
+	hash := sha1.Sum([]byte(key))
+	hashStr := hex.EncodeToString(hash[:])
+	return filepath.Join(s.dir, hashStr[0:2], hashStr[2:4], hashStr)
+}
+
+// Get 获取缓存值及其过期时间（过期的文件会被删除）
+func (s *LaravelStore) Get(key string) (string, time.Time, bool, error) {
+	path := s.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", time.Time{}, false, nil
+		}
+		return "", time.Time{}, false, fmt.Errorf("failed to read cache file: %w", err)
+	}
+
+	expiresAt, value, err := parseLaravelFile(data)
+	if err != nil {
+		return "", time.Time{}, false, err
+	}
+	if !time.Now().Before(expiresAt) {
+		os.Remove(path)
+		return "", time.Time{}, false, nil
+	}
+	return value, expiresAt, true, nil
+}
+
+// Put 写入缓存值（先写临时文件再重命名，避免PHP读到写了一半的文件）
+func (s *LaravelStore) Put(key, value string, ttl time.Duration) error {
+	path := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	expiresAt := time.Now().Add(max(ttl, time.Second)).Unix()
+	content := strconv.FormatInt(min(expiresAt, laravelForever), 10) + value
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+	return nil
+}
+
+// Forget 删除缓存值
+func (s *LaravelStore) Forget(key string) error {
+	if err := os.Remove(s.path(key)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete cache file: %w", err)
+	}
+	return nil
+}
+
+// CleanupExpired 删除过期的缓存文件
+func (s *LaravelStore) CleanupExpired() error {
+	now := time.Now()
+	return filepath.Walk(s.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil // 忽略错误，继续处理
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		if expiresAt, _, err := parseLaravelFile(data); err == nil && !now.Before(expiresAt) {
+			os.Remove(path)
+		}
+		return nil
+	})
+}
+
+// Close 关闭存储
+func (s *LaravelStore) Close() error {
+	return nil
+}
+
+// Type 存储类型
+func (s *LaravelStore) Type() string {
+	return "file"
+}
+
+// parseLaravelFile 解析Laravel FileStore缓存文件
+func parseLaravelFile(data []byte) (time.Time, string, error) {
+	if len(data) < 10 {
+		return time.Time{}, "", fmt.Errorf("invalid Laravel cache file: too short")
+	}
+	expiresAt, err := strconv.ParseInt(string(data[:10]), 10, 64)
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("invalid Laravel cache file: %w", err)
+	}
+	return time.Unix(expiresAt, 0), string(data[10:]), nil
+}
@@ -0,0 +1,105 @@
+// Package file 文件登录失败记录缓存实现
+package file
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"yggdrasil-api-go/src/yggdrasil"
+)
+
+// LoginAttemptCache 文件登录失败记录缓存（Laravel兼容格式，递增只在单个实例内是原子的）
+type LoginAttemptCache struct {
+	cache *LaravelFileCache
+	mu    sync.Mutex
+}
+
+// cachedLoginAttempts 缓存文件中的登录失败记录（时间以Unix毫秒保存）
+type cachedLoginAttempts struct {
+	Failures    int   `php:"failures"`
+	LastFailure int64 `php:"lastFailure"`
+}
+
+// NewLoginAttemptCache 创建文件登录失败记录缓存（选项见NewLaravelFileCache）
+func NewLoginAttemptCache(options map[string]any) (*LoginAttemptCache, error) {
+	cache, err := NewLaravelFileCache(options)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginAttemptCache{cache: cache}, nil
+}
+
+// RecordFailure 增加失败次数
+func (c *LoginAttemptCache) RecordFailure(key string, ttl time.Duration) (*yggdrasil.LoginAttempts, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	attemptsKey := generateLoginAttemptsKey(key)
+	var cached cachedLoginAttempts
+	if err := c.cache.Peek(attemptsKey, &cached); err != nil {
+		cached = cachedLoginAttempts{}
+	}
+
+	now := time.Now()
+	cached.Failures++
+	cached.LastFailure = now.UnixMilli()
+
+	// 失败记录不参与LRU淘汰，避免通过大量写入其他键清除锁定
+	if err := c.cache.StorePinned(attemptsKey, &cached, ttl); err != nil {
+		return nil, fmt.Errorf("failed to record login failure: %w", err)
+	}
+	return cached.toLoginAttempts(), nil
+}
+
+// Get 获取失败记录
+func (c *LoginAttemptCache) Get(key string) (*yggdrasil.LoginAttempts, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var cached cachedLoginAttempts
+	if err := c.cache.Peek(generateLoginAttemptsKey(key), &cached); err != nil {
+		return nil, nil
+	}
+	return cached.toLoginAttempts(), nil
+}
+
+// Reset 清除失败记录
+func (c *LoginAttemptCache) Reset(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.cache.Delete(generateLoginAttemptsKey(key))
+}
+
+// CleanupExpired 清理过期记录（与同一目录的其他缓存共用索引）
+func (c *LoginAttemptCache) CleanupExpired() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, err := c.cache.CleanupExpired()
+	return err
+}
+
+// Close 关闭缓存（释放缓存目录索引）
+func (c *LoginAttemptCache) Close() error {
+	return c.cache.Close()
+}
+
+// GetCacheType 获取缓存类型
+func (c *LoginAttemptCache) GetCacheType() string {
+	return "file"
+}
+
+// toLoginAttempts 转换为登录失败记录
+func (a *cachedLoginAttempts) toLoginAttempts() *yggdrasil.LoginAttempts {
+	return &yggdrasil.LoginAttempts{
+		Failures:    a.Failures,
+		LastFailure: time.UnixMilli(a.LastFailure),
+	}
+}
+
+// generateLoginAttemptsKey 生成登录失败记录缓存键
+func generateLoginAttemptsKey(key string) string {
+	return fmt.Sprintf("yggdrasil-login-%s", key)
+}
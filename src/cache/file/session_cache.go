@@ -2,6 +2,7 @@
 package file
 
 import (
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -15,15 +16,15 @@ type SessionCache struct {
 	mu    sync.RWMutex
 }
 
-// NewSessionCache 创建文件Session缓存
+// NewSessionCache 创建文件Session缓存（选项见NewLaravelFileCache）
 func NewSessionCache(options map[string]any) (*SessionCache, error) {
-	cacheDir := "storage/framework/cache"
-	if dir, ok := options["cache_dir"].(string); ok && dir != "" {
-		cacheDir = dir
+	cache, err := NewLaravelFileCache(options)
+	if err != nil {
+		return nil, err
 	}
 
 	return &SessionCache{
-		cache: NewLaravelFileCache(cacheDir),
+		cache: cache,
 	}, nil
 }
 
@@ -32,20 +33,25 @@ func (c *SessionCache) Store(serverID string, session *yggdrasil.Session) error
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	// Session固定过期时间为30秒（与Yggdrasil标准一致）
-	ttl := 30 * time.Second
+	// Session自创建起SessionTTL后过期
+	ttl := time.Until(session.ExpiresAt())
+	if ttl <= 0 {
+		return fmt.Errorf("session already expired")
+	}
 
 	// 创建简化的Session对象（不存储AccessToken和ProfileID）
 	cacheSession := &yggdrasil.Session{
 		ServerID:    serverID,
 		AccessToken: session.AccessToken, // 仍然存储AccessToken以供验证
+		TokenID:     session.TokenID,
 		ProfileID:   session.ProfileID,  // 仍然存储ProfileID以供验证
 		ClientIP:    session.ClientIP,
+		UserAgent:   session.UserAgent,
 		CreatedAt:   session.CreatedAt,
 	}
 
 	sessionKey := generateYggdrasilSessionKey(serverID)
-	if err := c.cache.Store(sessionKey, cacheSession, ttl); err != nil {
+	if err := c.cache.Store(sessionKey, newCachedSession(cacheSession), ttl); err != nil {
 		return fmt.Errorf("failed to store session: %w", err)
 	}
 
@@ -59,13 +65,16 @@ func (c *SessionCache) Get(serverID string) (*yggdrasil.Session, error) {
 
 	sessionKey := generateYggdrasilSessionKey(serverID)
 
-	var session yggdrasil.Session
-	if err := c.cache.Get(sessionKey, &session); err != nil {
-		return nil, fmt.Errorf("session not found: %w", err)
+	var cached cachedSession
+	if err := c.cache.Get(sessionKey, &cached); err != nil {
+		if errors.Is(err, errCacheMiss) {
+			return nil, yggdrasil.ErrSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to get session: %w", err)
 	}
 
-	// 直接返回缓存的Session对象（已经是简化版）
-	return &session, nil
+	// 从缓存记录构建Session对象
+	return cached.toSession(), nil
 }
 
 // Delete 删除Session
@@ -77,18 +86,22 @@ func (c *SessionCache) Delete(serverID string) error {
 	return c.cache.Delete(sessionKey)
 }
 
-// CleanupExpired 清理过期Session
+// CleanupExpired 清理过期Session并输出统计信息
 func (c *SessionCache) CleanupExpired() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	return c.cache.CleanupExpired()
+	stats, err := c.cache.CleanupExpired()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("🧹 File session cache: %s\n", stats)
+	return nil
 }
 
-// Close 关闭缓存连接
+// Close 关闭缓存（释放缓存目录索引）
 func (c *SessionCache) Close() error {
-	// 文件缓存无需关闭操作
-	return nil
+	return c.cache.Close()
 }
 
 // GetCacheType 获取缓存类型
@@ -2,11 +2,14 @@
 package file
 
 import (
+	"errors"
 	"fmt"
 	"slices"
+	"strings"
 	"sync"
 	"time"
 
+	"yggdrasil-api-go/src/cache/revocation"
 	"yggdrasil-api-go/src/utils"
 	"yggdrasil-api-go/src/yggdrasil"
 )
@@ -15,78 +18,87 @@ import (
 type TokenCache struct {
 	cache *LaravelFileCache
 	mu    sync.RWMutex
+
+	revocations revocation.Notifier // 撤销事件（文件缓存仅在进程内分发）
 }
 
-// NewTokenCache 创建文件Token缓存
+// NewTokenCache 创建文件Token缓存（选项见NewLaravelFileCache）
 func NewTokenCache(options map[string]any) (*TokenCache, error) {
-	cacheDir := "storage/framework/cache"
-	if dir, ok := options["cache_dir"].(string); ok && dir != "" {
-		cacheDir = dir
+	cache, err := NewLaravelFileCache(options)
+	if err != nil {
+		return nil, err
 	}
 
-	return &TokenCache{
-		cache: NewLaravelFileCache(cacheDir),
-	}, nil
+	c := &TokenCache{cache: cache}
+	cache.OnEvict(c.tokenEvicted)
+	return c, nil
 }
 
 // Store 存储Token（优化版：先验证JWT，提取信息）
 func (c *TokenCache) Store(token *yggdrasil.Token) error {
+	_, err := c.StoreWithLimit(token, 0)
+	return err
+}
+
+// StoreWithLimit 存储Token并撤销该用户最旧的Token，使有效Token数不超过limit
+func (c *TokenCache) StoreWithLimit(token *yggdrasil.Token, limit int) (int, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	// 第一步：验证JWT并提取信息
 	claims, err := utils.ValidateJWT(token.AccessToken)
 	if err != nil {
-		return fmt.Errorf("invalid JWT token: %w", err)
+		return 0, fmt.Errorf("invalid JWT token: %w", err)
 	}
 
-	// 计算TTL
-	ttl := time.Until(token.ExpiresAt)
+	// 计算TTL（暂时失效的Token仍需保留至刷新期限）
+	ttl := time.Until(token.RefreshDeadline())
 	if ttl <= 0 {
-		return fmt.Errorf("token already expired")
+		return 0, fmt.Errorf("token already expired")
 	}
 
 	// 创建简化的Token对象（只存储JWT中没有的信息）
 	cacheToken := &yggdrasil.Token{
-		AccessToken: token.AccessToken, // 保留完整的AccessToken用于兼容性
-		ClientToken: token.ClientToken,
-		ProfileID:   token.ProfileID,
-		Owner:       claims.UserID, // 从JWT中获取用户ID
-		CreatedAt:   token.CreatedAt,
-		ExpiresAt:   token.ExpiresAt,
+		AccessToken:      token.AccessToken, // 保留完整的AccessToken用于兼容性
+		ClientToken:      token.ClientToken,
+		ProfileID:        claims.ProfileID, // 从JWT中获取ProfileID
+		Owner:            claims.UserID,    // 从JWT中获取用户ID
+		CreatedAt:        token.CreatedAt,
+		ExpiresAt:        token.ExpiresAt,
+		RefreshExpiresAt: token.RefreshExpiresAt,
 	}
 
 	// 存储Token（使用用户ID+TokenID作为键）
 	tokenKey := generateOptimizedTokenKey(claims.UserID, claims.TokenID)
-	if err := c.cache.Store(tokenKey, cacheToken, ttl); err != nil {
-		return fmt.Errorf("failed to store token: %w", err)
+	if err := c.cache.Store(tokenKey, newCachedToken(cacheToken), ttl); err != nil {
+		return 0, fmt.Errorf("failed to store token: %w", err)
 	}
 
 	// 更新用户Token列表（使用用户ID）
 	userTokensKey := generateYggdrasilUserTokensKey(claims.UserID)
 
-	// 获取现有Token列表
-	var existingTokens []string
-	if err := c.cache.Get(userTokensKey, &existingTokens); err != nil {
+	// 获取现有TokenID列表（移除已失效的Token）
+	var tokenIDs []string
+	if err := c.cache.Get(userTokensKey, &tokenIDs); err != nil {
 		// 如果获取失败，创建新列表
-		existingTokens = []string{}
+		tokenIDs = []string{}
 	}
+	others, tokens := c.liveTokens(claims.UserID, claims.TokenID, tokenIDs)
 
-	// 检查Token是否已存在
-	found := slices.Contains(existingTokens, token.AccessToken)
-
-	// 如果不存在，添加到列表
-	if !found {
-		existingTokens = append(existingTokens, token.AccessToken)
+	// 超出数量限制时撤销最旧的Token
+	var evicted []revocation.Entry
+	if limit > 0 {
+		others, evicted = c.evictOldest(claims.UserID, others, tokens, limit)
 	}
 
-	// 存储更新后的Token列表（使用较长的TTL）
-	userTokensTTL := 7 * 24 * time.Hour // 7天
-	if err := c.cache.Store(userTokensKey, existingTokens, userTokensTTL); err != nil {
-		return fmt.Errorf("failed to store user tokens list: %w", err)
+	// 存储更新后的TokenID列表
+	tokens[claims.TokenID] = cacheToken
+	if err := c.storeUserTokens(claims.UserID, append(others, claims.TokenID), tokens); err != nil {
+		return 0, fmt.Errorf("failed to store user tokens list: %w", err)
 	}
 
-	return nil
+	c.revocations.Notify(evicted)
+	return len(evicted), nil
 }
 
 // Get 获取Token（优化版：先验证JWT，按需查询缓存）
@@ -94,28 +106,27 @@ func (c *TokenCache) Get(accessToken string) (*yggdrasil.Token, error) {
 	// 第一步：验证JWT（本地计算，极快）
 	claims, err := utils.ValidateJWT(accessToken)
 	if err != nil {
-		return nil, fmt.Errorf("invalid JWT token: %w", err)
+		return nil, fmt.Errorf("%w: invalid JWT token: %w", yggdrasil.ErrTokenNotFound, err)
 	}
 
 	// 第二步：从缓存获取ClientToken等额外信息
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	tokenKey := generateOptimizedTokenKey(claims.UserID, claims.TokenID)
-
-	var token yggdrasil.Token
-	if err := c.cache.Get(tokenKey, &token); err != nil {
-		return nil, fmt.Errorf("token not found in cache: %w", err)
+	token, err := c.getToken(claims.UserID, claims.TokenID)
+	if err != nil {
+		return nil, err
 	}
 
 	// 构建Token对象（结合JWT信息和缓存信息）
 	result := &yggdrasil.Token{
-		AccessToken: accessToken,
-		ClientToken: token.ClientToken,
-		ProfileID:   claims.ProfileID,
-		Owner:       claims.UserID,
-		CreatedAt:   token.CreatedAt,
-		ExpiresAt:   token.ExpiresAt,
+		AccessToken:      accessToken,
+		ClientToken:      token.ClientToken,
+		ProfileID:        claims.ProfileID,
+		Owner:            claims.UserID,
+		CreatedAt:        token.CreatedAt,
+		ExpiresAt:        token.ExpiresAt,
+		RefreshExpiresAt: token.RefreshExpiresAt,
 	}
 	return result, nil
 }
@@ -133,11 +144,16 @@ func (c *TokenCache) Delete(accessToken string) error {
 	defer c.mu.Unlock()
 
 	// 从用户Token列表中移除（使用用户ID）
-	c.removeTokenFromUserList(claims.UserID, accessToken)
+	c.removeTokenFromUserList(claims.UserID, claims.TokenID)
 
 	// 删除Token
 	tokenKey := generateOptimizedTokenKey(claims.UserID, claims.TokenID)
-	return c.cache.Delete(tokenKey)
+	if err := c.cache.Delete(tokenKey); err != nil {
+		return err
+	}
+
+	c.revocations.Notify([]revocation.Entry{revocation.EntryFromClaims(claims)})
+	return nil
 }
 
 // GetUserTokens 获取用户的所有Token（按用户ID查询）
@@ -147,15 +163,16 @@ func (c *TokenCache) GetUserTokens(userID string) ([]*yggdrasil.Token, error) {
 
 	userTokensKey := generateYggdrasilUserTokensKey(userID)
 
-	var accessTokens []string
-	if err := c.cache.Get(userTokensKey, &accessTokens); err != nil {
-		return []*yggdrasil.Token{}, nil
+	tokens := []*yggdrasil.Token{}
+
+	var tokenIDs []string
+	if err := c.cache.Get(userTokensKey, &tokenIDs); err != nil {
+		return tokens, nil
 	}
 
-	var tokens []*yggdrasil.Token
-	for _, accessToken := range accessTokens {
+	for _, tokenID := range tokenIDs {
 		// Laravel缓存已经处理了过期检查，如果能获取到Token就说明没有过期
-		if token, err := c.Get(accessToken); err == nil {
+		if token, err := c.peekToken(userID, tokenID); err == nil {
 			tokens = append(tokens, token)
 		}
 	}
@@ -168,19 +185,23 @@ func (c *TokenCache) DeleteUserTokens(userID string) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	// 获取用户的所有Token
+	// 获取用户的所有TokenID
 	userTokensKey := generateYggdrasilUserTokensKey(userID)
 
-	var accessTokens []string
-	if err := c.cache.Get(userTokensKey, &accessTokens); err != nil {
+	var tokenIDs []string
+	if err := c.cache.Get(userTokensKey, &tokenIDs); err != nil {
 		return nil // 用户没有Token
 	}
 
 	// 删除所有Token
-	for _, accessToken := range accessTokens {
-		tokenKey := generateYggdrasilTokenKey(accessToken)
-		c.cache.Delete(tokenKey)
+	var revoked []revocation.Entry
+	for _, tokenID := range tokenIDs {
+		if token, err := c.peekToken(userID, tokenID); err == nil {
+			revoked = append(revoked, revocation.Entry{TokenID: tokenID, ExpiresAt: token.RefreshDeadline()})
+		}
+		c.cache.Delete(generateOptimizedTokenKey(userID, tokenID))
 	}
+	c.revocations.Notify(revoked)
 
 	// 删除用户Token列表
 	return c.cache.Delete(userTokensKey)
@@ -195,18 +216,45 @@ func (c *TokenCache) GetUserTokenCount(userID string) (int, error) {
 	return len(tokens), nil
 }
 
-// CleanupExpired 清理过期Token
+// CleanupExpired 清理过期Token和用户Token列表中的失效TokenID，并输出统计信息
 func (c *TokenCache) CleanupExpired() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	return c.cache.CleanupExpired()
+	stats, err := c.cache.CleanupExpired()
+	if err != nil {
+		return err
+	}
+
+	for _, userTokensKey := range c.cache.Keys(generateYggdrasilUserTokensKey("")) {
+		var tokenIDs []string
+		if err := c.cache.Get(userTokensKey, &tokenIDs); err != nil {
+			continue
+		}
+		userID := strings.TrimPrefix(userTokensKey, generateYggdrasilUserTokensKey(""))
+		live, tokens := c.liveTokens(userID, "", tokenIDs)
+		if len(live) == len(tokenIDs) {
+			continue
+		}
+		stats.Pruned += len(tokenIDs) - len(live)
+		if err := c.storeUserTokens(userID, live, tokens); err != nil {
+			return err
+		}
+	}
+
+	stats.Entries, stats.Bytes = c.cache.Usage()
+	fmt.Printf("🧹 File token cache: %s\n", stats)
+	return nil
+}
+
+// SubscribeRevocations 订阅Token撤销事件
+func (c *TokenCache) SubscribeRevocations(handler func([]revocation.Entry)) {
+	c.revocations.Subscribe(handler)
 }
 
-// Close 关闭缓存连接
+// Close 关闭缓存（释放缓存目录索引）
 func (c *TokenCache) Close() error {
-	// 文件缓存无需关闭操作
-	return nil
+	return c.cache.Close()
 }
 
 // GetCacheType 获取缓存类型
@@ -214,34 +262,121 @@ func (c *TokenCache) GetCacheType() string {
 	return "file"
 }
 
-// removeTokenFromUserList 从用户Token列表中移除指定Token
-func (c *TokenCache) removeTokenFromUserList(userID, accessToken string) error {
-	userTokensKey := generateYggdrasilUserTokensKey(userID)
+// getToken 读取缓存的Token（调用方需持有锁）
+func (c *TokenCache) getToken(userID, tokenID string) (*yggdrasil.Token, error) {
+	return c.readToken(userID, tokenID, c.cache.Get)
+}
 
-	var accessTokens []string
-	if err := c.cache.Get(userTokensKey, &accessTokens); err != nil {
-		return nil // 用户没有Token列表
+// peekToken 读取缓存的Token但不改变LRU顺序（调用方需持有锁）
+func (c *TokenCache) peekToken(userID, tokenID string) (*yggdrasil.Token, error) {
+	return c.readToken(userID, tokenID, c.cache.Peek)
+}
+
+// readToken 使用指定的读取方法读取缓存的Token
+func (c *TokenCache) readToken(userID, tokenID string, read func(key string, target any) error) (*yggdrasil.Token, error) {
+	var cached cachedToken
+	if err := read(generateOptimizedTokenKey(userID, tokenID), &cached); err != nil {
+		if errors.Is(err, errCacheMiss) {
+			return nil, yggdrasil.ErrTokenNotFound
+		}
+		return nil, fmt.Errorf("failed to get token: %w", err)
+	}
+
+	token := cached.toToken()
+	if !token.IsRefreshable() {
+		return nil, fmt.Errorf("%w: token expired", yggdrasil.ErrTokenNotFound)
 	}
+	return token, nil
+}
 
-	// 移除指定Token
-	for i, token := range accessTokens {
-		if token == accessToken {
-			accessTokens = append(accessTokens[:i], accessTokens[i+1:]...)
-			break
+// liveTokens 读取列表中仍然有效的Token（不包括skipID），删除已失效Token的缓存文件（调用方需持有写锁）
+func (c *TokenCache) liveTokens(userID, skipID string, tokenIDs []string) ([]string, map[string]*yggdrasil.Token) {
+	tokens := make(map[string]*yggdrasil.Token, len(tokenIDs))
+	var live []string
+	for _, tokenID := range tokenIDs {
+		if tokenID == skipID || tokens[tokenID] != nil {
+			continue
 		}
+		token, err := c.peekToken(userID, tokenID)
+		if err != nil {
+			c.cache.Delete(generateOptimizedTokenKey(userID, tokenID))
+			continue
+		}
+		tokens[tokenID] = token
+		live = append(live, tokenID)
 	}
+	return live, tokens
+}
 
-	// 如果列表为空，删除用户Token列表
-	if len(accessTokens) == 0 {
+// evictOldest 撤销最旧的Token，使加上新Token后有效Token数不超过limit，返回保留的TokenID列表和被撤销的Token（调用方需持有写锁）
+func (c *TokenCache) evictOldest(userID string, others []string, tokens map[string]*yggdrasil.Token, limit int) ([]string, []revocation.Entry) {
+	// 按创建时间升序排列，撤销最旧的Token
+	excess := max(len(others)-(limit-1), 0)
+	slices.SortStableFunc(others, func(a, b string) int {
+		return tokens[a].CreatedAt.Compare(tokens[b].CreatedAt)
+	})
+	evicted := make([]revocation.Entry, 0, excess)
+	for _, tokenID := range others[:excess] {
+		evicted = append(evicted, revocation.Entry{TokenID: tokenID, ExpiresAt: tokens[tokenID].RefreshDeadline()})
+		c.cache.Delete(generateOptimizedTokenKey(userID, tokenID))
+	}
+
+	return others[excess:], evicted
+}
+
+// storeUserTokens 存储用户Token列表，保留至最晚的刷新期限（列表为空时删除，调用方需持有写锁）
+// 列表不参与LRU淘汰，否则其中的Token将无法被DeleteUserTokens找到
+func (c *TokenCache) storeUserTokens(userID string, tokenIDs []string, tokens map[string]*yggdrasil.Token) error {
+	userTokensKey := generateYggdrasilUserTokensKey(userID)
+	if len(tokenIDs) == 0 {
 		return c.cache.Delete(userTokensKey)
 	}
 
-	// 更新Token列表
-	userTokensTTL := 7 * 24 * time.Hour // 7天
-	return c.cache.Store(userTokensKey, accessTokens, userTokensTTL)
+	var deadline time.Time
+	for _, tokenID := range tokenIDs {
+		if token := tokens[tokenID]; token != nil && token.RefreshDeadline().After(deadline) {
+			deadline = token.RefreshDeadline()
+		}
+	}
+	return c.cache.StorePinned(userTokensKey, tokenIDs, time.Until(deadline))
+}
+
+// removeTokenFromUserList 从用户Token列表中移除指定TokenID（同时移除已失效的Token）
+func (c *TokenCache) removeTokenFromUserList(userID, tokenID string) error {
+	userTokensKey := generateYggdrasilUserTokensKey(userID)
+
+	var tokenIDs []string
+	if err := c.cache.Get(userTokensKey, &tokenIDs); err != nil {
+		return nil // 用户没有Token列表
+	}
+
+	live, tokens := c.liveTokens(userID, tokenID, tokenIDs)
+	return c.storeUserTokens(userID, live, tokens)
+}
+
+// tokenEvicted Token文件因容量限制被淘汰时产生撤销事件（列表中的TokenID在之后的写入或清理时移除）
+func (c *TokenCache) tokenEvicted(key string, expiresAt time.Time) {
+	_, tokenID, ok := parseOptimizedTokenKey(key)
+	if !ok {
+		return
+	}
+	c.revocations.Notify([]revocation.Entry{{TokenID: tokenID, ExpiresAt: expiresAt}})
 }
 
 // generateOptimizedTokenKey 生成优化的Token键（用户ID+TokenID）
 func generateOptimizedTokenKey(userID, tokenID string) string {
 	return fmt.Sprintf("yggdrasil:token:%s:%s", userID, tokenID)
 }
+
+// parseOptimizedTokenKey 从Token键中提取用户ID和TokenID
+func parseOptimizedTokenKey(key string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(key, "yggdrasil:token:")
+	if !ok {
+		return "", "", false
+	}
+	i := strings.LastIndex(rest, ":")
+	if i < 0 {
+		return "", "", false
+	}
+	return rest[:i], rest[i+1:], true
+}
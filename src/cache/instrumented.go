@@ -0,0 +1,184 @@
+// Package cache 缓存操作统计装饰器
+package cache
+
+import (
+	"errors"
+	"time"
+
+	"yggdrasil-api-go/src/cache/revocation"
+	"yggdrasil-api-go/src/utils"
+	"yggdrasil-api-go/src/yggdrasil"
+)
+
+// InstrumentedTokenCache 记录Token缓存每个操作的次数、错误和延迟（按GetCacheType()区分后端）
+type InstrumentedTokenCache struct {
+	inner   TokenCache
+	backend string
+}
+
+// NewInstrumentedTokenCache 为Token缓存增加操作统计
+func NewInstrumentedTokenCache(inner TokenCache) *InstrumentedTokenCache {
+	return &InstrumentedTokenCache{
+		inner:   inner,
+		backend: inner.GetCacheType(),
+	}
+}
+
+// record 记录Token缓存操作
+func (c *InstrumentedTokenCache) record(op string, start time.Time, err error) {
+	utils.GlobalCacheMetrics.Record("token", c.backend, op, time.Since(start), err)
+}
+
+// Store 存储Token
+func (c *InstrumentedTokenCache) Store(token *yggdrasil.Token) error {
+	start := time.Now()
+	err := c.inner.Store(token)
+	c.record("store", start, err)
+	return err
+}
+
+// StoreWithLimit 存储Token并撤销最旧的Token
+func (c *InstrumentedTokenCache) StoreWithLimit(token *yggdrasil.Token, limit int) (int, error) {
+	start := time.Now()
+	revoked, err := c.inner.StoreWithLimit(token, limit)
+	c.record("store_with_limit", start, err)
+	return revoked, err
+}
+
+// Get 获取Token（未找到计为miss，其他错误计为错误）
+func (c *InstrumentedTokenCache) Get(accessToken string) (*yggdrasil.Token, error) {
+	start := time.Now()
+	token, err := c.inner.Get(accessToken)
+	if err == nil || errors.Is(err, yggdrasil.ErrTokenNotFound) {
+		utils.GlobalCacheMetrics.RecordLookup("token", c.backend, "get", time.Since(start), err == nil)
+	} else {
+		c.record("get", start, err)
+	}
+	return token, err
+}
+
+// Delete 删除Token
+func (c *InstrumentedTokenCache) Delete(accessToken string) error {
+	start := time.Now()
+	err := c.inner.Delete(accessToken)
+	c.record("delete", start, err)
+	return err
+}
+
+// GetUserTokens 获取用户的所有Token
+func (c *InstrumentedTokenCache) GetUserTokens(userID string) ([]*yggdrasil.Token, error) {
+	start := time.Now()
+	tokens, err := c.inner.GetUserTokens(userID)
+	c.record("get_user_tokens", start, err)
+	return tokens, err
+}
+
+// DeleteUserTokens 删除用户的所有Token
+func (c *InstrumentedTokenCache) DeleteUserTokens(userID string) error {
+	start := time.Now()
+	err := c.inner.DeleteUserTokens(userID)
+	c.record("delete_user_tokens", start, err)
+	return err
+}
+
+// GetUserTokenCount 获取用户Token数量
+func (c *InstrumentedTokenCache) GetUserTokenCount(userID string) (int, error) {
+	start := time.Now()
+	count, err := c.inner.GetUserTokenCount(userID)
+	c.record("get_user_token_count", start, err)
+	return count, err
+}
+
+// CleanupExpired 清理过期Token
+func (c *InstrumentedTokenCache) CleanupExpired() error {
+	start := time.Now()
+	err := c.inner.CleanupExpired()
+	c.record("cleanup_expired", start, err)
+	return err
+}
+
+// SubscribeRevocations 订阅Token撤销事件
+func (c *InstrumentedTokenCache) SubscribeRevocations(handler func([]revocation.Entry)) {
+	c.inner.SubscribeRevocations(handler)
+}
+
+// ExternallyManaged 被装饰缓存的Token是否可能由其他程序签发或删除
+func (c *InstrumentedTokenCache) ExternallyManaged() bool {
+	managed, ok := c.inner.(ExternallyManaged)
+	return ok && managed.ExternallyManaged()
+}
+
+// Close 关闭缓存连接
+func (c *InstrumentedTokenCache) Close() error {
+	return c.inner.Close()
+}
+
+// GetCacheType 获取缓存类型
+func (c *InstrumentedTokenCache) GetCacheType() string {
+	return c.backend
+}
+
+// InstrumentedSessionCache 记录Session缓存每个操作的次数、错误和延迟（按GetCacheType()区分后端）
+type InstrumentedSessionCache struct {
+	inner   SessionCache
+	backend string
+}
+
+// NewInstrumentedSessionCache 为Session缓存增加操作统计
+func NewInstrumentedSessionCache(inner SessionCache) *InstrumentedSessionCache {
+	return &InstrumentedSessionCache{
+		inner:   inner,
+		backend: inner.GetCacheType(),
+	}
+}
+
+// record 记录Session缓存操作
+func (c *InstrumentedSessionCache) record(op string, start time.Time, err error) {
+	utils.GlobalCacheMetrics.Record("session", c.backend, op, time.Since(start), err)
+}
+
+// Store 存储Session
+func (c *InstrumentedSessionCache) Store(serverID string, session *yggdrasil.Session) error {
+	start := time.Now()
+	err := c.inner.Store(serverID, session)
+	c.record("store", start, err)
+	return err
+}
+
+// Get 获取Session（未找到计为miss，其他错误计为错误）
+func (c *InstrumentedSessionCache) Get(serverID string) (*yggdrasil.Session, error) {
+	start := time.Now()
+	session, err := c.inner.Get(serverID)
+	if err == nil || errors.Is(err, yggdrasil.ErrSessionNotFound) {
+		utils.GlobalCacheMetrics.RecordLookup("session", c.backend, "get", time.Since(start), err == nil)
+	} else {
+		c.record("get", start, err)
+	}
+	return session, err
+}
+
+// Delete 删除Session
+func (c *InstrumentedSessionCache) Delete(serverID string) error {
+	start := time.Now()
+	err := c.inner.Delete(serverID)
+	c.record("delete", start, err)
+	return err
+}
+
+// CleanupExpired 清理过期Session
+func (c *InstrumentedSessionCache) CleanupExpired() error {
+	start := time.Now()
+	err := c.inner.CleanupExpired()
+	c.record("cleanup_expired", start, err)
+	return err
+}
+
+// Close 关闭缓存连接
+func (c *InstrumentedSessionCache) Close() error {
+	return c.inner.Close()
+}
+
+// GetCacheType 获取缓存类型
+func (c *InstrumentedSessionCache) GetCacheType() string {
+	return c.backend
+}
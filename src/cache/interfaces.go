@@ -4,6 +4,8 @@ package cache
 import (
 	"time"
 
+	"yggdrasil-api-go/src/cache/revocation"
+	storage "yggdrasil-api-go/src/storage/interface"
 	"yggdrasil-api-go/src/yggdrasil"
 )
 
@@ -12,6 +14,10 @@ type TokenCache interface {
 	// Store 存储Token
 	Store(token *yggdrasil.Token) error
 
+	// StoreWithLimit 存储Token并原子地撤销该用户最旧（按CreatedAt）的Token，使有效Token数不超过limit
+	// limit<=0表示不限制，返回被撤销的Token数量
+	StoreWithLimit(token *yggdrasil.Token, limit int) (int, error)
+
 	// Get 获取Token
 	Get(accessToken string) (*yggdrasil.Token, error)
 
@@ -30,6 +36,10 @@ type TokenCache interface {
 	// CleanupExpired 清理过期Token
 	CleanupExpired() error
 
+	// SubscribeRevocations 订阅Token撤销事件（Delete、DeleteUserTokens和数量限制淘汰）
+	// 共享后端（Redis、数据库）还会收到其他实例产生的撤销事件；订阅时先收到已分发且仍未过期的撤销记录
+	SubscribeRevocations(handler func([]revocation.Entry))
+
 	// Close 关闭缓存连接
 	Close() error
 
@@ -37,6 +47,12 @@ type TokenCache interface {
 	GetCacheType() string
 }
 
+// ExternallyManaged 可选接口：Token可能由其他程序签发或删除的缓存（如与BlessingSkin插件互通时）
+// 此类缓存的撤销事件不完整，访问令牌也不一定是本服务签发的JWT，进入服务器时需要查询缓存确认令牌
+type ExternallyManaged interface {
+	ExternallyManaged() bool
+}
+
 // SessionCache Session缓存接口
 type SessionCache interface {
 	// Store 存储Session
@@ -58,6 +74,28 @@ type SessionCache interface {
 	GetCacheType() string
 }
 
+// LoginAttemptCache 登录失败记录缓存接口（共享后端时多个实例共用同一份记录）
+type LoginAttemptCache interface {
+	// RecordFailure 原子地增加失败次数并更新最近失败时间，返回更新后的记录
+	// 记录在ttl后过期；已过期的记录视为不存在，重新从1开始计数
+	RecordFailure(key string, ttl time.Duration) (*yggdrasil.LoginAttempts, error)
+
+	// Get 获取失败记录（不存在或已过期时返回nil）
+	Get(key string) (*yggdrasil.LoginAttempts, error)
+
+	// Reset 清除失败记录（不存在时不报错）
+	Reset(key string) error
+
+	// CleanupExpired 清理过期记录
+	CleanupExpired() error
+
+	// Close 关闭缓存连接
+	Close() error
+
+	// GetCacheType 获取缓存类型
+	GetCacheType() string
+}
+
 // CacheFactory 缓存工厂接口
 type CacheFactory interface {
 	// CreateTokenCache 创建Token缓存实例
@@ -66,8 +104,23 @@ type CacheFactory interface {
 	// CreateSessionCache 创建Session缓存实例
 	CreateSessionCache(cacheType string, options map[string]any) (SessionCache, error)
 
+	// CreateLoginAttemptCache 创建登录失败记录缓存实例（不支持插件互通模式，忽略interop选项）
+	CreateLoginAttemptCache(cacheType string, options map[string]any) (LoginAttemptCache, error)
+
 	// GetSupportedTypes 获取支持的缓存类型
 	GetSupportedTypes() []string
+
+	// RegisterRedisConnection 注册命名Redis连接（缓存选项connection引用同一名称时共用连接池）
+	RegisterRedisConnection(name string, options map[string]any)
+
+	// RegisterDatabaseConnection 注册命名数据库连接（缓存选项connection引用同一名称时共用连接池）
+	RegisterDatabaseConnection(name string, options map[string]any)
+
+	// SetUserStorage 设置用户存储（插件互通模式的Token缓存以邮箱标识令牌所有者，需要查询用户）
+	SetUserStorage(users storage.UserStorage)
+
+	// Close 关闭工厂持有的共享连接（应在缓存关闭之后调用）
+	Close() error
 }
 
 // CacheConfig 缓存配置
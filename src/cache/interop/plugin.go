@@ -0,0 +1,162 @@
+// Package interop 与BlessingSkin yggdrasil-api插件（PHP）互通的缓存实现
+// 以插件的缓存键和数据结构读写Laravel缓存（Redis或文件），两种实现可以在同一域名下并行运行，
+// 一方签发的令牌和创建的会话另一方都能识别，便于逐步迁移
+//
+// 插件的缓存结构（值均先由插件serialize()，再由Laravel缓存serialize()）：
+//
+//	yggdrasil-token-{accessToken}  Yggdrasil\Models\Token对象（clientToken、accessToken、profileId、owner、createdAt）
+//	yggdrasil-id-{email}           该用户的Token对象数组
+//	yggdrasil-server-{serverId}    数组（accessToken、selectedProfile、ip；本服务写入时附加tokenId、userAgent，插件会忽略）
+//
+// 插件以用户邮箱标识令牌所有者，createdAt为Unix秒，有效期由选项ygg_token_expire_1/ygg_token_expire_2决定
+package interop
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"yggdrasil-api-go/src/yggdrasil"
+
+	"github.com/trim21/go-phpserialize"
+)
+
+// pluginTokenClass 插件Token模型的PHP类名
+const pluginTokenClass = `Yggdrasil\Models\Token`
+
+// Store Laravel缓存存储（值为Laravel序列化后的内容，过期由存储负责）
+type Store interface {
+	// Get 获取缓存值及其过期时间
+	Get(key string) (value string, expiresAt time.Time, found bool, err error)
+
+	// Put 写入缓存值
+	Put(key, value string, ttl time.Duration) error
+
+	// Forget 删除缓存值
+	Forget(key string) error
+
+	// CleanupExpired 清理过期缓存（存储自身按TTL过期时为空操作）
+	CleanupExpired() error
+
+	// Close 关闭存储
+	Close() error
+
+	// Type 存储类型
+	Type() string
+}
+
+// tokenKey 插件Token键
+func tokenKey(accessToken string) string {
+	return "yggdrasil-token-" + accessToken
+}
+
+// userTokensKey 插件用户Token列表键
+func userTokensKey(email string) string {
+	return "yggdrasil-id-" + email
+}
+
+// sessionKey 插件Session键
+func sessionKey(serverID string) string {
+	return "yggdrasil-server-" + serverID
+}
+
+// pluginToken 插件的Yggdrasil\Models\Token对象
+type pluginToken struct {
+	ClientToken string `php:"clientToken"`
+	AccessToken string `php:"accessToken"`
+	ProfileID   string `php:"profileId"`
+	Owner       string `php:"owner"` // 用户邮箱
+	CreatedAt   int64  `php:"createdAt"`
+}
+
+// newPluginToken 转换为插件Token对象
+func newPluginToken(token *yggdrasil.Token, email string) *pluginToken {
+	return &pluginToken{
+		ClientToken: token.ClientToken,
+		AccessToken: token.AccessToken,
+		ProfileID:   token.ProfileID,
+		Owner:       email,
+		CreatedAt:   token.CreatedAt.Unix(),
+	}
+}
+
+// toToken 转换为Token（有效期按插件规则自创建时间起算）
+func (t *pluginToken) toToken(userID string, expiration, refreshExpiration time.Duration) *yggdrasil.Token {
+	createdAt := time.Unix(t.CreatedAt, 0)
+	return &yggdrasil.Token{
+		AccessToken:      t.AccessToken,
+		ClientToken:      t.ClientToken,
+		ProfileID:        t.ProfileID,
+		Owner:            userID,
+		CreatedAt:        createdAt,
+		ExpiresAt:        createdAt.Add(expiration),
+		RefreshExpiresAt: createdAt.Add(refreshExpiration),
+	}
+}
+
+// serialize 按PHP serialize()的格式序列化Token对象
+func (t *pluginToken) serialize() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "O:%d:\"%s\":5:{", len(pluginTokenClass), pluginTokenClass)
+	writeString(&b, "clientToken")
+	writeString(&b, t.ClientToken)
+	writeString(&b, "accessToken")
+	writeString(&b, t.AccessToken)
+	writeString(&b, "profileId")
+	writeString(&b, t.ProfileID)
+	writeString(&b, "owner")
+	writeString(&b, t.Owner)
+	writeString(&b, "createdAt")
+	b.WriteString("i:" + strconv.FormatInt(t.CreatedAt, 10) + ";")
+	b.WriteString("}")
+	return b.String()
+}
+
+// serializeTokens 按PHP serialize()的格式序列化Token对象数组
+func serializeTokens(tokens []*pluginToken) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "a:%d:{", len(tokens))
+	for i, token := range tokens {
+		b.WriteString("i:" + strconv.Itoa(i) + ";")
+		b.WriteString(token.serialize())
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// pluginSession 插件的会话数组
+type pluginSession struct {
+	AccessToken     string `php:"accessToken"`
+	SelectedProfile string `php:"selectedProfile"`
+	IP              string `php:"ip"`
+	TokenID         string `php:"tokenId,omitempty"`   // 插件写入的会话没有此项
+	UserAgent       string `php:"userAgent,omitempty"` // 插件写入的会话没有此项
+}
+
+// writeString 写入PHP字符串（长度按字节计算）
+func writeString(b *strings.Builder, s string) {
+	b.WriteString("s:" + strconv.Itoa(len(s)) + ":\"" + s + "\";")
+}
+
+// encodeValue 将插件serialize()的结果再按Laravel缓存的方式序列化
+func encodeValue(serialized string) string {
+	var b strings.Builder
+	writeString(&b, serialized)
+	return b.String()
+}
+
+// decodeValue 解码缓存值（兼容插件未预先serialize()直接存入的值）
+func decodeValue(value string, target any) error {
+	if strings.HasPrefix(value, "s:") {
+		var inner string
+		if err := phpserialize.Unmarshal([]byte(value), &inner); err != nil {
+			return fmt.Errorf("failed to unserialize cached value: %w", err)
+		}
+		value = inner
+	}
+	if err := phpserialize.Unmarshal([]byte(value), target); err != nil {
+		return fmt.Errorf("failed to unserialize plugin data: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package interop
+
+import (
+	"fmt"
+	"time"
+
+	"yggdrasil-api-go/src/yggdrasil"
+
+	"github.com/trim21/go-phpserialize"
+)
+
+// SessionCache 以插件格式读写的Session缓存
+// 插件的会话数组不包含创建时间，读取时按缓存的过期时间减去会话有效期推算
+type SessionCache struct {
+	store Store
+	ttl   time.Duration // 插件写入会话时使用的有效期（为0时与yggdrasil.SessionTTL()一致）
+}
+
+// NewSessionCache 创建插件格式的Session缓存
+// 选项：session_ttl（插件写入会话时使用的有效期，默认与本服务的会话有效期一致）
+func NewSessionCache(store Store, options map[string]any) (*SessionCache, error) {
+	c := &SessionCache{store: store}
+	switch v := options["session_ttl"].(type) {
+	case time.Duration:
+		c.ttl = v
+	case string:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid session_ttl: %w", err)
+		}
+		c.ttl = d
+	}
+	return c, nil
+}
+
+// Store 存储Session
+func (c *SessionCache) Store(serverID string, session *yggdrasil.Session) error {
+	ttl := time.Until(session.ExpiresAt())
+	if ttl <= 0 {
+		return fmt.Errorf("session already expired")
+	}
+
+	serialized, err := phpserialize.Marshal(&pluginSession{
+		AccessToken:     session.AccessToken,
+		SelectedProfile: session.ProfileID,
+		IP:              session.ClientIP,
+		TokenID:         session.TokenID,
+		UserAgent:       session.UserAgent,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to serialize session: %w", err)
+	}
+
+	if err := c.store.Put(sessionKey(serverID), encodeValue(string(serialized)), ttl); err != nil {
+		return fmt.Errorf("failed to store session: %w", err)
+	}
+	return nil
+}
+
+// Get 获取Session
+func (c *SessionCache) Get(serverID string) (*yggdrasil.Session, error) {
+	value, expiresAt, found, err := c.store.Get(sessionKey(serverID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	if !found {
+		return nil, yggdrasil.ErrSessionNotFound
+	}
+
+	var stored pluginSession
+	if err := decodeValue(value, &stored); err != nil {
+		return nil, err
+	}
+
+	ttl := c.ttl
+	if ttl <= 0 {
+		ttl = yggdrasil.SessionTTL()
+	}
+
+	return &yggdrasil.Session{
+		ServerID:    serverID,
+		AccessToken: stored.AccessToken,
+		TokenID:     stored.TokenID,
+		ProfileID:   stored.SelectedProfile,
+		ClientIP:    stored.IP,
+		UserAgent:   stored.UserAgent,
+		CreatedAt:   expiresAt.Add(-ttl),
+	}, nil
+}
+
+// Delete 删除Session
+func (c *SessionCache) Delete(serverID string) error {
+	if err := c.store.Forget(sessionKey(serverID)); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// CleanupExpired 清理过期Session
+func (c *SessionCache) CleanupExpired() error {
+	return c.store.CleanupExpired()
+}
+
+// Close 关闭缓存
+func (c *SessionCache) Close() error {
+	return c.store.Close()
+}
+
+// GetCacheType 获取缓存类型
+func (c *SessionCache) GetCacheType() string {
+	return c.store.Type() + "-interop"
+}
@@ -0,0 +1,354 @@
+package interop
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"yggdrasil-api-go/src/cache/revocation"
+	storage "yggdrasil-api-go/src/storage/interface"
+	"yggdrasil-api-go/src/utils"
+	"yggdrasil-api-go/src/yggdrasil"
+)
+
+// 插件默认有效期（ygg_token_expire_1、ygg_token_expire_2）
+const (
+	defaultTokenExpiration        = 72 * time.Hour
+	defaultTokenRefreshExpiration = 168 * time.Hour
+)
+
+// TokenCache 以插件格式读写的Token缓存
+// 插件签发的访问令牌不是本服务的JWT，因此Get直接按访问令牌查询缓存；
+// 撤销事件仅包含本服务签发的JWT令牌，插件删除的令牌需要查询缓存才能发现
+type TokenCache struct {
+	store Store
+	users storage.UserStorage // 用户ID与邮箱的对应关系
+
+	expiration        time.Duration
+	refreshExpiration time.Duration
+
+	mu          sync.Mutex // 用户Token列表的读-改-写（仅进程内互斥，与插件并发写入时以后写者为准）
+	revocations revocation.Notifier
+}
+
+// NewTokenCache 创建插件格式的Token缓存
+// 选项：token_expiration、token_refresh_expiration（插件令牌的有效期，存储提供BlessingSkin选项时以选项为准）
+func NewTokenCache(store Store, users storage.UserStorage, options map[string]any) (*TokenCache, error) {
+	if users == nil {
+		return nil, fmt.Errorf("blessingskin interop requires user storage")
+	}
+
+	c := &TokenCache{
+		store:             store,
+		users:             users,
+		expiration:        defaultTokenExpiration,
+		refreshExpiration: defaultTokenRefreshExpiration,
+	}
+	for name, target := range map[string]*time.Duration{
+		"token_expiration":         &c.expiration,
+		"token_refresh_expiration": &c.refreshExpiration,
+	} {
+		switch v := options[name].(type) {
+		case time.Duration:
+			*target = v
+		case string:
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", name, err)
+			}
+			*target = d
+		}
+	}
+	return c, nil
+}
+
+// Store 存储Token
+func (c *TokenCache) Store(token *yggdrasil.Token) error {
+	_, err := c.StoreWithLimit(token, 0)
+	return err
+}
+
+// StoreWithLimit 存储Token并撤销该用户最旧的Token，使有效Token数不超过limit
+func (c *TokenCache) StoreWithLimit(token *yggdrasil.Token, limit int) (int, error) {
+	ttl := time.Until(token.RefreshDeadline())
+	if ttl <= 0 {
+		return 0, fmt.Errorf("token already expired")
+	}
+
+	user, err := c.users.GetUserByID(token.Owner)
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve token owner: %w", err)
+	}
+
+	stored := newPluginToken(token, user.Email)
+	if err := c.store.Put(tokenKey(token.AccessToken), encodeValue(stored.serialize()), ttl); err != nil {
+		return 0, fmt.Errorf("failed to store token: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tokens, err := c.loadUserTokens(user.Email)
+	if err != nil {
+		return 0, err
+	}
+	tokens = slices.DeleteFunc(tokens, func(t *pluginToken) bool {
+		return t.AccessToken == token.AccessToken
+	})
+
+	var evicted []*pluginToken
+	if limit > 0 && len(tokens) > limit-1 {
+		// 按创建时间升序排列，撤销最旧的Token
+		slices.SortStableFunc(tokens, func(a, b *pluginToken) int {
+			return cmp.Compare(a.CreatedAt, b.CreatedAt)
+		})
+		excess := len(tokens) - (limit - 1)
+		evicted, tokens = tokens[:excess], tokens[excess:]
+		for _, victim := range evicted {
+			if err := c.store.Forget(tokenKey(victim.AccessToken)); err != nil {
+				return 0, fmt.Errorf("failed to evict token: %w", err)
+			}
+		}
+	}
+
+	if err := c.saveUserTokens(user.Email, append(tokens, stored)); err != nil {
+		return 0, err
+	}
+
+	c.revocations.Notify(revocationEntries(evicted))
+	return len(evicted), nil
+}
+
+// Get 获取Token（访问令牌可以是插件签发的非JWT令牌）
+func (c *TokenCache) Get(accessToken string) (*yggdrasil.Token, error) {
+	stored, err := c.getToken(accessToken)
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		return nil, yggdrasil.ErrTokenNotFound
+	}
+
+	user, err := c.users.GetUserByEmail(stored.Owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve token owner: %w", err)
+	}
+
+	expiration, refreshExpiration := c.expirations()
+	token := stored.toToken(user.ID, expiration, refreshExpiration)
+	if !token.IsRefreshable() {
+		return nil, yggdrasil.ErrTokenNotFound
+	}
+	return token, nil
+}
+
+// Delete 删除Token并从所有者的Token列表中移除
+func (c *TokenCache) Delete(accessToken string) error {
+	stored, err := c.getToken(accessToken)
+	if err != nil {
+		return err
+	}
+	if stored == nil {
+		return nil
+	}
+
+	if err := c.store.Forget(tokenKey(accessToken)); err != nil {
+		return fmt.Errorf("failed to delete token: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tokens, err := c.loadUserTokens(stored.Owner)
+	if err != nil {
+		return err
+	}
+	tokens = slices.DeleteFunc(tokens, func(t *pluginToken) bool {
+		return t.AccessToken == accessToken
+	})
+	if err := c.saveUserTokens(stored.Owner, tokens); err != nil {
+		return err
+	}
+
+	c.revocations.Notify(revocationEntries([]*pluginToken{stored}))
+	return nil
+}
+
+// GetUserTokens 获取用户的所有Token（列表中已被删除或过期的Token会被忽略）
+func (c *TokenCache) GetUserTokens(userID string) ([]*yggdrasil.Token, error) {
+	user, err := c.users.GetUserByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve user: %w", err)
+	}
+
+	c.mu.Lock()
+	listed, err := c.loadUserTokens(user.Email)
+	c.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	expiration, refreshExpiration := c.expirations()
+	tokens := []*yggdrasil.Token{}
+	for _, entry := range listed {
+		stored, err := c.getToken(entry.AccessToken)
+		if err != nil {
+			return nil, err
+		}
+		if stored == nil {
+			continue
+		}
+		if token := stored.toToken(user.ID, expiration, refreshExpiration); token.IsRefreshable() {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+// DeleteUserTokens 删除用户的所有Token
+func (c *TokenCache) DeleteUserTokens(userID string) error {
+	user, err := c.users.GetUserByID(userID)
+	if err != nil {
+		return fmt.Errorf("failed to resolve user: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tokens, err := c.loadUserTokens(user.Email)
+	if err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		if err := c.store.Forget(tokenKey(token.AccessToken)); err != nil {
+			return fmt.Errorf("failed to delete token: %w", err)
+		}
+	}
+	if err := c.store.Forget(userTokensKey(user.Email)); err != nil {
+		return fmt.Errorf("failed to delete user tokens: %w", err)
+	}
+
+	c.revocations.Notify(revocationEntries(tokens))
+	return nil
+}
+
+// GetUserTokenCount 获取用户Token数量（仅统计仍然有效的Token）
+func (c *TokenCache) GetUserTokenCount(userID string) (int, error) {
+	tokens, err := c.GetUserTokens(userID)
+	if err != nil {
+		return 0, err
+	}
+	return len(tokens), nil
+}
+
+// CleanupExpired 清理过期Token
+func (c *TokenCache) CleanupExpired() error {
+	return c.store.CleanupExpired()
+}
+
+// SubscribeRevocations 订阅Token撤销事件（仅包含本实例撤销的JWT令牌）
+func (c *TokenCache) SubscribeRevocations(handler func([]revocation.Entry)) {
+	c.revocations.Subscribe(handler)
+}
+
+// ExternallyManaged 令牌可能由插件签发或删除
+func (c *TokenCache) ExternallyManaged() bool {
+	return true
+}
+
+// Close 关闭缓存
+func (c *TokenCache) Close() error {
+	return c.store.Close()
+}
+
+// GetCacheType 获取缓存类型
+func (c *TokenCache) GetCacheType() string {
+	return c.store.Type() + "-interop"
+}
+
+// expirations 获取插件令牌的有效期和可刷新期限
+func (c *TokenCache) expirations() (time.Duration, time.Duration) {
+	if provider, ok := c.users.(storage.TokenExpirationProvider); ok {
+		if expiration, refreshExpiration, ok := provider.GetTokenExpiration(); ok {
+			return expiration, refreshExpiration
+		}
+	}
+	return c.expiration, c.refreshExpiration
+}
+
+// getToken 读取插件Token对象（不存在时返回nil）
+func (c *TokenCache) getToken(accessToken string) (*pluginToken, error) {
+	value, _, found, err := c.store.Get(tokenKey(accessToken))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token: %w", err)
+	}
+	if !found {
+		return nil, nil
+	}
+
+	var token pluginToken
+	if err := decodeValue(value, &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// loadUserTokens 读取用户Token列表并移除已超过刷新期限的Token（调用方需持有锁）
+func (c *TokenCache) loadUserTokens(email string) ([]*pluginToken, error) {
+	value, _, found, err := c.store.Get(userTokensKey(email))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user tokens: %w", err)
+	}
+	if !found {
+		return nil, nil
+	}
+
+	var tokens []*pluginToken
+	if err := decodeValue(value, &tokens); err != nil {
+		return nil, err
+	}
+
+	_, refreshExpiration := c.expirations()
+	now := time.Now()
+	return slices.DeleteFunc(tokens, func(t *pluginToken) bool {
+		return t == nil || !now.Before(time.Unix(t.CreatedAt, 0).Add(refreshExpiration))
+	}), nil
+}
+
+// saveUserTokens 写入用户Token列表，保留至最晚的刷新期限（列表为空时删除，调用方需持有锁）
+func (c *TokenCache) saveUserTokens(email string, tokens []*pluginToken) error {
+	if len(tokens) == 0 {
+		if err := c.store.Forget(userTokensKey(email)); err != nil {
+			return fmt.Errorf("failed to update user tokens: %w", err)
+		}
+		return nil
+	}
+
+	_, refreshExpiration := c.expirations()
+	var latest int64
+	for _, token := range tokens {
+		latest = max(latest, token.CreatedAt)
+	}
+	ttl := time.Until(time.Unix(latest, 0).Add(refreshExpiration))
+	if ttl <= 0 {
+		ttl = time.Second
+	}
+
+	if err := c.store.Put(userTokensKey(email), encodeValue(serializeTokens(tokens)), ttl); err != nil {
+		return fmt.Errorf("failed to update user tokens: %w", err)
+	}
+	return nil
+}
+
+// revocationEntries 生成撤销记录（插件签发的非JWT令牌不会进入撤销集合，跳过）
+func revocationEntries(tokens []*pluginToken) []revocation.Entry {
+	var entries []revocation.Entry
+	for _, token := range tokens {
+		if claims, err := utils.ValidateJWT(token.AccessToken); err == nil {
+			entries = append(entries, revocation.EntryFromClaims(claims))
+		}
+	}
+	return entries
+}
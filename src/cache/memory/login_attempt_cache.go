@@ -0,0 +1,94 @@
+// Package memory 内存登录失败记录缓存实现
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"yggdrasil-api-go/src/yggdrasil"
+)
+
+// LoginAttemptCache 内存登录失败记录缓存（仅在单个实例内生效）
+type LoginAttemptCache struct {
+	attempts map[string]*loginAttemptEntry
+	mu       sync.Mutex
+}
+
+// loginAttemptEntry 登录失败记录缓存条目
+type loginAttemptEntry struct {
+	Attempts  yggdrasil.LoginAttempts
+	ExpiresAt time.Time
+}
+
+// NewLoginAttemptCache 创建内存登录失败记录缓存
+func NewLoginAttemptCache(options map[string]any) (*LoginAttemptCache, error) {
+	return &LoginAttemptCache{
+		attempts: make(map[string]*loginAttemptEntry),
+	}, nil
+}
+
+// RecordFailure 增加失败次数
+func (c *LoginAttemptCache) RecordFailure(key string, ttl time.Duration) (*yggdrasil.LoginAttempts, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	entry, exists := c.attempts[key]
+	if !exists || now.After(entry.ExpiresAt) {
+		entry = &loginAttemptEntry{}
+		c.attempts[key] = entry
+	}
+	entry.Attempts.Failures++
+	entry.Attempts.LastFailure = now
+	entry.ExpiresAt = now.Add(ttl)
+
+	attempts := entry.Attempts
+	return &attempts, nil
+}
+
+// Get 获取失败记录
+func (c *LoginAttemptCache) Get(key string) (*yggdrasil.LoginAttempts, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, exists := c.attempts[key]
+	if !exists || time.Now().After(entry.ExpiresAt) {
+		return nil, nil
+	}
+	attempts := entry.Attempts
+	return &attempts, nil
+}
+
+// Reset 清除失败记录
+func (c *LoginAttemptCache) Reset(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.attempts, key)
+	return nil
+}
+
+// CleanupExpired 清理过期记录
+func (c *LoginAttemptCache) CleanupExpired() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for key, entry := range c.attempts {
+		if now.After(entry.ExpiresAt) {
+			delete(c.attempts, key)
+		}
+	}
+	return nil
+}
+
+// Close 关闭缓存连接
+func (c *LoginAttemptCache) Close() error {
+	// 内存缓存无需关闭操作
+	return nil
+}
+
+// GetCacheType 获取缓存类型
+func (c *LoginAttemptCache) GetCacheType() string {
+	return "memory"
+}
@@ -33,12 +33,13 @@ func (c *SessionCache) Store(serverID string, session *yggdrasil.Session) error
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	// Session固定过期时间为120秒
-	expiresAt := time.Now().Add(120 * time.Second)
+	// 存储副本，过期时间自会话创建起计算
+	sessionCopy := *session
+	sessionCopy.ServerID = serverID
 
 	c.sessions[serverID] = &sessionEntry{
-		Session:   session,
-		ExpiresAt: expiresAt,
+		Session:   &sessionCopy,
+		ExpiresAt: session.ExpiresAt(),
 	}
 
 	return nil
@@ -51,12 +52,12 @@ func (c *SessionCache) Get(serverID string) (*yggdrasil.Session, error) {
 
 	entry, exists := c.sessions[serverID]
 	if !exists {
-		return nil, fmt.Errorf("session not found")
+		return nil, yggdrasil.ErrSessionNotFound
 	}
 
 	// 检查是否过期
 	if time.Now().After(entry.ExpiresAt) {
-		return nil, fmt.Errorf("session expired")
+		return nil, fmt.Errorf("%w: session expired", yggdrasil.ErrSessionNotFound)
 	}
 
 	// 返回副本
@@ -6,6 +6,7 @@ import (
 	"slices"
 	"sync"
 
+	"yggdrasil-api-go/src/cache/revocation"
 	"yggdrasil-api-go/src/utils"
 	"yggdrasil-api-go/src/yggdrasil"
 )
@@ -15,6 +16,8 @@ type TokenCache struct {
 	tokens     map[string]*yggdrasil.Token // "userID:tokenID" -> Token（简化版）
 	userTokens map[string][]string         // userID -> []tokenID
 	mu         sync.RWMutex
+
+	revocations revocation.Notifier
 }
 
 // NewTokenCache 创建内存Token缓存
@@ -27,28 +30,34 @@ func NewTokenCache(options map[string]any) (*TokenCache, error) {
 
 // Store 存储Token（优化版：先验证JWT，提取信息）
 func (c *TokenCache) Store(token *yggdrasil.Token) error {
+	_, err := c.StoreWithLimit(token, 0)
+	return err
+}
+
+// StoreWithLimit 存储Token并撤销该用户最旧的Token，使有效Token数不超过limit
+func (c *TokenCache) StoreWithLimit(token *yggdrasil.Token, limit int) (int, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	// 第一步：验证JWT并提取信息
 	claims, err := utils.ValidateJWT(token.AccessToken)
 	if err != nil {
-		return fmt.Errorf("invalid JWT token: %w", err)
+		return 0, fmt.Errorf("invalid JWT token: %w", err)
 	}
 
 	// 创建简化的Token对象（只存储JWT中没有的信息）
 	cacheToken := &yggdrasil.Token{
-		AccessToken: token.AccessToken, // 保留完整的AccessToken用于兼容性
-		ClientToken: token.ClientToken,
-		ProfileID:   claims.ProfileID, // 从JWT中获取ProfileID
-		Owner:       claims.UserID,    // 从JWT中获取用户ID
-		CreatedAt:   token.CreatedAt,
-		ExpiresAt:   token.ExpiresAt,
+		AccessToken:      token.AccessToken, // 保留完整的AccessToken用于兼容性
+		ClientToken:      token.ClientToken,
+		ProfileID:        claims.ProfileID, // 从JWT中获取ProfileID
+		Owner:            claims.UserID,    // 从JWT中获取用户ID
+		CreatedAt:        token.CreatedAt,
+		ExpiresAt:        token.ExpiresAt,
+		RefreshExpiresAt: token.RefreshExpiresAt,
 	}
 
 	// 存储Token（使用用户ID:TokenID作为键）
-	tokenKey := fmt.Sprintf("%s:%s", claims.UserID, claims.TokenID)
-	c.tokens[tokenKey] = cacheToken
+	c.tokens[tokenKey(claims.UserID, claims.TokenID)] = cacheToken
 
 	// 更新用户Token列表（使用用户ID）
 	userTokens := c.userTokens[claims.UserID]
@@ -60,7 +69,13 @@ func (c *TokenCache) Store(token *yggdrasil.Token) error {
 		c.userTokens[claims.UserID] = append(userTokens, claims.TokenID)
 	}
 
-	return nil
+	if limit <= 0 {
+		return 0, nil
+	}
+
+	evicted := c.evictOldest(claims.UserID, claims.TokenID, limit)
+	c.revocations.Notify(evicted)
+	return len(evicted), nil
 }
 
 // Get 获取Token（优化版：先验证JWT，按需查询缓存）
@@ -68,73 +83,56 @@ func (c *TokenCache) Get(accessToken string) (*yggdrasil.Token, error) {
 	// 第一步：验证JWT（本地计算，极快）
 	claims, err := utils.ValidateJWT(accessToken)
 	if err != nil {
-		return nil, fmt.Errorf("invalid JWT token: %w", err)
+		return nil, fmt.Errorf("%w: invalid JWT token: %w", yggdrasil.ErrTokenNotFound, err)
 	}
 
 	// 第二步：从缓存获取ClientToken等额外信息
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	tokenKey := fmt.Sprintf("%s:%s", claims.UserID, claims.TokenID)
-	token, exists := c.tokens[tokenKey]
-	if !exists {
-		return nil, fmt.Errorf("token not found in cache")
+	token, exists := c.tokens[tokenKey(claims.UserID, claims.TokenID)]
+	if !exists || !token.IsRefreshable() {
+		return nil, yggdrasil.ErrTokenNotFound
 	}
 
 	// 构建Token对象（结合JWT信息和缓存信息）
 	result := &yggdrasil.Token{
-		AccessToken: accessToken,
-		ClientToken: token.ClientToken,
-		ProfileID:   claims.ProfileID,
-		Owner:       claims.UserID,
-		CreatedAt:   token.CreatedAt,
-		ExpiresAt:   token.ExpiresAt,
+		AccessToken:      accessToken,
+		ClientToken:      token.ClientToken,
+		ProfileID:        claims.ProfileID,
+		Owner:            claims.UserID,
+		CreatedAt:        token.CreatedAt,
+		ExpiresAt:        token.ExpiresAt,
+		RefreshExpiresAt: token.RefreshExpiresAt,
 	}
 
 	return result, nil
 }
 
-// Delete 删除Token
+// Delete 删除Token（先验证JWT，提取用户ID和TokenID）
 func (c *TokenCache) Delete(accessToken string) error {
+	claims, err := utils.ValidateJWT(accessToken)
+	if err != nil {
+		// JWT无效，无需删除（兼容性）
+		return nil
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	// 获取Token以找到所有者
-	token, exists := c.tokens[accessToken]
-	if exists {
-		// 从用户Token列表中移除
-		userTokens := c.userTokens[token.Owner]
-		for i, userToken := range userTokens {
-			if userToken == accessToken {
-				c.userTokens[token.Owner] = append(userTokens[:i], userTokens[i+1:]...)
-				break
-			}
-		}
-
-		// 如果用户没有Token了，删除用户条目
-		if len(c.userTokens[token.Owner]) == 0 {
-			delete(c.userTokens, token.Owner)
-		}
-	}
-
-	// 删除Token
-	delete(c.tokens, accessToken)
+	c.removeToken(claims.UserID, claims.TokenID)
+	c.revocations.Notify([]revocation.Entry{revocation.EntryFromClaims(claims)})
 	return nil
 }
 
 // GetUserTokens 获取用户的所有Token
-func (c *TokenCache) GetUserTokens(userEmail string) ([]*yggdrasil.Token, error) {
+func (c *TokenCache) GetUserTokens(userID string) ([]*yggdrasil.Token, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	accessTokens, exists := c.userTokens[userEmail]
-	if !exists {
-		return []*yggdrasil.Token{}, nil
-	}
-
-	var tokens []*yggdrasil.Token
-	for _, accessToken := range accessTokens {
-		if token, exists := c.tokens[accessToken]; exists && token.IsValid() {
+	tokens := []*yggdrasil.Token{}
+	for _, tokenID := range c.userTokens[userID] {
+		if token, exists := c.tokens[tokenKey(userID, tokenID)]; exists && token.IsRefreshable() {
 			tokenCopy := *token
 			tokens = append(tokens, &tokenCopy)
 		}
@@ -144,38 +142,34 @@ func (c *TokenCache) GetUserTokens(userEmail string) ([]*yggdrasil.Token, error)
 }
 
 // DeleteUserTokens 删除用户的所有Token
-func (c *TokenCache) DeleteUserTokens(userEmail string) error {
+func (c *TokenCache) DeleteUserTokens(userID string) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	accessTokens, exists := c.userTokens[userEmail]
-	if !exists {
-		return nil
-	}
-
 	// 删除所有Token
-	for _, accessToken := range accessTokens {
-		delete(c.tokens, accessToken)
+	var revoked []revocation.Entry
+	for _, tokenID := range c.userTokens[userID] {
+		if token, exists := c.tokens[tokenKey(userID, tokenID)]; exists && token.IsRefreshable() {
+			revoked = append(revoked, revocation.Entry{TokenID: tokenID, ExpiresAt: token.RefreshDeadline()})
+		}
+		delete(c.tokens, tokenKey(userID, tokenID))
 	}
 
 	// 删除用户Token列表
-	delete(c.userTokens, userEmail)
+	delete(c.userTokens, userID)
+
+	c.revocations.Notify(revoked)
 	return nil
 }
 
 // GetUserTokenCount 获取用户Token数量
-func (c *TokenCache) GetUserTokenCount(userEmail string) (int, error) {
+func (c *TokenCache) GetUserTokenCount(userID string) (int, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	accessTokens, exists := c.userTokens[userEmail]
-	if !exists {
-		return 0, nil
-	}
-
 	count := 0
-	for _, accessToken := range accessTokens {
-		if token, exists := c.tokens[accessToken]; exists && token.IsValid() {
+	for _, tokenID := range c.userTokens[userID] {
+		if token, exists := c.tokens[tokenKey(userID, tokenID)]; exists && token.IsRefreshable() {
 			count++
 		}
 	}
@@ -188,37 +182,67 @@ func (c *TokenCache) CleanupExpired() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	// 收集过期的Token
-	var expiredTokens []string
-	for accessToken, token := range c.tokens {
-		if !token.IsValid() {
-			expiredTokens = append(expiredTokens, accessToken)
+	for userID, tokenIDs := range c.userTokens {
+		for _, tokenID := range slices.Clone(tokenIDs) {
+			if token, exists := c.tokens[tokenKey(userID, tokenID)]; !exists || !token.IsRefreshable() {
+				c.removeToken(userID, tokenID)
+			}
 		}
 	}
 
-	// 删除过期Token
-	for _, accessToken := range expiredTokens {
-		token := c.tokens[accessToken]
+	return nil
+}
 
-		// 从用户Token列表中移除
-		userTokens := c.userTokens[token.Owner]
-		for i, userToken := range userTokens {
-			if userToken == accessToken {
-				c.userTokens[token.Owner] = append(userTokens[:i], userTokens[i+1:]...)
-				break
-			}
-		}
+// removeToken 删除Token并从用户Token列表中移除（调用方需持有写锁）
+func (c *TokenCache) removeToken(userID, tokenID string) {
+	delete(c.tokens, tokenKey(userID, tokenID))
+
+	userTokens := slices.DeleteFunc(c.userTokens[userID], func(id string) bool {
+		return id == tokenID
+	})
 
-		// 如果用户没有Token了，删除用户条目
-		if len(c.userTokens[token.Owner]) == 0 {
-			delete(c.userTokens, token.Owner)
+	// 如果用户没有Token了，删除用户条目
+	if len(userTokens) == 0 {
+		delete(c.userTokens, userID)
+	} else {
+		c.userTokens[userID] = userTokens
+	}
+}
+
+// evictOldest 撤销用户最旧的Token（不包括keepID），使有效Token数不超过limit，返回被撤销的Token（调用方需持有写锁）
+func (c *TokenCache) evictOldest(userID, keepID string, limit int) []revocation.Entry {
+	var others []string
+	for _, tokenID := range slices.Clone(c.userTokens[userID]) {
+		if tokenID == keepID {
+			continue
+		}
+		if token, exists := c.tokens[tokenKey(userID, tokenID)]; !exists || !token.IsRefreshable() {
+			c.removeToken(userID, tokenID)
+			continue
 		}
+		others = append(others, tokenID)
+	}
 
-		// 删除Token
-		delete(c.tokens, accessToken)
+	excess := len(others) - (limit - 1)
+	if excess <= 0 {
+		return nil
 	}
 
-	return nil
+	// 按创建时间升序排列，撤销最旧的Token
+	slices.SortStableFunc(others, func(a, b string) int {
+		return c.tokens[tokenKey(userID, a)].CreatedAt.Compare(c.tokens[tokenKey(userID, b)].CreatedAt)
+	})
+	evicted := make([]revocation.Entry, 0, excess)
+	for _, tokenID := range others[:excess] {
+		evicted = append(evicted, revocation.Entry{TokenID: tokenID, ExpiresAt: c.tokens[tokenKey(userID, tokenID)].RefreshDeadline()})
+		c.removeToken(userID, tokenID)
+	}
+	return evicted
+}
+
+// SubscribeRevocations 订阅Token撤销事件
+func (c *TokenCache) SubscribeRevocations(handler func([]revocation.Entry)) {
+	c.revocations.Subscribe(handler)
 }
 
 // Close 关闭缓存连接
@@ -231,3 +255,8 @@ func (c *TokenCache) Close() error {
 func (c *TokenCache) GetCacheType() string {
 	return "memory"
 }
+
+// tokenKey 生成Token键（用户ID:TokenID）
+func tokenKey(userID, tokenID string) string {
+	return userID + ":" + tokenID
+}
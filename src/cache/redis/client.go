@@ -0,0 +1,315 @@
+package redis
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Redis部署模式
+const (
+	modeSingle   = "single"   // 单机
+	modeSentinel = "sentinel" // Sentinel主从
+	modeCluster  = "cluster"  // Cluster集群
+)
+
+// clientConfig 解析后的Redis客户端配置
+type clientConfig struct {
+	mode     string
+	options  redis.UniversalOptions
+	hashTags bool // 用户级键是否使用哈希标签（Cluster模式下必须开启）
+}
+
+// parseClientConfig 解析Redis客户端选项
+// redis_url作为基础配置，其余选项（addrs、master_name、username等）会覆盖URL中的对应部分
+func parseClientConfig(options map[string]any) (*clientConfig, error) {
+	cfg := &clientConfig{}
+	opts := &cfg.options
+
+	// 基础配置：redis_url（单机默认为本地Redis）
+	redisURL := "redis://localhost:6379"
+	if url, ok := options["redis_url"].(string); ok && url != "" {
+		redisURL = url
+	}
+	base, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
+	}
+	opts.Addrs = []string{base.Addr}
+	opts.Username = base.Username
+	opts.Password = base.Password
+	opts.DB = base.DB
+	opts.TLSConfig = base.TLSConfig
+
+	// 节点地址（Sentinel地址或Cluster种子节点）
+	if addrs, ok := stringList(options["addrs"]); ok && len(addrs) > 0 {
+		opts.Addrs = addrs
+	}
+	if masterName, ok := options["master_name"].(string); ok {
+		opts.MasterName = masterName
+	}
+
+	// 认证
+	if username, ok := options["username"].(string); ok {
+		opts.Username = username
+	}
+	if password, ok := options["password"].(string); ok {
+		opts.Password = password
+	}
+	if username, ok := options["sentinel_username"].(string); ok {
+		opts.SentinelUsername = username
+	}
+	if password, ok := options["sentinel_password"].(string); ok {
+		opts.SentinelPassword = password
+	}
+	if db, ok := options["db"].(int); ok {
+		opts.DB = db
+	}
+
+	// 连接池
+	if poolSize, ok := options["pool_size"].(int); ok {
+		opts.PoolSize = poolSize
+	}
+	if minIdleConns, ok := options["min_idle_conns"].(int); ok {
+		opts.MinIdleConns = minIdleConns
+	}
+	for key, target := range map[string]*time.Duration{
+		"dial_timeout":  &opts.DialTimeout,
+		"read_timeout":  &opts.ReadTimeout,
+		"write_timeout": &opts.WriteTimeout,
+		"pool_timeout":  &opts.PoolTimeout,
+		"idle_timeout":  &opts.IdleTimeout,
+	} {
+		timeout, ok, err := durationOption(options[key])
+		if err != nil {
+			return nil, fmt.Errorf("invalid Redis option %s: %w", key, err)
+		}
+		if ok {
+			*target = timeout
+		}
+	}
+
+	// TLS
+	if opts.TLSConfig, err = tlsConfig(options, opts.TLSConfig); err != nil {
+		return nil, err
+	}
+
+	// 部署模式（未指定时：有master_name为Sentinel，多个地址为Cluster，否则为单机）
+	cfg.mode, _ = options["mode"].(string)
+	switch cfg.mode {
+	case "":
+		switch {
+		case opts.MasterName != "":
+			cfg.mode = modeSentinel
+		case len(opts.Addrs) > 1:
+			cfg.mode = modeCluster
+		default:
+			cfg.mode = modeSingle
+		}
+	case modeSingle, modeSentinel, modeCluster:
+	default:
+		return nil, fmt.Errorf("unsupported Redis mode: %s", cfg.mode)
+	}
+
+	if cfg.mode == modeSentinel && opts.MasterName == "" {
+		return nil, fmt.Errorf("redis sentinel mode requires master_name")
+	}
+	if cfg.mode == modeCluster && opts.DB != 0 {
+		return nil, fmt.Errorf("redis cluster mode does not support db %d", opts.DB)
+	}
+
+	// 哈希标签：Cluster模式下默认开启，单机/Sentinel模式保持原有键名以兼容已有数据
+	cfg.hashTags = cfg.mode == modeCluster
+	if hashTags, ok := options["hash_tags"].(bool); ok {
+		cfg.hashTags = hashTags
+	}
+	if cfg.mode == modeCluster && !cfg.hashTags {
+		return nil, fmt.Errorf("redis cluster mode requires hash_tags")
+	}
+
+	return cfg, nil
+}
+
+// Connection Redis连接（可通过Registry在多个缓存之间共享连接池）
+type Connection struct {
+	client    redis.UniversalClient
+	hashTags  bool
+	keyPrefix string // 连接的默认键名前缀（缓存选项中的key_prefix优先）
+}
+
+// Connect 根据选项创建Redis连接并测试连接
+func Connect(ctx context.Context, options map[string]any) (*Connection, error) {
+	client, cfg, err := newClient(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	keyPrefix, _ := options["key_prefix"].(string)
+	return &Connection{
+		client:    client,
+		hashTags:  cfg.hashTags,
+		keyPrefix: keyPrefix,
+	}, nil
+}
+
+// Close 关闭连接
+func (c *Connection) Close() error {
+	return c.client.Close()
+}
+
+// keySpace 获取缓存使用的键名空间（options为缓存自身的选项）
+func (c *Connection) keySpace(options map[string]any) keySpace {
+	prefix := c.keyPrefix
+	if keyPrefix, ok := options["key_prefix"].(string); ok {
+		prefix = keyPrefix
+	}
+	return keySpace{prefix: prefix, hashTags: c.hashTags}
+}
+
+// newClient 根据选项创建Redis客户端并测试连接
+func newClient(ctx context.Context, options map[string]any) (redis.UniversalClient, *clientConfig, error) {
+	cfg, err := parseClientConfig(options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var client redis.UniversalClient
+	switch cfg.mode {
+	case modeCluster:
+		client = redis.NewClusterClient(cfg.options.Cluster())
+	case modeSentinel:
+		client = redis.NewFailoverClient(cfg.options.Failover())
+	default:
+		client = redis.NewClient(cfg.options.Simple())
+	}
+
+	// 测试连接
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+
+	return client, cfg, nil
+}
+
+// tlsConfig 根据选项构建TLS配置（base为redis_url为rediss://时解析出的配置）
+func tlsConfig(options map[string]any, base *tls.Config) (*tls.Config, error) {
+	enabled, ok := options["tls"].(bool)
+	if !ok {
+		enabled = base != nil
+	}
+	if !enabled {
+		return nil, nil
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if base != nil {
+		config = base.Clone()
+	}
+
+	if serverName, ok := options["tls_server_name"].(string); ok && serverName != "" {
+		config.ServerName = serverName
+	}
+	if insecure, ok := options["tls_insecure_skip_verify"].(bool); ok {
+		config.InsecureSkipVerify = insecure
+	}
+	if caFile, ok := options["tls_ca_file"].(string); ok && caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Redis TLS CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in Redis TLS CA file: %s", caFile)
+		}
+		config.RootCAs = pool
+	}
+
+	certFile, _ := options["tls_cert_file"].(string)
+	keyFile, _ := options["tls_key_file"].(string)
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load Redis TLS client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// stringList 解析字符串列表选项（YAML列表或逗号分隔的字符串）
+func stringList(value any) ([]string, bool) {
+	switch v := value.(type) {
+	case []string:
+		return v, true
+	case []any:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			list = append(list, s)
+		}
+		return list, true
+	case string:
+		var list []string
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		return list, true
+	}
+	return nil, false
+}
+
+// durationOption 解析时长选项（time.Duration或"5s"形式的字符串）
+func durationOption(value any) (time.Duration, bool, error) {
+	switch v := value.(type) {
+	case nil:
+		return 0, false, nil
+	case time.Duration:
+		return v, true, nil
+	case string:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return 0, false, err
+		}
+		return d, true, nil
+	}
+	return 0, false, fmt.Errorf("unexpected type %T", value)
+}
+
+// scanKeys 遍历匹配的键（Cluster模式下遍历所有主节点）
+func scanKeys(ctx context.Context, client redis.UniversalClient, pattern string) ([]string, error) {
+	var (
+		keys []string
+		mu   sync.Mutex
+	)
+	scan := func(ctx context.Context, node redis.Cmdable) error {
+		iter := node.Scan(ctx, 0, pattern, 100).Iterator()
+		for iter.Next(ctx) {
+			mu.Lock()
+			keys = append(keys, iter.Val())
+			mu.Unlock()
+		}
+		return iter.Err()
+	}
+
+	if cluster, ok := client.(*redis.ClusterClient); ok {
+		err := cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			return scan(ctx, node)
+		})
+		return keys, err
+	}
+	return keys, scan(ctx, client)
+}
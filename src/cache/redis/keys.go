@@ -0,0 +1,96 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"yggdrasil-api-go/src/utils"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// keySpace Redis键名
+// prefix用于区分共用同一Redis数据库的多个部署；开启哈希标签时同一用户的Token列表和Token位于同一哈希槽
+type keySpace struct {
+	prefix   string
+	hashTags bool
+}
+
+// userTag 用户ID在键名中的形式
+func (k keySpace) userTag(userID string) string {
+	if k.hashTags {
+		return "{" + userID + "}"
+	}
+	return userID
+}
+
+// userTokens 用户Token列表键
+func (k keySpace) userTokens(userID string) string {
+	return k.prefix + "yggdrasil-id-" + k.userTag(userID)
+}
+
+// userTokensPattern 匹配所有用户Token列表键
+func (k keySpace) userTokensPattern() string {
+	return k.prefix + "yggdrasil-id-*"
+}
+
+// userIDFromTokensKey 从用户Token列表键中提取用户ID
+func (k keySpace) userIDFromTokensKey(key string) string {
+	userID := strings.TrimPrefix(key, k.prefix+"yggdrasil-id-")
+	if k.hashTags {
+		userID = strings.TrimSuffix(strings.TrimPrefix(userID, "{"), "}")
+	}
+	return userID
+}
+
+// token Token键
+func (k keySpace) token(userID, tokenID string) string {
+	return k.prefix + "yggdrasil-token-" + k.userTag(userID) + ":" + tokenID
+}
+
+// revokedTokens 已撤销的TokenID（有序集合，分数为JWT过期时间戳）
+func (k keySpace) revokedTokens() string {
+	return k.prefix + "yggdrasil-revoked"
+}
+
+// revokedTokensChannel 撤销事件频道（消息为[]revocation.Entry的JSON）
+func (k keySpace) revokedTokensChannel() string {
+	return k.prefix + "yggdrasil-revoked"
+}
+
+// session Session键
+func (k keySpace) session(serverID string) string {
+	return k.prefix + "yggdrasil-server-" + serverID
+}
+
+// loginAttempts 登录失败记录键（哈希：failures、last_failure）
+func (k keySpace) loginAttempts(key string) string {
+	return k.prefix + "yggdrasil-login-" + key
+}
+
+// owner 键名空间归属标记（值为JWT密钥指纹）
+func (k keySpace) owner() string {
+	return k.prefix + "yggdrasil-namespace"
+}
+
+// namespaceOwnerTTL 键名空间归属标记的有效期（每次启动时续期）
+const namespaceOwnerTTL = 7 * 24 * time.Hour
+
+// checkNamespace 检查键名空间是否已被其他部署使用
+// 同一部署的实例共用JWT密钥；标记中的密钥指纹不同说明另一个部署正在使用相同的数据库和键名前缀，
+// 双方的Token会互相覆盖或无法验证，此时输出警告但不阻止启动
+func checkNamespace(ctx context.Context, client redis.UniversalClient, keys keySpace) {
+	fingerprint := utils.JWTSecretFingerprint()
+
+	owner, err := client.Get(ctx, keys.owner()).Result()
+	switch {
+	case err == redis.Nil || owner == fingerprint:
+		client.Set(ctx, keys.owner(), fingerprint, namespaceOwnerTTL)
+	case err != nil:
+		fmt.Printf("⚠️  Failed to check Redis key namespace: %v\n", err)
+	default:
+		fmt.Printf("⚠️  Redis key namespace %q appears to be in use by another deployment (different JWT secret); set key_prefix or db to separate deployments\n", keys.prefix)
+	}
+}
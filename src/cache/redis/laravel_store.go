@@ -0,0 +1,93 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// LaravelStore Laravel RedisStore格式的缓存存储（供BlessingSkin插件互通模式使用）
+// 键为Laravel缓存前缀（key_prefix，需与Laravel的Redis前缀和缓存前缀之和一致）加缓存键，值为Laravel序列化后的内容
+type LaravelStore struct {
+	client redis.UniversalClient
+	ctx    context.Context
+	prefix string
+	owned  bool // 连接由存储自身创建（共享连接由Registry负责关闭）
+}
+
+// NewLaravelStore 创建Laravel格式的Redis存储
+func NewLaravelStore(options map[string]any) (*LaravelStore, error) {
+	conn, err := Connect(context.Background(), options)
+	if err != nil {
+		return nil, err
+	}
+	return newLaravelStore(conn, options, true), nil
+}
+
+// NewLaravelStoreWithConnection 使用共享连接创建Laravel格式的Redis存储
+func NewLaravelStoreWithConnection(conn *Connection, options map[string]any) (*LaravelStore, error) {
+	return newLaravelStore(conn, options, false), nil
+}
+
+// newLaravelStore 创建Laravel格式的Redis存储
+func newLaravelStore(conn *Connection, options map[string]any, owned bool) *LaravelStore {
+	return &LaravelStore{
+		client: conn.client,
+		ctx:    context.Background(),
+		prefix: conn.keySpace(options).prefix,
+		owned:  owned,
+	}
+}
+
+// Get 获取缓存值及其过期时间
+func (s *LaravelStore) Get(key string) (string, time.Time, bool, error) {
+	var get *redis.StringCmd
+	var ttl *redis.DurationCmd
+	_, err := s.client.Pipelined(s.ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(s.ctx, s.prefix+key)
+		ttl = pipe.PTTL(s.ctx, s.prefix+key)
+		return nil
+	})
+	if err == redis.Nil {
+		return "", time.Time{}, false, nil
+	}
+	if err != nil {
+		return "", time.Time{}, false, err
+	}
+
+	// 未设置过期时间的键（Laravel forever）视为不会过期
+	expiresAt := time.Now().Add(100 * 365 * 24 * time.Hour)
+	if ttl.Val() > 0 {
+		expiresAt = time.Now().Add(ttl.Val())
+	}
+	return get.Val(), expiresAt, true, nil
+}
+
+// Put 写入缓存值（与Laravel一致，TTL至少1秒）
+func (s *LaravelStore) Put(key, value string, ttl time.Duration) error {
+	return s.client.Set(s.ctx, s.prefix+key, value, max(ttl, time.Second)).Err()
+}
+
+// Forget 删除缓存值
+func (s *LaravelStore) Forget(key string) error {
+	return s.client.Del(s.ctx, s.prefix+key).Err()
+}
+
+// CleanupExpired 清理过期缓存（Redis会自动清理过期的键）
+func (s *LaravelStore) CleanupExpired() error {
+	return nil
+}
+
+// Close 关闭连接（共享连接不会被关闭）
+func (s *LaravelStore) Close() error {
+	if !s.owned {
+		return nil
+	}
+	return s.client.Close()
+}
+
+// Type 存储类型
+func (s *LaravelStore) Type() string {
+	return "redis"
+}
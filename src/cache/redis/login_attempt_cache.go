@@ -0,0 +1,116 @@
+// Package redis Redis登录失败记录缓存实现
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"yggdrasil-api-go/src/yggdrasil"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// LoginAttemptCache Redis登录失败记录缓存（多个实例共用）
+type LoginAttemptCache struct {
+	client redis.UniversalClient
+	ctx    context.Context
+	keys   keySpace
+	owned  bool // 连接由缓存自身创建（共享连接由Registry负责关闭）
+}
+
+// NewLoginAttemptCache 创建Redis登录失败记录缓存
+func NewLoginAttemptCache(options map[string]any) (*LoginAttemptCache, error) {
+	conn, err := Connect(context.Background(), options)
+	if err != nil {
+		return nil, err
+	}
+	return newLoginAttemptCache(conn, options, true), nil
+}
+
+// NewLoginAttemptCacheWithConnection 使用共享连接创建Redis登录失败记录缓存
+func NewLoginAttemptCacheWithConnection(conn *Connection, options map[string]any) (*LoginAttemptCache, error) {
+	return newLoginAttemptCache(conn, options, false), nil
+}
+
+// newLoginAttemptCache 创建Redis登录失败记录缓存
+func newLoginAttemptCache(conn *Connection, options map[string]any, owned bool) *LoginAttemptCache {
+	return &LoginAttemptCache{
+		client: conn.client,
+		ctx:    context.Background(),
+		keys:   conn.keySpace(options),
+		owned:  owned,
+	}
+}
+
+// RecordFailure 增加失败次数（HINCRBY是原子操作，过期的键已被Redis删除，计数从1开始）
+func (c *LoginAttemptCache) RecordFailure(key string, ttl time.Duration) (*yggdrasil.LoginAttempts, error) {
+	attemptsKey := c.keys.loginAttempts(key)
+	now := time.Now()
+
+	var failures *redis.IntCmd
+	_, err := c.client.TxPipelined(c.ctx, func(pipe redis.Pipeliner) error {
+		failures = pipe.HIncrBy(c.ctx, attemptsKey, "failures", 1)
+		pipe.HSet(c.ctx, attemptsKey, "last_failure", now.UnixMilli())
+		pipe.PExpire(c.ctx, attemptsKey, ttl)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record login failure: %w", err)
+	}
+
+	return &yggdrasil.LoginAttempts{
+		Failures:    int(failures.Val()),
+		LastFailure: time.UnixMilli(now.UnixMilli()),
+	}, nil
+}
+
+// Get 获取失败记录
+func (c *LoginAttemptCache) Get(key string) (*yggdrasil.LoginAttempts, error) {
+	values, err := c.client.HGetAll(c.ctx, c.keys.loginAttempts(key)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get login attempts: %w", err)
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	failures, err := strconv.Atoi(values["failures"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid login attempts record: %w", err)
+	}
+	lastFailure, err := strconv.ParseInt(values["last_failure"], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid login attempts record: %w", err)
+	}
+
+	return &yggdrasil.LoginAttempts{
+		Failures:    failures,
+		LastFailure: time.UnixMilli(lastFailure),
+	}, nil
+}
+
+// Reset 清除失败记录
+func (c *LoginAttemptCache) Reset(key string) error {
+	return c.client.Del(c.ctx, c.keys.loginAttempts(key)).Err()
+}
+
+// CleanupExpired 清理过期记录
+func (c *LoginAttemptCache) CleanupExpired() error {
+	// Redis会自动清理过期的键，这里不需要额外操作
+	return nil
+}
+
+// Close 关闭缓存连接（共享连接不会被关闭）
+func (c *LoginAttemptCache) Close() error {
+	if !c.owned {
+		return nil
+	}
+	return c.client.Close()
+}
+
+// GetCacheType 获取缓存类型
+func (c *LoginAttemptCache) GetCacheType() string {
+	return "redis"
+}
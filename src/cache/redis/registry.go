@@ -0,0 +1,68 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Registry 命名Redis连接注册表
+// Token缓存和Session缓存通过选项connection引用同一个命名连接时共用连接池，连接在首次使用时建立
+type Registry struct {
+	options     map[string]map[string]any
+	connections map[string]*Connection
+	mu          sync.Mutex
+}
+
+// NewRegistry 创建连接注册表
+func NewRegistry() *Registry {
+	return &Registry{
+		options:     make(map[string]map[string]any),
+		connections: make(map[string]*Connection),
+	}
+}
+
+// Register 注册命名连接（选项与Redis缓存的连接选项相同）
+func (r *Registry) Register(name string, options map[string]any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.options[name] = options
+}
+
+// Get 获取命名连接（首次获取时建立连接）
+func (r *Registry) Get(name string) (*Connection, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if conn, exists := r.connections[name]; exists {
+		return conn, nil
+	}
+
+	options, exists := r.options[name]
+	if !exists {
+		return nil, fmt.Errorf("redis connection not registered: %s", name)
+	}
+
+	conn, err := Connect(context.Background(), options)
+	if err != nil {
+		return nil, fmt.Errorf("redis connection %s: %w", name, err)
+	}
+	r.connections[name] = conn
+	return conn, nil
+}
+
+// Close 关闭所有已建立的连接（应在使用这些连接的缓存关闭之后调用）
+func (r *Registry) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var errs []error
+	for name, conn := range r.connections {
+		if err := conn.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("redis connection %s: %w", name, err))
+		}
+		delete(r.connections, name)
+	}
+	return errors.Join(errs...)
+}
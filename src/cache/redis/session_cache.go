@@ -14,34 +14,36 @@ import (
 
 // SessionCache Redis Session缓存
 type SessionCache struct {
-	client *redis.Client
+	client redis.UniversalClient
 	ctx    context.Context
+	keys   keySpace
+	owned  bool // 连接由缓存自身创建（共享连接由Registry负责关闭）
 }
 
-// NewSessionCache 创建Redis Session缓存
+// NewSessionCache 创建Redis Session缓存（支持单机、Sentinel和Cluster）
 func NewSessionCache(options map[string]any) (*SessionCache, error) {
-	redisURL := "redis://localhost:6379"
-	if url, ok := options["redis_url"].(string); ok && url != "" {
-		redisURL = url
-	}
-
-	opt, err := redis.ParseURL(redisURL)
+	conn, err := Connect(context.Background(), options)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
+		return nil, err
 	}
+	return newSessionCache(conn, options, true), nil
+}
 
-	client := redis.NewClient(opt)
-	ctx := context.Background()
+// NewSessionCacheWithConnection 使用共享连接创建Redis Session缓存
+func NewSessionCacheWithConnection(conn *Connection, options map[string]any) (*SessionCache, error) {
+	return newSessionCache(conn, options, false), nil
+}
 
-	// 测试连接
-	if err := client.Ping(ctx).Err(); err != nil {
-		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+// newSessionCache 创建Redis Session缓存并检查键名空间
+func newSessionCache(conn *Connection, options map[string]any, owned bool) *SessionCache {
+	cache := &SessionCache{
+		client: conn.client,
+		ctx:    context.Background(),
+		keys:   conn.keySpace(options),
+		owned:  owned,
 	}
-
-	return &SessionCache{
-		client: client,
-		ctx:    ctx,
-	}, nil
+	checkNamespace(cache.ctx, cache.client, cache.keys)
+	return cache
 }
 
 // Store 存储Session（优化版：验证JWT但只存储必要信息）
@@ -50,8 +52,10 @@ func (c *SessionCache) Store(serverID string, session *yggdrasil.Session) error
 	cacheSession := &yggdrasil.Session{
 		ServerID:    serverID,
 		AccessToken: session.AccessToken,
+		TokenID:     session.TokenID,
 		ProfileID:   session.ProfileID,
 		ClientIP:    session.ClientIP,
+		UserAgent:   session.UserAgent,
 		CreatedAt:   session.CreatedAt,
 	}
 
@@ -61,11 +65,14 @@ func (c *SessionCache) Store(serverID string, session *yggdrasil.Session) error
 		return fmt.Errorf("failed to marshal session: %w", err)
 	}
 
-	// Session固定过期时间为30秒（与Yggdrasil标准一致）
-	ttl := 30 * time.Second
+	// Session自创建起SessionTTL后过期
+	ttl := time.Until(session.ExpiresAt())
+	if ttl <= 0 {
+		return fmt.Errorf("session already expired")
+	}
 
 	// 存储Session
-	sessionKey := fmt.Sprintf("yggdrasil-server-%s", serverID)
+	sessionKey := c.keys.session(serverID)
 	if err := c.client.Set(c.ctx, sessionKey, sessionData, ttl).Err(); err != nil {
 		return fmt.Errorf("failed to store session: %w", err)
 	}
@@ -75,12 +82,12 @@ func (c *SessionCache) Store(serverID string, session *yggdrasil.Session) error
 
 // Get 获取Session
 func (c *SessionCache) Get(serverID string) (*yggdrasil.Session, error) {
-	sessionKey := fmt.Sprintf("yggdrasil-server-%s", serverID)
+	sessionKey := c.keys.session(serverID)
 
 	data, err := c.client.Get(c.ctx, sessionKey).Result()
 	if err != nil {
 		if err == redis.Nil {
-			return nil, fmt.Errorf("session not found")
+			return nil, yggdrasil.ErrSessionNotFound
 		}
 		return nil, fmt.Errorf("failed to get session: %w", err)
 	}
@@ -95,7 +102,7 @@ func (c *SessionCache) Get(serverID string) (*yggdrasil.Session, error) {
 
 // Delete 删除Session
 func (c *SessionCache) Delete(serverID string) error {
-	sessionKey := fmt.Sprintf("yggdrasil-server-%s", serverID)
+	sessionKey := c.keys.session(serverID)
 	return c.client.Del(c.ctx, sessionKey).Err()
 }
 
@@ -105,8 +112,11 @@ func (c *SessionCache) CleanupExpired() error {
 	return nil
 }
 
-// Close 关闭缓存连接
+// Close 关闭缓存连接（共享连接不会被关闭）
 func (c *SessionCache) Close() error {
+	if !c.owned {
+		return nil
+	}
 	return c.client.Close()
 }
 
@@ -4,8 +4,13 @@ package redis
 import (
 	"context"
 	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"sync"
 	"time"
 
+	"yggdrasil-api-go/src/cache/revocation"
 	"yggdrasil-api-go/src/utils"
 	"yggdrasil-api-go/src/yggdrasil"
 
@@ -15,82 +20,202 @@ import (
 
 // TokenCache Redis Token缓存
 type TokenCache struct {
-	client *redis.Client
+	client redis.UniversalClient
 	ctx    context.Context
+	keys   keySpace
+	owned  bool // 连接由缓存自身创建（共享连接由Registry负责关闭）
+
+	revocations revocation.Notifier
+	listenOnce  sync.Once
+	pubsub      *redis.PubSub // 撤销事件订阅（首次SubscribeRevocations时创建）
 }
 
-// NewTokenCache 创建Redis Token缓存
+// NewTokenCache 创建Redis Token缓存（支持单机、Sentinel和Cluster）
 func NewTokenCache(options map[string]any) (*TokenCache, error) {
-	redisURL := "redis://localhost:6379"
-	if url, ok := options["redis_url"].(string); ok && url != "" {
-		redisURL = url
-	}
-
-	opt, err := redis.ParseURL(redisURL)
+	conn, err := Connect(context.Background(), options)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
+		return nil, err
 	}
+	return newTokenCache(conn, options, true), nil
+}
 
-	client := redis.NewClient(opt)
-	ctx := context.Background()
+// NewTokenCacheWithConnection 使用共享连接创建Redis Token缓存
+func NewTokenCacheWithConnection(conn *Connection, options map[string]any) (*TokenCache, error) {
+	return newTokenCache(conn, options, false), nil
+}
 
-	// 测试连接
-	if err := client.Ping(ctx).Err(); err != nil {
-		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+// newTokenCache 创建Redis Token缓存并检查键名空间
+func newTokenCache(conn *Connection, options map[string]any, owned bool) *TokenCache {
+	cache := &TokenCache{
+		client: conn.client,
+		ctx:    context.Background(),
+		keys:   conn.keySpace(options),
+		owned:  owned,
 	}
-
-	return &TokenCache{
-		client: client,
-		ctx:    ctx,
-	}, nil
+	checkNamespace(cache.ctx, cache.client, cache.keys)
+	return cache
 }
 
 // Store 存储Token（优化版：先验证JWT，提取信息）
 func (c *TokenCache) Store(token *yggdrasil.Token) error {
+	_, err := c.StoreWithLimit(token, 0)
+	return err
+}
+
+// StoreWithLimit 存储Token并撤销该用户最旧的Token，使有效Token数不超过limit
+// 使用WATCH/MULTI乐观事务，多个实例共享同一Redis时并发登录也不会超出限制
+func (c *TokenCache) StoreWithLimit(token *yggdrasil.Token, limit int) (int, error) {
 	// 第一步：验证JWT并提取信息
 	claims, err := utils.ValidateJWT(token.AccessToken)
 	if err != nil {
-		return fmt.Errorf("invalid JWT token: %w", err)
+		return 0, fmt.Errorf("invalid JWT token: %w", err)
 	}
 
 	// 创建简化的Token对象（只存储JWT中没有的信息）
 	cacheToken := &yggdrasil.Token{
-		AccessToken: token.AccessToken, // 保留完整的AccessToken用于兼容性
-		ClientToken: token.ClientToken,
-		ProfileID:   claims.ProfileID, // 从JWT中获取ProfileID
-		Owner:       claims.UserID, // 从JWT中获取用户ID
-		CreatedAt:   token.CreatedAt,
-		ExpiresAt:   token.ExpiresAt,
+		AccessToken:      token.AccessToken, // 保留完整的AccessToken用于兼容性
+		ClientToken:      token.ClientToken,
+		ProfileID:        claims.ProfileID, // 从JWT中获取ProfileID
+		Owner:            claims.UserID,    // 从JWT中获取用户ID
+		CreatedAt:        token.CreatedAt,
+		ExpiresAt:        token.ExpiresAt,
+		RefreshExpiresAt: token.RefreshExpiresAt,
 	}
 
 	// 序列化Token
 	tokenData, err := sonic.Marshal(cacheToken)
 	if err != nil {
-		return fmt.Errorf("failed to marshal token: %w", err)
+		return 0, fmt.Errorf("failed to marshal token: %w", err)
 	}
 
-	// 计算TTL
-	ttl := time.Until(token.ExpiresAt)
+	// 计算TTL（暂时失效的Token仍需保留至刷新期限）
+	ttl := time.Until(token.RefreshDeadline())
 	if ttl <= 0 {
-		return fmt.Errorf("token already expired")
+		return 0, fmt.Errorf("token already expired")
 	}
 
-	// 存储Token（使用用户ID:TokenID作为键）
-	tokenKey := fmt.Sprintf("yggdrasil-token-%s:%s", claims.UserID, claims.TokenID)
-	if err := c.client.Set(c.ctx, tokenKey, tokenData, ttl).Err(); err != nil {
-		return fmt.Errorf("failed to store token: %w", err)
+	tokenKey := c.keys.token(claims.UserID, claims.TokenID)
+	userTokensKey := c.keys.userTokens(claims.UserID)
+
+	// 写入Token及用户Token列表，并移除已失效的和被撤销的TokenID
+	write := func(pipe redis.Pipeliner, stale []string, revoked []revocation.Entry) {
+		// 存储Token（使用用户ID:TokenID作为键）
+		pipe.Set(c.ctx, tokenKey, tokenData, ttl)
+
+		// 更新用户Token列表（使用用户ID），列表至少保留到该Token的刷新期限（不缩短已有的过期时间）
+		pipe.SAdd(c.ctx, userTokensKey, claims.TokenID)
+		pipe.Eval(c.ctx, extendExpiryScript, []string{userTokensKey}, ttl.Milliseconds())
+
+		for _, tokenID := range stale {
+			pipe.SRem(c.ctx, userTokensKey, tokenID)
+		}
+		for _, entry := range revoked {
+			pipe.SRem(c.ctx, userTokensKey, entry.TokenID)
+			pipe.Del(c.ctx, c.keys.token(claims.UserID, entry.TokenID))
+		}
 	}
 
-	// 更新用户Token列表（使用用户ID）
-	userTokensKey := fmt.Sprintf("yggdrasil-id-%s", claims.UserID)
-	if err := c.client.SAdd(c.ctx, userTokensKey, claims.TokenID).Err(); err != nil {
-		return fmt.Errorf("failed to add token to user list: %w", err)
+	// 不限制数量时无需读取用户Token列表
+	if limit <= 0 {
+		_, err := c.client.TxPipelined(c.ctx, func(pipe redis.Pipeliner) error {
+			write(pipe, nil, nil)
+			return nil
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to store token: %w", err)
+		}
+		return 0, nil
 	}
 
-	// 设置用户Token列表的过期时间（7天）
-	c.client.Expire(c.ctx, userTokensKey, 7*24*time.Hour)
+	var evicted []revocation.Entry
+	txf := func(tx *redis.Tx) error {
+		// 选出需要移除的TokenID（已失效的和超出限制的最旧Token）
+		stale, victims, err := c.selectEvictions(tx, claims.UserID, claims.TokenID, limit)
+		if err != nil {
+			return err
+		}
 
-	return nil
+		_, err = tx.TxPipelined(c.ctx, func(pipe redis.Pipeliner) error {
+			write(pipe, stale, victims)
+			return nil
+		})
+		evicted = victims
+		return err
+	}
+
+	// 用户Token列表被其他客户端修改时随机退避后重试
+	for attempt := range maxStoreRetries {
+		err = c.client.Watch(c.ctx, txf, userTokensKey)
+		if err != redis.TxFailedErr {
+			break
+		}
+		time.Sleep(time.Duration(rand.IntN(attempt+1)+1) * time.Millisecond)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to store token: %w", err)
+	}
+
+	c.publishRevocations(evicted)
+	c.revocations.Notify(evicted)
+	return len(evicted), nil
+}
+
+// extendExpiryScript 过期时间短于ARGV[1]毫秒（或未设置）时延长到ARGV[1]毫秒，不缩短已有的过期时间
+const extendExpiryScript = `
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl < tonumber(ARGV[1]) then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return ttl
+`
+
+// maxStoreRetries 乐观事务冲突时的最大重试次数
+const maxStoreRetries = 50
+
+// selectEvictions 读取用户的Token列表，返回已失效的TokenID和需要撤销的最旧Token（不包括keepID）
+func (c *TokenCache) selectEvictions(tx *redis.Tx, userID, keepID string, limit int) (stale []string, victims []revocation.Entry, err error) {
+	tokenIDs, err := tx.SMembers(c.ctx, c.keys.userTokens(userID)).Result()
+	if err != nil && err != redis.Nil {
+		return nil, nil, fmt.Errorf("failed to get user tokens: %w", err)
+	}
+	tokenIDs = slices.DeleteFunc(tokenIDs, func(id string) bool {
+		return id == keepID
+	})
+	if len(tokenIDs) == 0 {
+		return nil, nil, nil
+	}
+
+	keys := make([]string, len(tokenIDs))
+	for i, tokenID := range tokenIDs {
+		keys[i] = c.keys.token(userID, tokenID)
+	}
+	values, err := tx.MGet(c.ctx, keys...).Result()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get user tokens: %w", err)
+	}
+
+	tokens := make(map[string]*yggdrasil.Token, len(tokenIDs))
+	var live []string
+	for i, value := range values {
+		data, ok := value.(string)
+		var token yggdrasil.Token
+		if !ok || sonic.Unmarshal([]byte(data), &token) != nil {
+			stale = append(stale, tokenIDs[i])
+			continue
+		}
+		tokens[tokenIDs[i]] = &token
+		live = append(live, tokenIDs[i])
+	}
+
+	// 按创建时间升序排列，撤销最旧的Token
+	excess := max(len(live)-(limit-1), 0)
+	slices.SortStableFunc(live, func(a, b string) int {
+		return tokens[a].CreatedAt.Compare(tokens[b].CreatedAt)
+	})
+	for _, tokenID := range live[:excess] {
+		victims = append(victims, revocation.Entry{TokenID: tokenID, ExpiresAt: tokens[tokenID].RefreshDeadline()})
+	}
+	return stale, victims, nil
 }
 
 // Get 获取Token（优化版：先验证JWT，按需查询缓存）
@@ -98,16 +223,16 @@ func (c *TokenCache) Get(accessToken string) (*yggdrasil.Token, error) {
 	// 第一步：验证JWT（本地计算，极快）
 	claims, err := utils.ValidateJWT(accessToken)
 	if err != nil {
-		return nil, fmt.Errorf("invalid JWT token: %w", err)
+		return nil, fmt.Errorf("%w: invalid JWT token: %w", yggdrasil.ErrTokenNotFound, err)
 	}
 
 	// 第二步：从缓存获取ClientToken等额外信息
-	tokenKey := fmt.Sprintf("yggdrasil-token-%s:%s", claims.UserID, claims.TokenID)
+	tokenKey := c.keys.token(claims.UserID, claims.TokenID)
 
 	data, err := c.client.Get(c.ctx, tokenKey).Result()
 	if err != nil {
 		if err == redis.Nil {
-			return nil, fmt.Errorf("token not found in cache")
+			return nil, yggdrasil.ErrTokenNotFound
 		}
 		return nil, fmt.Errorf("failed to get token: %w", err)
 	}
@@ -119,12 +244,13 @@ func (c *TokenCache) Get(accessToken string) (*yggdrasil.Token, error) {
 
 	// 构建Token对象（结合JWT信息和缓存信息）
 	result := &yggdrasil.Token{
-		AccessToken: accessToken,
-		ClientToken: token.ClientToken,
-		ProfileID:   claims.ProfileID,
-		Owner:       claims.UserID,
-		CreatedAt:   token.CreatedAt,
-		ExpiresAt:   token.ExpiresAt,
+		AccessToken:      accessToken,
+		ClientToken:      token.ClientToken,
+		ProfileID:        claims.ProfileID,
+		Owner:            claims.UserID,
+		CreatedAt:        token.CreatedAt,
+		ExpiresAt:        token.ExpiresAt,
+		RefreshExpiresAt: token.RefreshExpiresAt,
 	}
 
 	return result, nil
@@ -139,18 +265,28 @@ func (c *TokenCache) Delete(accessToken string) error {
 		return nil
 	}
 
-	// 从用户Token列表中移除（使用用户ID）
-	userTokensKey := fmt.Sprintf("yggdrasil-id-%s", claims.UserID)
-	c.client.SRem(c.ctx, userTokensKey, claims.TokenID)
+	userTokensKey := c.keys.userTokens(claims.UserID)
+	tokenKey := c.keys.token(claims.UserID, claims.TokenID)
+	revoked := []revocation.Entry{revocation.EntryFromClaims(claims)}
 
-	// 删除Token
-	tokenKey := fmt.Sprintf("yggdrasil-token-%s:%s", claims.UserID, claims.TokenID)
-	return c.client.Del(c.ctx, tokenKey).Err()
+	_, err = c.client.TxPipelined(c.ctx, func(pipe redis.Pipeliner) error {
+		// 从用户Token列表中移除（使用用户ID）并删除Token
+		pipe.SRem(c.ctx, userTokensKey, claims.TokenID)
+		pipe.Del(c.ctx, tokenKey)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete token: %w", err)
+	}
+
+	c.publishRevocations(revoked)
+	c.revocations.Notify(revoked)
+	return nil
 }
 
 // GetUserTokens 获取用户的所有Token（按用户ID查询）
 func (c *TokenCache) GetUserTokens(userID string) ([]*yggdrasil.Token, error) {
-	userTokensKey := fmt.Sprintf("yggdrasil-id-%s", userID)
+	userTokensKey := c.keys.userTokens(userID)
 
 	tokenIDs, err := c.client.SMembers(c.ctx, userTokensKey).Result()
 	if err != nil {
@@ -160,10 +296,10 @@ func (c *TokenCache) GetUserTokens(userID string) ([]*yggdrasil.Token, error) {
 		return nil, fmt.Errorf("failed to get user tokens: %w", err)
 	}
 
-	var tokens []*yggdrasil.Token
+	tokens := []*yggdrasil.Token{}
 	for _, tokenID := range tokenIDs {
 		// 直接从Redis获取Token数据
-		tokenKey := fmt.Sprintf("yggdrasil-token-%s:%s", userID, tokenID)
+		tokenKey := c.keys.token(userID, tokenID)
 		data, err := c.client.Get(c.ctx, tokenKey).Result()
 		if err != nil {
 			// 清理无效的Token引用
@@ -186,7 +322,7 @@ func (c *TokenCache) GetUserTokens(userID string) ([]*yggdrasil.Token, error) {
 
 // DeleteUserTokens 删除用户的所有Token（按用户ID）
 func (c *TokenCache) DeleteUserTokens(userID string) error {
-	userTokensKey := fmt.Sprintf("yggdrasil-id-%s", userID)
+	userTokensKey := c.keys.userTokens(userID)
 
 	// 获取用户的所有TokenID
 	tokenIDs, err := c.client.SMembers(c.ctx, userTokensKey).Result()
@@ -197,44 +333,60 @@ func (c *TokenCache) DeleteUserTokens(userID string) error {
 		return fmt.Errorf("failed to get user tokens: %w", err)
 	}
 
-	// 删除所有Token
-	for _, tokenID := range tokenIDs {
-		tokenKey := fmt.Sprintf("yggdrasil-token-%s:%s", userID, tokenID)
-		c.client.Del(c.ctx, tokenKey)
+	if len(tokenIDs) == 0 {
+		return nil
+	}
+
+	// 读取Token数据以获得过期时间（已不存在的Token无需撤销）
+	keys := make([]string, len(tokenIDs))
+	for i, tokenID := range tokenIDs {
+		keys[i] = c.keys.token(userID, tokenID)
+	}
+	values, err := c.client.MGet(c.ctx, keys...).Result()
+	if err != nil {
+		return fmt.Errorf("failed to get user tokens: %w", err)
+	}
+
+	var revoked []revocation.Entry
+	for i, value := range values {
+		var token yggdrasil.Token
+		if data, ok := value.(string); ok && sonic.Unmarshal([]byte(data), &token) == nil {
+			revoked = append(revoked, revocation.Entry{TokenID: tokenIDs[i], ExpiresAt: token.RefreshDeadline()})
+		}
+	}
+
+	// 删除所有Token和用户Token列表（开启哈希标签时位于同一哈希槽）
+	if err := c.client.Del(c.ctx, append(keys, userTokensKey)...).Err(); err != nil {
+		return fmt.Errorf("failed to delete user tokens: %w", err)
 	}
 
-	// 删除用户Token列表
-	return c.client.Del(c.ctx, userTokensKey).Err()
+	c.publishRevocations(revoked)
+	c.revocations.Notify(revoked)
+	return nil
 }
 
-// GetUserTokenCount 获取用户Token数量
+// GetUserTokenCount 获取用户Token数量（仅统计仍然有效的Token）
 func (c *TokenCache) GetUserTokenCount(userID string) (int, error) {
-	userTokensKey := fmt.Sprintf("yggdrasil-id-%s", userID)
-
-	count, err := c.client.SCard(c.ctx, userTokensKey).Result()
+	tokens, err := c.GetUserTokens(userID)
 	if err != nil {
-		if err == redis.Nil {
-			return 0, nil
-		}
-		return 0, fmt.Errorf("failed to get user token count: %w", err)
+		return 0, err
 	}
-
-	return int(count), nil
+	return len(tokens), nil
 }
 
 // CleanupExpired 清理过期Token
 func (c *TokenCache) CleanupExpired() error {
 	// Redis会自动清理过期的键，这里主要清理用户Token列表中的无效引用
 
-	// 获取所有用户Token列表键
-	keys, err := c.client.Keys(c.ctx, "yggdrasil-id-*").Result()
+	// 获取所有用户Token列表键（Cluster模式下遍历所有主节点）
+	keys, err := scanKeys(c.ctx, c.client, c.keys.userTokensPattern())
 	if err != nil {
 		return fmt.Errorf("failed to get user token keys: %w", err)
 	}
 
 	for _, userTokensKey := range keys {
 		// 提取用户ID
-		userID := userTokensKey[len("yggdrasil-id-"):]
+		userID := c.keys.userIDFromTokensKey(userTokensKey)
 
 		// 获取用户TokenID列表
 		tokenIDs, err := c.client.SMembers(c.ctx, userTokensKey).Result()
@@ -244,7 +396,7 @@ func (c *TokenCache) CleanupExpired() error {
 
 		// 检查每个Token是否仍然存在
 		for _, tokenID := range tokenIDs {
-			tokenKey := fmt.Sprintf("yggdrasil-token-%s:%s", userID, tokenID)
+			tokenKey := c.keys.token(userID, tokenID)
 			exists, err := c.client.Exists(c.ctx, tokenKey).Result()
 			if err != nil || exists == 0 {
 				// Token不存在，从用户列表中移除
@@ -259,11 +411,85 @@ func (c *TokenCache) CleanupExpired() error {
 		}
 	}
 
+	// 清理JWT已过期的撤销记录
+	maxScore := strconv.FormatInt(time.Now().Unix(), 10)
+	if err := c.client.ZRemRangeByScore(c.ctx, c.keys.revokedTokens(), "-inf", maxScore).Err(); err != nil {
+		return fmt.Errorf("failed to cleanup revoked tokens: %w", err)
+	}
+
 	return nil
 }
 
-// Close 关闭缓存连接
+// SubscribeRevocations 订阅Token撤销事件（首次订阅时加载现有撤销记录并监听其他实例的撤销事件）
+func (c *TokenCache) SubscribeRevocations(handler func([]revocation.Entry)) {
+	c.revocations.Subscribe(handler)
+	c.listenOnce.Do(c.listenRevocations)
+}
+
+// publishRevocations 记录撤销的Token并通知其他实例
+// 撤销记录与用户键不在同一哈希槽，因此在删除Token的事务之外写入
+func (c *TokenCache) publishRevocations(entries []revocation.Entry) {
+	if len(entries) == 0 {
+		return
+	}
+
+	members := make([]*redis.Z, len(entries))
+	for i, entry := range entries {
+		members[i] = &redis.Z{Score: float64(entry.ExpiresAt.Unix()), Member: entry.TokenID}
+	}
+
+	_, err := c.client.Pipelined(c.ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(c.ctx, c.keys.revokedTokens(), members...)
+		if message, err := sonic.Marshal(entries); err == nil {
+			pipe.Publish(c.ctx, c.keys.revokedTokensChannel(), message)
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("⚠️  Failed to publish token revocations: %v\n", err)
+	}
+}
+
+// listenRevocations 订阅撤销事件频道，并加载仍未过期的撤销记录
+func (c *TokenCache) listenRevocations() {
+	// 先订阅再加载，避免遗漏两者之间产生的撤销事件
+	c.pubsub = c.client.Subscribe(c.ctx, c.keys.revokedTokensChannel())
+	if _, err := c.pubsub.Receive(c.ctx); err != nil {
+		fmt.Printf("⚠️  Failed to subscribe to token revocations: %v\n", err)
+	}
+
+	minScore := strconv.FormatInt(time.Now().Unix(), 10)
+	members, err := c.client.ZRangeByScoreWithScores(c.ctx, c.keys.revokedTokens(), &redis.ZRangeBy{Min: minScore, Max: "+inf"}).Result()
+	if err != nil {
+		fmt.Printf("⚠️  Failed to load revoked tokens: %v\n", err)
+	}
+	entries := make([]revocation.Entry, 0, len(members))
+	for _, member := range members {
+		if tokenID, ok := member.Member.(string); ok {
+			entries = append(entries, revocation.Entry{TokenID: tokenID, ExpiresAt: time.Unix(int64(member.Score), 0)})
+		}
+	}
+	c.revocations.Notify(entries)
+
+	go func() {
+		for message := range c.pubsub.Channel() {
+			var entries []revocation.Entry
+			if err := sonic.UnmarshalString(message.Payload, &entries); err != nil {
+				continue
+			}
+			c.revocations.Notify(entries)
+		}
+	}()
+}
+
+// Close 关闭缓存连接（共享连接不会被关闭）
 func (c *TokenCache) Close() error {
+	if c.pubsub != nil {
+		c.pubsub.Close()
+	}
+	if !c.owned {
+		return nil
+	}
 	return c.client.Close()
 }
 
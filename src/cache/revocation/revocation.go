@@ -0,0 +1,158 @@
+// Package revocation 令牌撤销通知与进程内撤销集合
+// Token缓存在删除、批量删除和数量限制淘汰令牌时产生撤销事件（共享后端会同步给其他实例），
+// 进程内的撤销集合据此让被撤销的令牌立即无法进入服务器，而无需每次查询缓存后端
+package revocation
+
+import (
+	"sync"
+	"time"
+
+	"yggdrasil-api-go/src/utils"
+)
+
+// Entry 已撤销的令牌
+type Entry struct {
+	TokenID   string    `json:"id"`  // 令牌ID（JWT.yggt）
+	ExpiresAt time.Time `json:"exp"` // JWT过期时间（之后JWT校验本身即会失败，无需继续记录）
+}
+
+// EntryFromClaims 根据JWT声明生成撤销记录
+func EntryFromClaims(claims *utils.JWTClaims) Entry {
+	entry := Entry{TokenID: claims.TokenID}
+	if claims.ExpiresAt != nil {
+		entry.ExpiresAt = claims.ExpiresAt.Time
+	}
+	return entry
+}
+
+// Notifier 撤销事件分发器（供Token缓存实现使用）
+// 已分发且JWT尚未过期的记录会保留下来，之后订阅的处理器会先收到这些记录，
+// 因此多个订阅者（如分级缓存的L1和Session处理器）先后订阅时都能收到启动时加载的撤销记录
+type Notifier struct {
+	handlers  []func([]Entry)
+	history   map[string]time.Time // TokenID -> JWT过期时间
+	pruneSize int                  // history达到该大小时清理已过期的记录
+	mu        sync.Mutex
+}
+
+// Subscribe 订阅撤销事件（先同步收到已分发且仍未过期的撤销记录）
+func (n *Notifier) Subscribe(handler func([]Entry)) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.handlers = append(n.handlers, handler)
+
+	now := time.Now()
+	var entries []Entry
+	for tokenID, expiresAt := range n.history {
+		if now.Before(expiresAt) {
+			entries = append(entries, Entry{TokenID: tokenID, ExpiresAt: expiresAt})
+		}
+	}
+	if len(entries) > 0 {
+		handler(entries)
+	}
+}
+
+// Notify 分发撤销事件（回调同步执行，不应阻塞或回调缓存）
+func (n *Notifier) Notify(entries []Entry) {
+	if len(entries) == 0 {
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.remember(entries)
+	for _, handler := range n.handlers {
+		handler(entries)
+	}
+}
+
+// remember 保留撤销记录供之后的订阅者使用（调用方需持有锁）
+func (n *Notifier) remember(entries []Entry) {
+	if n.history == nil {
+		n.history = make(map[string]time.Time)
+	}
+
+	now := time.Now()
+	for _, entry := range entries {
+		if entry.ExpiresAt.After(now) {
+			n.history[entry.TokenID] = entry.ExpiresAt
+		}
+	}
+
+	// 记录数量翻倍时清理一次，均摊开销
+	if len(n.history) < n.pruneSize {
+		return
+	}
+	for tokenID, expiresAt := range n.history {
+		if !now.Before(expiresAt) {
+			delete(n.history, tokenID)
+		}
+	}
+	n.pruneSize = max(2*len(n.history), 1024)
+}
+
+// Set 进程内撤销集合
+type Set struct {
+	entries map[string]time.Time // TokenID -> JWT过期时间
+	mu      sync.RWMutex
+}
+
+// NewSet 创建撤销集合（后台定期清理已过期的记录）
+func NewSet() *Set {
+	set := &Set{
+		entries: make(map[string]time.Time),
+	}
+
+	go set.cleanup()
+
+	return set
+}
+
+// Add 添加已撤销的令牌
+func (s *Set) Add(entries []Entry) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, entry := range entries {
+		if entry.ExpiresAt.After(now) {
+			s.entries[entry.TokenID] = entry.ExpiresAt
+		}
+	}
+}
+
+// Contains 检查令牌是否已被撤销
+func (s *Set) Contains(tokenID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	expiresAt, exists := s.entries[tokenID]
+	return exists && time.Now().Before(expiresAt)
+}
+
+// Len 获取记录数量
+func (s *Set) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.entries)
+}
+
+// cleanup 定期清理已过期的记录
+func (s *Set) cleanup() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		now := time.Now()
+
+		s.mu.Lock()
+		for tokenID, expiresAt := range s.entries {
+			if !now.Before(expiresAt) {
+				delete(s.entries, tokenID)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,256 @@
+// Package cache 两级Token缓存（进程内L1 + 共享后端L2）
+package cache
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"time"
+
+	"yggdrasil-api-go/src/cache/revocation"
+	"yggdrasil-api-go/src/utils"
+	"yggdrasil-api-go/src/yggdrasil"
+)
+
+// 两级Token缓存默认配置
+const (
+	defaultL1TTL = time.Minute // L1条目最长保留时间（撤销事件丢失时的兜底）
+)
+
+// TieredTokenCache 两级Token缓存
+// Get优先读取进程内L1（按JWT中的用户ID和TokenID索引，LRU淘汰），未命中时读取L2并回填；
+// 写操作直接作用于L2，L2的撤销事件（Redis发布订阅、数据库轮询）会从所有实例的L1中移除被撤销的Token
+type TieredTokenCache struct {
+	l2 TokenCache
+
+	entries    map[string]*list.Element // "userID:tokenID" -> LRU元素
+	byTokenID  map[string]string        // tokenID -> "userID:tokenID"（撤销事件只包含TokenID）
+	lru        *list.List               // 最近使用的在前
+	maxEntries int
+	ttl        time.Duration
+	generation uint64 // 每次移除条目时递增，避免回填与撤销并发时写回已撤销的Token
+	mu         sync.Mutex
+}
+
+// l1Entry L1缓存条目
+type l1Entry struct {
+	key      string
+	tokenID  string
+	token    yggdrasil.Token
+	cachedAt time.Time
+}
+
+// NewTieredTokenCache 在L2前增加进程内L1缓存
+// 选项：l1_size（最大条目数）、l1_ttl（条目最长保留时间，默认1分钟）
+func NewTieredTokenCache(l2 TokenCache, options map[string]any) (*TieredTokenCache, error) {
+	maxEntries, _ := options["l1_size"].(int)
+	if maxEntries <= 0 {
+		return nil, fmt.Errorf("l1_size must be positive")
+	}
+
+	ttl := defaultL1TTL
+	switch v := options["l1_ttl"].(type) {
+	case time.Duration:
+		ttl = v
+	case string:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid l1_ttl: %w", err)
+		}
+		ttl = d
+	}
+
+	c := &TieredTokenCache{
+		l2:         l2,
+		entries:    make(map[string]*list.Element),
+		byTokenID:  make(map[string]string),
+		lru:        list.New(),
+		maxEntries: maxEntries,
+		ttl:        ttl,
+	}
+
+	// 订阅L2的撤销事件（包括其他实例产生的）
+	l2.SubscribeRevocations(c.evictRevoked)
+
+	return c, nil
+}
+
+// Store 存储Token
+func (c *TieredTokenCache) Store(token *yggdrasil.Token) error {
+	return c.l2.Store(token)
+}
+
+// StoreWithLimit 存储Token并撤销最旧的Token（被撤销的Token通过撤销事件从L1中移除）
+func (c *TieredTokenCache) StoreWithLimit(token *yggdrasil.Token, limit int) (int, error) {
+	return c.l2.StoreWithLimit(token, limit)
+}
+
+// Get 获取Token（优先读取L1，非本服务签发的JWT直接读取L2）
+func (c *TieredTokenCache) Get(accessToken string) (*yggdrasil.Token, error) {
+	claims, err := utils.ValidateJWT(accessToken)
+	if err != nil {
+		return c.l2.Get(accessToken)
+	}
+	key := tokenKey(claims.UserID, claims.TokenID)
+
+	c.mu.Lock()
+	if element, exists := c.entries[key]; exists {
+		entry := element.Value.(*l1Entry)
+		if time.Since(entry.cachedAt) < c.ttl && entry.token.IsRefreshable() {
+			c.lru.MoveToFront(element)
+			token := entry.token
+			c.mu.Unlock()
+			token.AccessToken = accessToken
+			return &token, nil
+		}
+		c.removeElement(element)
+	}
+	generation := c.generation
+	c.mu.Unlock()
+
+	token, err := c.l2.Get(accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// 读取L2期间有Token被移除时不回填（无法确定读到的是否为已撤销的Token）
+	if c.generation == generation {
+		c.add(key, claims.TokenID, token)
+	}
+	return token, nil
+}
+
+// Delete 删除Token
+func (c *TieredTokenCache) Delete(accessToken string) error {
+	if claims, err := utils.ValidateJWT(accessToken); err == nil {
+		c.evict(tokenKey(claims.UserID, claims.TokenID))
+	}
+	return c.l2.Delete(accessToken)
+}
+
+// GetUserTokens 获取用户的所有Token
+func (c *TieredTokenCache) GetUserTokens(userID string) ([]*yggdrasil.Token, error) {
+	return c.l2.GetUserTokens(userID)
+}
+
+// DeleteUserTokens 删除用户的所有Token
+func (c *TieredTokenCache) DeleteUserTokens(userID string) error {
+	c.mu.Lock()
+	for element := c.lru.Front(); element != nil; {
+		next := element.Next()
+		if element.Value.(*l1Entry).token.Owner == userID {
+			c.removeElement(element)
+		}
+		element = next
+	}
+	c.mu.Unlock()
+
+	return c.l2.DeleteUserTokens(userID)
+}
+
+// GetUserTokenCount 获取用户Token数量
+func (c *TieredTokenCache) GetUserTokenCount(userID string) (int, error) {
+	return c.l2.GetUserTokenCount(userID)
+}
+
+// CleanupExpired 清理过期Token
+func (c *TieredTokenCache) CleanupExpired() error {
+	c.mu.Lock()
+	for element := c.lru.Front(); element != nil; {
+		next := element.Next()
+		entry := element.Value.(*l1Entry)
+		if time.Since(entry.cachedAt) >= c.ttl || !entry.token.IsRefreshable() {
+			c.removeElement(element)
+		}
+		element = next
+	}
+	c.mu.Unlock()
+
+	return c.l2.CleanupExpired()
+}
+
+// SubscribeRevocations 订阅Token撤销事件
+func (c *TieredTokenCache) SubscribeRevocations(handler func([]revocation.Entry)) {
+	c.l2.SubscribeRevocations(handler)
+}
+
+// ExternallyManaged L2的Token是否可能由其他程序签发或删除
+func (c *TieredTokenCache) ExternallyManaged() bool {
+	managed, ok := c.l2.(ExternallyManaged)
+	return ok && managed.ExternallyManaged()
+}
+
+// Close 关闭缓存连接
+func (c *TieredTokenCache) Close() error {
+	return c.l2.Close()
+}
+
+// GetCacheType 获取缓存类型
+func (c *TieredTokenCache) GetCacheType() string {
+	return "l1+" + c.l2.GetCacheType()
+}
+
+// Len 获取L1条目数量
+func (c *TieredTokenCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// evictRevoked 从L1中移除被撤销的Token
+func (c *TieredTokenCache) evictRevoked(entries []revocation.Entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	for _, entry := range entries {
+		if key, exists := c.byTokenID[entry.TokenID]; exists {
+			c.removeElement(c.entries[key])
+		}
+	}
+}
+
+// evict 从L1中移除Token
+func (c *TieredTokenCache) evict(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	if element, exists := c.entries[key]; exists {
+		c.removeElement(element)
+	}
+}
+
+// add 添加L1条目，超出容量时淘汰最久未使用的条目（调用方需持有锁）
+func (c *TieredTokenCache) add(key, tokenID string, token *yggdrasil.Token) {
+	if element, exists := c.entries[key]; exists {
+		c.removeElement(element)
+	}
+
+	entry := &l1Entry{key: key, tokenID: tokenID, token: *token, cachedAt: time.Now()}
+	entry.token.AccessToken = "" // 访问令牌由调用方提供，无需在L1中保存
+	c.entries[key] = c.lru.PushFront(entry)
+	c.byTokenID[tokenID] = key
+
+	for c.lru.Len() > c.maxEntries {
+		c.removeElement(c.lru.Back())
+	}
+}
+
+// removeElement 移除L1条目（调用方需持有锁）
+func (c *TieredTokenCache) removeElement(element *list.Element) {
+	entry := element.Value.(*l1Entry)
+	c.lru.Remove(element)
+	delete(c.entries, entry.key)
+	if c.byTokenID[entry.tokenID] == entry.key {
+		delete(c.byTokenID, entry.tokenID)
+	}
+}
+
+// tokenKey 生成L1键（用户ID:TokenID）
+func tokenKey(userID, tokenID string) string {
+	return userID + ":" + tokenID
+}
@@ -2,15 +2,25 @@
 package cache
 
 import (
+	"container/list"
 	"sync"
 	"time"
 
+	"yggdrasil-api-go/src/config"
 	"yggdrasil-api-go/src/middleware"
 	"yggdrasil-api-go/src/yggdrasil"
 )
 
+// 用户缓存默认配置（与DefaultConfig一致）
+const (
+	defaultUserCacheDuration        = 5 * time.Minute
+	defaultUserCacheMaxUsers        = 500
+	defaultUserCacheCleanupInterval = time.Minute
+)
+
 // UserCacheItem 用户缓存项
 type UserCacheItem struct {
+	Key       string
 	User      *yggdrasil.User
 	ExpiresAt time.Time
 }
@@ -20,34 +30,66 @@ func (item *UserCacheItem) IsExpired() bool {
 	return time.Now().After(item.ExpiresAt)
 }
 
-// UserCache 用户信息缓存
+// UserCache 用户信息缓存（LRU，超出容量时淘汰最久未使用的用户）
+// 缓存的用户对象由所有调用方共享，不应修改
 type UserCache struct {
-	cache    sync.Map
+	items    map[string]*list.Element
+	byUser   map[string]map[string]struct{} // 用户ID -> 缓存键（缓存键可能是用户ID、邮箱、角色名等）
+	lru      *list.List                     // 最近使用的在前
 	duration time.Duration
+	maxUsers int
+	epoch    uint64 // 每次失效递增，查询期间发生失效时不写入查询结果
+	mu       sync.Mutex
+
+	stop chan struct{} // 停止清理协程
 }
 
-// NewUserCache 创建用户缓存
-func NewUserCache(duration time.Duration) *UserCache {
-	cache := &UserCache{
-		duration: duration,
+// NewUserCache 创建用户缓存（duration或maxUsers<=0时不缓存，cleanupInterval<=0时不启动清理协程）
+func NewUserCache(duration time.Duration, maxUsers int, cleanupInterval time.Duration) *UserCache {
+	uc := &UserCache{
+		items:  make(map[string]*list.Element),
+		byUser: make(map[string]map[string]struct{}),
+		lru:    list.New(),
 	}
+	uc.Configure(duration, maxUsers, cleanupInterval)
+	return uc
+}
 
-	// 启动清理协程
-	go cache.cleanup()
+// Configure 修改缓存配置并重启清理协程（缩小容量时立即淘汰多余的用户）
+func (uc *UserCache) Configure(duration time.Duration, maxUsers int, cleanupInterval time.Duration) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	if duration <= 0 || maxUsers <= 0 {
+		duration, maxUsers, cleanupInterval = 0, 0, 0
+	}
+	uc.duration = duration
+	uc.maxUsers = maxUsers
+	for uc.lru.Len() > uc.maxUsers {
+		uc.remove(uc.lru.Back())
+	}
 
-	return cache
+	uc.stopCleanup()
+	if cleanupInterval > 0 {
+		uc.stop = make(chan struct{})
+		go uc.cleanup(cleanupInterval, uc.stop)
+	}
 }
 
 // Get 获取用户信息
 func (uc *UserCache) Get(key string) (*yggdrasil.User, bool) {
-	if value, ok := uc.cache.Load(key); ok {
-		item := value.(*UserCacheItem)
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	if element, ok := uc.items[key]; ok {
+		item := element.Value.(*UserCacheItem)
 		if !item.IsExpired() {
+			uc.lru.MoveToFront(element)
 			middleware.GlobalCacheMonitor.RecordHit()
 			return item.User, true
 		}
 		// 过期则删除
-		uc.cache.Delete(key)
+		uc.remove(element)
 	}
 
 	middleware.GlobalCacheMonitor.RecordMiss()
@@ -56,64 +98,185 @@ func (uc *UserCache) Get(key string) (*yggdrasil.User, bool) {
 
 // Set 设置用户信息
 func (uc *UserCache) Set(key string, user *yggdrasil.User) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	uc.set(key, user)
+}
+
+// set 写入缓存项并淘汰超出容量的用户（调用方需持有锁）
+func (uc *UserCache) set(key string, user *yggdrasil.User) {
+	if uc.maxUsers <= 0 || user == nil {
+		return
+	}
+	if element, ok := uc.items[key]; ok {
+		uc.remove(element)
+	}
+
 	item := &UserCacheItem{
+		Key:       key,
 		User:      user,
 		ExpiresAt: time.Now().Add(uc.duration),
 	}
-	uc.cache.Store(key, item)
+	uc.items[key] = uc.lru.PushFront(item)
+	keys, ok := uc.byUser[user.ID]
+	if !ok {
+		keys = make(map[string]struct{})
+		uc.byUser[user.ID] = keys
+	}
+	keys[key] = struct{}{}
+
+	for uc.lru.Len() > uc.maxUsers {
+		uc.remove(uc.lru.Back())
+	}
 }
 
 // Delete 删除用户信息
 func (uc *UserCache) Delete(key string) {
-	uc.cache.Delete(key)
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	uc.epoch++
+	if element, ok := uc.items[key]; ok {
+		uc.remove(element)
+	}
+}
+
+// DeleteUser 删除指定用户ID的所有缓存项（缓存键可能是邮箱、角色名等）
+func (uc *UserCache) DeleteUser(userID string) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	uc.epoch++
+	for key := range uc.byUser[userID] {
+		if element, ok := uc.items[key]; ok {
+			uc.remove(element)
+		}
+	}
+}
+
+// UserIDs 获取缓存中的所有用户ID
+func (uc *UserCache) UserIDs() []string {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	ids := make([]string, 0, len(uc.byUser))
+	for userID := range uc.byUser {
+		ids = append(ids, userID)
+	}
+	return ids
+}
+
+// Clear 清空缓存
+func (uc *UserCache) Clear() {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	uc.epoch++
+	uc.items = make(map[string]*list.Element)
+	uc.byUser = make(map[string]map[string]struct{})
+	uc.lru.Init()
+}
+
+// Stop 停止清理协程
+func (uc *UserCache) Stop() {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	uc.stopCleanup()
+}
+
+// stopCleanup 停止清理协程（调用方需持有锁）
+func (uc *UserCache) stopCleanup() {
+	if uc.stop != nil {
+		close(uc.stop)
+		uc.stop = nil
+	}
+}
+
+// remove 移除缓存项及其用户索引（调用方需持有锁）
+func (uc *UserCache) remove(element *list.Element) {
+	item := element.Value.(*UserCacheItem)
+	uc.lru.Remove(element)
+	delete(uc.items, item.Key)
+	if keys, ok := uc.byUser[item.User.ID]; ok {
+		delete(keys, item.Key)
+		if len(keys) == 0 {
+			delete(uc.byUser, item.User.ID)
+		}
+	}
 }
 
 // cleanup 定期清理过期缓存
-func (uc *UserCache) cleanup() {
-	ticker := time.NewTicker(time.Minute)
+func (uc *UserCache) cleanup(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		uc.cache.Range(func(key, value any) bool {
-			item := value.(*UserCacheItem)
-			if item.IsExpired() {
-				uc.cache.Delete(key)
+	for {
+		select {
+		case <-ticker.C:
+			uc.mu.Lock()
+			for element := uc.lru.Back(); element != nil; {
+				prev := element.Prev()
+				if element.Value.(*UserCacheItem).IsExpired() {
+					uc.remove(element)
+				}
+				element = prev
 			}
-			return true
-		})
+			uc.mu.Unlock()
+		case <-stop:
+			return
+		}
 	}
 }
 
 // GetStats 获取缓存统计
 func (uc *UserCache) GetStats() map[string]any {
-	count := 0
-	expired := 0
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
 
-	uc.cache.Range(func(key, value any) bool {
-		count++
-		item := value.(*UserCacheItem)
-		if item.IsExpired() {
+	count := uc.lru.Len()
+	expired := 0
+	for element := uc.lru.Front(); element != nil; element = element.Next() {
+		if element.Value.(*UserCacheItem).IsExpired() {
 			expired++
 		}
-		return true
-	})
+	}
 
 	return map[string]any{
 		"total_items":            count,
 		"expired_items":          expired,
 		"valid_items":            count - expired,
+		"max_users":              uc.maxUsers,
 		"cache_duration_minutes": uc.duration.Minutes(),
 	}
 }
 
 // 全局用户缓存实例（默认5分钟缓存，可通过配置修改）
-var GlobalUserCache = NewUserCache(5 * time.Minute)
+var GlobalUserCache = NewUserCache(defaultUserCacheDuration, defaultUserCacheMaxUsers, defaultUserCacheCleanupInterval)
+
+// InitUserCache 根据配置初始化用户缓存（未启用时不缓存，未设置的选项使用默认值）
+func InitUserCache(cfg config.UserCacheConfig) {
+	if !cfg.Enabled {
+		GlobalUserCache.Configure(0, 0, 0)
+		GlobalUserCache.Clear()
+		return
+	}
 
-// InitUserCache 根据配置初始化用户缓存
-func InitUserCache(duration time.Duration) {
-	if duration > 0 {
-		GlobalUserCache = NewUserCache(duration)
+	duration, maxUsers, cleanupInterval := cfg.Duration, cfg.MaxUsers, cfg.CleanupInterval
+	if duration <= 0 {
+		duration = defaultUserCacheDuration
 	}
+	if maxUsers <= 0 {
+		maxUsers = defaultUserCacheMaxUsers
+	}
+	if cleanupInterval <= 0 {
+		cleanupInterval = defaultUserCacheCleanupInterval
+	}
+	GlobalUserCache.Configure(duration, maxUsers, cleanupInterval)
+}
+
+// UserIDCacheKey 按用户ID查询的缓存键
+func UserIDCacheKey(userID string) string {
+	return "id:" + userID
 }
 
 // CachedUserLookup 带缓存的用户查询装饰器
@@ -123,13 +286,21 @@ func CachedUserLookup(key string, lookupFunc func() (*yggdrasil.User, error)) (*
 		return user, nil
 	}
 
+	GlobalUserCache.mu.Lock()
+	epoch := GlobalUserCache.epoch
+	GlobalUserCache.mu.Unlock()
+
 	// 缓存未命中，执行查询
 	user, err := lookupFunc()
 	if err != nil {
 		return nil, err
 	}
 
-	// 存储到缓存
-	GlobalUserCache.Set(key, user)
+	// 存储到缓存（查询期间用户被失效时，查询结果可能已过时，不写入）
+	GlobalUserCache.mu.Lock()
+	if GlobalUserCache.epoch == epoch {
+		GlobalUserCache.set(key, user)
+	}
+	GlobalUserCache.mu.Unlock()
 	return user, nil
 }
@@ -2,6 +2,7 @@
 package config
 
 import (
+	"encoding/base64"
 	"fmt"
 	"os"
 	"strings"
@@ -18,6 +19,7 @@ type Config struct {
 	Storage    StorageConfig    `yaml:"storage"`
 	Cache      CacheConfig      `yaml:"cache"`
 	Texture    TextureConfig    `yaml:"texture"`
+	Profile    ProfileConfig    `yaml:"profile"`
 	Yggdrasil  YggdrasilConfig  `yaml:"yggdrasil"`
 	Middleware MiddlewareConfig `yaml:"middleware"`
 	Logging    LoggingConfig    `yaml:"logging"`
@@ -42,7 +44,8 @@ type MemoryStorageOptions struct {
 
 // FileStorageOptions 文件存储选项
 type FileStorageOptions struct {
-	DataDir string `yaml:"data_dir"` // 数据目录
+	DataDir       string `yaml:"data_dir"`       // 数据目录
+	UUIDAlgorithm string `yaml:"uuid_algorithm"` // 角色UUID生成算法：v3(离线模式兼容) | v4(随机)
 }
 
 // DatabaseStorageOptions 数据库存储选项
@@ -53,10 +56,12 @@ type DatabaseStorageOptions struct {
 
 // BlessingSkinStorageOptions BlessingSkin存储选项
 type BlessingSkinStorageOptions struct {
-	DatabaseDSN            string               `yaml:"database_dsn"`              // MySQL连接字符串
-	Debug                  bool                 `yaml:"debug"`                     // 调试模式
-	TextureBaseURLOverride bool                 `yaml:"texture_base_url_override"` // 为true时使用配置文件的texture.base_url而不是options中的site_url
-	Security               BlessingSkinSecurity `yaml:"security"`                  // 安全配置
+	DatabaseDSN            string                 `yaml:"database_dsn"`              // MySQL连接字符串
+	Debug                  bool                   `yaml:"debug"`                     // 调试模式
+	TextureBaseURLOverride bool                   `yaml:"texture_base_url_override"` // 为true时使用配置文件的texture.base_url而不是options中的site_url
+	Security               BlessingSkinSecurity   `yaml:"security"`                  // 安全配置
+	Reconcile              BlessingSkinReconcile  `yaml:"reconcile"`                 // UUID对账配置
+	ChangeFeed             BlessingSkinChangeFeed `yaml:"change_feed"`               // 数据变更检测配置
 }
 
 // BlessingSkinSecurity BlessingSkin安全配置
@@ -66,17 +71,35 @@ type BlessingSkinSecurity struct {
 	AppKey    string `yaml:"app_key"`    // 应用密钥 (对应BlessingSkin的APP_KEY)
 }
 
+// BlessingSkinReconcile BlessingSkin角色与UUID映射对账配置
+type BlessingSkinReconcile struct {
+	Enabled      bool          `yaml:"enabled"`       // 是否启用定时对账
+	Interval     time.Duration `yaml:"interval"`      // 对账间隔
+	AutoApply    bool          `yaml:"auto_apply"`    // 是否自动应用修复（否则仅生成报告）
+	PruneOrphans bool          `yaml:"prune_orphans"` // 是否删除已删除角色的遗留映射
+	ReportDir    string        `yaml:"report_dir"`    // 对账报告输出目录（为空则只输出日志）
+}
+
+// BlessingSkinChangeFeed BlessingSkin数据变更检测配置（网站上的修改通知缓存失效）
+type BlessingSkinChangeFeed struct {
+	Enabled  bool          `yaml:"enabled"`  // 是否启用变更检测
+	Interval time.Duration `yaml:"interval"` // 轮询间隔
+}
+
 // CacheConfig 缓存配置
 type CacheConfig struct {
-	Token    CacheBackendConfig  `yaml:"token"`    // Token缓存配置
-	Session  CacheBackendConfig  `yaml:"session"`  // Session缓存配置
-	Response ResponseCacheConfig `yaml:"response"` // 响应缓存配置
-	User     UserCacheConfig     `yaml:"user"`     // 用户缓存配置
+	RedisConnections    map[string]map[string]any `yaml:"redis_connections"`    // 命名Redis连接（redis缓存选项connection引用）
+	DatabaseConnections map[string]map[string]any `yaml:"database_connections"` // 命名数据库连接（database缓存选项connection引用）
+	Token               CacheBackendConfig        `yaml:"token"`                // Token缓存配置
+	Session             CacheBackendConfig        `yaml:"session"`              // Session缓存配置
+	LoginAttempts       CacheBackendConfig        `yaml:"login_attempts"`       // 登录失败记录缓存配置（留空使用Session缓存配置）
+	Response            ResponseCacheConfig       `yaml:"response"`             // 响应缓存配置
+	User                UserCacheConfig           `yaml:"user"`                 // 用户缓存配置
 }
 
 // CacheBackendConfig 缓存后端配置
 type CacheBackendConfig struct {
-	Type    string         `yaml:"type"`    // 缓存类型：memory, redis, file, database
+	Type    string         `yaml:"type"`    // 缓存类型：memory, redis, file, database, bolt
 	Options map[string]any `yaml:"options"` // 缓存选项
 }
 
@@ -187,10 +210,58 @@ type ServerConfig struct {
 
 // AuthConfig 认证配置
 type AuthConfig struct {
-	TokenExpiration     time.Duration `yaml:"token_expiration"`     // 令牌过期时间
-	JWTSecret           string        `yaml:"jwt_secret"`           // JWT密钥
-	TokensLimit         int           `yaml:"tokens_limit"`         // 每用户令牌数量限制
-	RequireVerification bool          `yaml:"require_verification"` // 是否需要邮箱验证
+	TokenExpiration        time.Duration   `yaml:"token_expiration"`         // 令牌有效期（之后令牌暂时失效，validate/join失败但仍可刷新）
+	TokenRefreshExpiration time.Duration   `yaml:"token_refresh_expiration"` // 令牌可刷新期限（自签发起算，之后令牌彻底失效）
+	SessionTTL             time.Duration   `yaml:"session_ttl"`              // 进入服务器会话有效期（join到hasJoined之间的最长间隔，所有Session缓存后端共用）
+	SessionAuditLog        bool            `yaml:"session_audit_log"`        // 记录进入服务器审计日志（join和hasJoined验证结果，包含令牌ID、客户端IP和User-Agent）
+	JWTSecret              string          `yaml:"jwt_secret"`               // JWT密钥
+	TokensLimit            int             `yaml:"tokens_limit"`             // 每用户令牌数量限制（超出时撤销最旧的令牌，0表示不限制；BlessingSkin存储使用ygg_tokens_limit）
+	RequireVerification    bool            `yaml:"require_verification"`     // 是否需要邮箱验证
+	ProfileReselection     bool            `yaml:"profile_reselection"`      // 刷新时是否允许更换已绑定的角色（规范只允许未绑定角色的令牌选择角色，部分启动器依赖更换）
+	TokenPolicies          []TokenPolicy   `yaml:"token_policies"`           // 令牌策略（按顺序使用第一条匹配的策略，都不匹配时使用上面的有效期）
+	TwoFactor              TwoFactorConfig `yaml:"two_factor"`               // 两步验证（TOTP）配置
+	Lockout                LockoutConfig   `yaml:"lockout"`                  // 账户登录失败锁定配置
+}
+
+// LockoutConfig 账户登录失败锁定配置
+// 按用户名记录失败次数（与客户端IP无关），达到阈值后锁定，之后每次失败锁定时间翻倍
+type LockoutConfig struct {
+	Enabled     bool          `yaml:"enabled"`      // 是否启用登录失败锁定
+	MaxFailures int           `yaml:"max_failures"` // 开始锁定的连续失败次数（默认5）
+	Duration    time.Duration `yaml:"duration"`     // 首次锁定时间（默认1分钟，之后每次失败翻倍）
+	MaxDuration time.Duration `yaml:"max_duration"` // 最长锁定时间（默认1小时）
+	ResetAfter  time.Duration `yaml:"reset_after"`  // 最后一次失败后多久清除失败记录（默认24小时）
+}
+
+// TwoFactorConfig 两步验证配置
+// 启动器只提交用户名和密码，验证码以"密码:验证码"的形式附加在密码后
+type TwoFactorConfig struct {
+	Enabled       bool     `yaml:"enabled"`        // 是否启用两步验证
+	Issuer        string   `yaml:"issuer"`         // 验证器应用中显示的发行方（留空使用服务器名称）
+	EncryptionKey string   `yaml:"encryption_key"` // TOTP密钥的加密密钥（base64编码的32字节，留空时由jwt_secret派生）
+	RequiredRoles []string `yaml:"required_roles"` // 必须启用两步验证的账户角色：normal、admin、super_admin
+	Skew          *int     `yaml:"skew"`           // 允许的时间偏差（30秒步长的个数，默认1；0表示只接受当前步长）
+	RecoveryCodes *int     `yaml:"recovery_codes"` // 生成的恢复码数量（默认10，1到100）
+}
+
+// 令牌策略的clientToken匹配条件
+const (
+	ClientTokenProvided  = "provided"  // 登录时客户端提供了clientToken
+	ClientTokenGenerated = "generated" // 登录时未提供clientToken，由服务端生成
+)
+
+// TokenPolicy 令牌策略（决定令牌有效期、可刷新期限和刷新时是否续期）
+// 匹配条件都为空时匹配所有请求；agent由客户端自行声明，不应作为授予更长有效期的唯一依据
+type TokenPolicy struct {
+	Name                   string        `yaml:"name"`                     // 策略名称（写入令牌，刷新时沿用签发时的策略）
+	Agents                 []string      `yaml:"agents"`                   // 匹配的客户端名称（agent.name，不区分大小写）
+	MinAgentVersion        int           `yaml:"min_agent_version"`        // 最低客户端版本（agent.version）
+	MaxAgentVersion        int           `yaml:"max_agent_version"`        // 最高客户端版本（0表示不限制）
+	ClientToken            string        `yaml:"client_token"`             // clientToken条件：provided、generated，留空不限制
+	Roles                  []string      `yaml:"roles"`                    // 匹配的账户角色：normal、admin、super_admin
+	TokenExpiration        time.Duration `yaml:"token_expiration"`         // 令牌有效期（0表示使用默认值）
+	TokenRefreshExpiration time.Duration `yaml:"token_refresh_expiration"` // 令牌可刷新期限（0表示使用默认值）
+	Sliding                *bool         `yaml:"sliding"`                  // 刷新时是否从刷新时刻重新计算可刷新期限（默认true；false时新令牌不超过首次登录的可刷新期限）
 }
 
 // RateConfig 速率限制配置
@@ -199,6 +270,12 @@ type RateConfig struct {
 	Enabled      bool          `yaml:"enabled"`       // 是否启用速率限制
 }
 
+// ProfileConfig 角色管理配置
+type ProfileConfig struct {
+	RenameEnabled  bool          `yaml:"rename_enabled"`  // 是否允许角色改名
+	RenameCooldown time.Duration `yaml:"rename_cooldown"` // 两次改名之间的冷却时间
+}
+
 // YggdrasilConfig Yggdrasil相关配置
 type YggdrasilConfig struct {
 	Meta        MetaConfig     `yaml:"meta"`         // 元数据配置
@@ -294,6 +371,34 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("JWT secret must be at least 32 characters long")
 	}
 
+	// 验证会话有效期（未设置时使用默认的30秒）
+	if c.Auth.SessionTTL < 0 {
+		return fmt.Errorf("session_ttl must not be negative, got: %s", c.Auth.SessionTTL)
+	}
+
+	// 验证令牌策略
+	if err := validateTokenPolicies(c.Auth.TokenPolicies); err != nil {
+		return err
+	}
+
+	// 验证两步验证配置
+	if err := validateTwoFactor(&c.Auth.TwoFactor); err != nil {
+		return err
+	}
+
+	// 验证登录失败锁定配置
+	if err := validateLockout(&c.Auth.Lockout); err != nil {
+		return err
+	}
+
+	// 验证响应缓存配置（未设置时使用默认的1000条、10分钟）
+	if c.Cache.Response.MaxCacheSize < 0 {
+		return fmt.Errorf("response max_cache_size must not be negative, got: %d", c.Cache.Response.MaxCacheSize)
+	}
+	if c.Cache.Response.CacheDuration < 0 {
+		return fmt.Errorf("response cache_duration must not be negative, got: %s", c.Cache.Response.CacheDuration)
+	}
+
 	// 验证密钥文件路径（对于BlessingSkin存储，允许为空）
 	if c.Storage.Type != "blessing_skin" {
 		if c.Yggdrasil.Keys.PrivateKeyPath == "" || c.Yggdrasil.Keys.PublicKeyPath == "" {
@@ -311,6 +416,87 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// validateTokenPolicies 验证令牌策略
+func validateTokenPolicies(policies []TokenPolicy) error {
+	names := make(map[string]bool, len(policies))
+	for i, policy := range policies {
+		if policy.Name == "" {
+			return fmt.Errorf("token policy #%d: name is required", i+1)
+		}
+		if names[policy.Name] {
+			return fmt.Errorf("token policy %s: duplicate name", policy.Name)
+		}
+		names[policy.Name] = true
+
+		switch policy.ClientToken {
+		case "", ClientTokenProvided, ClientTokenGenerated:
+		default:
+			return fmt.Errorf("token policy %s: invalid client_token %q (expected provided or generated)", policy.Name, policy.ClientToken)
+		}
+		for _, role := range policy.Roles {
+			switch role {
+			case "normal", "admin", "super_admin":
+			default:
+				return fmt.Errorf("token policy %s: invalid role %q (expected normal, admin or super_admin)", policy.Name, role)
+			}
+		}
+		if policy.MaxAgentVersion > 0 && policy.MaxAgentVersion < policy.MinAgentVersion {
+			return fmt.Errorf("token policy %s: max_agent_version must not be less than min_agent_version", policy.Name)
+		}
+		if policy.TokenExpiration < 0 || policy.TokenRefreshExpiration < 0 {
+			return fmt.Errorf("token policy %s: token expiration must not be negative", policy.Name)
+		}
+		if policy.TokenExpiration > 0 && policy.TokenRefreshExpiration > 0 && policy.TokenRefreshExpiration < policy.TokenExpiration {
+			return fmt.Errorf("token policy %s: token_refresh_expiration must not be less than token_expiration", policy.Name)
+		}
+	}
+	return nil
+}
+
+// validateTwoFactor 验证两步验证配置
+func validateTwoFactor(cfg *TwoFactorConfig) error {
+	if !cfg.Enabled {
+		return nil
+	}
+	for _, role := range cfg.RequiredRoles {
+		switch role {
+		case "normal", "admin", "super_admin":
+		default:
+			return fmt.Errorf("two_factor: invalid required role %q (expected normal, admin or super_admin)", role)
+		}
+	}
+	if cfg.EncryptionKey != "" {
+		key, err := base64.StdEncoding.DecodeString(cfg.EncryptionKey)
+		if err != nil || len(key) != 32 {
+			return fmt.Errorf("two_factor: encryption_key must be 32 bytes encoded in base64")
+		}
+	}
+	if cfg.Skew != nil && (*cfg.Skew < 0 || *cfg.Skew > 10) {
+		return fmt.Errorf("two_factor: skew must be between 0 and 10")
+	}
+	if cfg.RecoveryCodes != nil && (*cfg.RecoveryCodes < 1 || *cfg.RecoveryCodes > 100) {
+		return fmt.Errorf("two_factor: recovery_codes must be between 1 and 100")
+	}
+	return nil
+}
+
+// validateLockout 验证登录失败锁定配置（未设置的项使用默认值）
+func validateLockout(cfg *LockoutConfig) error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.MaxFailures < 0 {
+		return fmt.Errorf("lockout: max_failures must not be negative, got: %d", cfg.MaxFailures)
+	}
+	if cfg.Duration < 0 || cfg.MaxDuration < 0 || cfg.ResetAfter < 0 {
+		return fmt.Errorf("lockout: durations must not be negative")
+	}
+	if cfg.Duration > 0 && cfg.MaxDuration > 0 && cfg.MaxDuration < cfg.Duration {
+		return fmt.Errorf("lockout: max_duration must not be less than duration")
+	}
+	return nil
+}
+
 // validateDomainOrCIDR 验证域名格式
 func validateDomainOrCIDR(input string) error {
 	// 检查域名格式（简单验证）
@@ -387,10 +573,20 @@ func DefaultConfig() *Config {
 			BaseURL: "", // 默认为空，表示不使用基础路径
 		},
 		Auth: AuthConfig{
-			TokenExpiration:     3 * 24 * time.Hour, // 3天
-			JWTSecret:           "yggdrasil-api-secret-key-change-in-production",
-			TokensLimit:         10,
-			RequireVerification: false,
+			TokenExpiration:        3 * 24 * time.Hour, // 3天
+			TokenRefreshExpiration: 7 * 24 * time.Hour, // 7天
+			SessionTTL:             30 * time.Second,   // 与Yggdrasil标准一致
+			JWTSecret:              "yggdrasil-api-secret-key-change-in-production",
+			TokensLimit:            10,
+			RequireVerification:    false,
+			ProfileReselection:     false,
+			Lockout: LockoutConfig{
+				Enabled:     true,
+				MaxFailures: 5,
+				Duration:    1 * time.Minute,
+				MaxDuration: 1 * time.Hour,
+				ResetAfter:  24 * time.Hour,
+			},
 		},
 		Rate: RateConfig{
 			AuthInterval: 1 * time.Second, // 1秒间隔
@@ -400,7 +596,8 @@ func DefaultConfig() *Config {
 			Type:          "memory",
 			MemoryOptions: MemoryStorageOptions{},
 			FileOptions: FileStorageOptions{
-				DataDir: "data",
+				DataDir:       "data",
+				UUIDAlgorithm: "v3",
 			},
 			DatabaseOptions: DatabaseStorageOptions{
 				DatabaseDSN: "",
@@ -415,6 +612,17 @@ func DefaultConfig() *Config {
 					PwdMethod: "BCRYPT",
 					AppKey:    "base64:your_app_key_here",
 				},
+				Reconcile: BlessingSkinReconcile{
+					Enabled:      false,
+					Interval:     6 * time.Hour,
+					AutoApply:    false,
+					PruneOrphans: false,
+					ReportDir:    "",
+				},
+				ChangeFeed: BlessingSkinChangeFeed{
+					Enabled:  true,
+					Interval: 5 * time.Second,
+				},
 			},
 		},
 		Cache: CacheConfig{
@@ -447,6 +655,10 @@ func DefaultConfig() *Config {
 			MaxFileSize:   1024 * 1024, // 1MB
 			AllowedTypes:  []string{"image/png", "image/jpeg"},
 		},
+		Profile: ProfileConfig{
+			RenameEnabled:  true,
+			RenameCooldown: 30 * 24 * time.Hour, // 30天
+		},
 		Yggdrasil: YggdrasilConfig{
 			Meta: MetaConfig{
 				ServerName:            "Yggdrasil API Server (Go)",
@@ -0,0 +1,89 @@
+// Package handlers 管理员处理器
+package handlers
+
+import (
+	"yggdrasil-api-go/src/cache"
+	"yggdrasil-api-go/src/lockout"
+	storage "yggdrasil-api-go/src/storage/interface"
+	"yggdrasil-api-go/src/utils"
+	"yggdrasil-api-go/src/yggdrasil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminHandler 管理员处理器（需要管理员或超级管理员账户的Bearer令牌）
+type AdminHandler struct {
+	storage    storage.Storage
+	tokenCache cache.TokenCache
+	lockout    *lockout.Guard
+}
+
+// NewAdminHandler 创建新的管理员处理器
+func NewAdminHandler(storage storage.Storage, tokenCache cache.TokenCache, guard *lockout.Guard) *AdminHandler {
+	return &AdminHandler{
+		storage:    storage,
+		tokenCache: tokenCache,
+		lockout:    guard,
+	}
+}
+
+// GetLockout 查询账户的登录失败锁定状态（账户不存在时同样返回失败记录）
+func (h *AdminHandler) GetLockout(c *gin.Context) {
+	if !h.authenticateAdmin(c) {
+		return
+	}
+
+	status, err := h.lockout.Status(h.lockout.Key(c.Param("username")))
+	if err != nil {
+		utils.RespondError(c, 500, "InternalServerError", "Failed to get lockout status")
+		return
+	}
+
+	response := yggdrasil.LockoutStatusResponse{
+		Key:      status.Key,
+		Failures: status.Failures,
+		Locked:   !status.LockedUntil.IsZero(),
+	}
+	if !status.LastFailure.IsZero() {
+		response.LastFailure = status.LastFailure.UnixMilli()
+	}
+	if response.Locked {
+		response.LockedUntil = status.LockedUntil.UnixMilli()
+	}
+	utils.RespondJSONFast(c, response)
+}
+
+// Unlock 解除账户的登录失败锁定（清除失败记录）
+func (h *AdminHandler) Unlock(c *gin.Context) {
+	if !h.authenticateAdmin(c) {
+		return
+	}
+
+	if err := h.lockout.Reset(h.lockout.Key(c.Param("username"))); err != nil {
+		utils.RespondError(c, 500, "InternalServerError", "Failed to unlock account")
+		return
+	}
+
+	utils.RespondNoContent(c)
+}
+
+// authenticateAdmin 验证Bearer令牌属于管理员或超级管理员，失败时直接写入错误响应
+func (h *AdminHandler) authenticateAdmin(c *gin.Context) bool {
+	token, ok := authenticateBearer(c, h.tokenCache)
+	if !ok {
+		return false
+	}
+
+	user, err := getUserByID(h.storage, token.Owner)
+	if err != nil {
+		utils.RespondUnauthorized(c, utils.MsgInvalidToken)
+		return false
+	}
+	switch user.Role() {
+	case yggdrasil.RoleAdmin, yggdrasil.RoleSuperAdmin:
+		return true
+	default:
+		utils.RespondForbiddenOperation(c, utils.MsgPermissionRequired)
+		return false
+	}
+}
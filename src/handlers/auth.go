@@ -5,7 +5,11 @@ import (
 	"time"
 
 	"yggdrasil-api-go/src/cache"
+	"yggdrasil-api-go/src/config"
+	"yggdrasil-api-go/src/lockout"
 	storage "yggdrasil-api-go/src/storage/interface"
+	"yggdrasil-api-go/src/tokenpolicy"
+	"yggdrasil-api-go/src/twofactor"
 	"yggdrasil-api-go/src/utils"
 	"yggdrasil-api-go/src/yggdrasil"
 
@@ -17,17 +21,95 @@ type AuthHandler struct {
 	storage      storage.Storage
 	tokenCache   cache.TokenCache
 	sessionCache cache.SessionCache
+	config       *config.Config
+	policies     *tokenpolicy.Engine
+	credentials  *credentials
 }
 
-// NewAuthHandler 创建新的认证处理器
-func NewAuthHandler(storage storage.Storage, tokenCache cache.TokenCache, sessionCache cache.SessionCache) *AuthHandler {
+// NewAuthHandler 创建新的认证处理器（twoFactor为nil时不检查两步验证，guard为nil时不检查登录失败锁定）
+func NewAuthHandler(storage storage.Storage, tokenCache cache.TokenCache, sessionCache cache.SessionCache, cfg *config.Config, twoFactor *twofactor.Service, guard *lockout.Guard) *AuthHandler {
 	return &AuthHandler{
 		storage:      storage,
 		tokenCache:   tokenCache,
 		sessionCache: sessionCache,
+		config:       cfg,
+		policies:     tokenpolicy.New(cfg.Auth.TokenPolicies),
+		credentials:  &credentials{storage: storage, twoFactor: twoFactor, lockout: guard},
 	}
 }
 
+// tokensLimit 获取每用户令牌数量限制（存储自带的配置优先，如BlessingSkin的ygg_tokens_limit）
+func (h *AuthHandler) tokensLimit() int {
+	if provider, ok := h.storage.(storage.TokensLimitProvider); ok {
+		if limit, ok := provider.GetTokensLimit(); ok {
+			return limit
+		}
+	}
+	return h.config.Auth.TokensLimit
+}
+
+// tokenExpiration 获取默认的令牌有效期和可刷新期限（存储自带的配置优先，如BlessingSkin的ygg_token_expire_1/2；令牌策略可覆盖）
+func (h *AuthHandler) tokenExpiration() (time.Duration, time.Duration) {
+	if provider, ok := h.storage.(storage.TokenExpirationProvider); ok {
+		if expiration, refreshExpiration, ok := provider.GetTokenExpiration(); ok {
+			return expiration, refreshExpiration
+		}
+	}
+	return h.config.Auth.TokenExpiration, h.config.Auth.TokenRefreshExpiration
+}
+
+// authenticateLifetime 根据令牌策略获取登录签发的令牌有效期
+func (h *AuthHandler) authenticateLifetime(req *yggdrasil.AuthenticateRequest, user *yggdrasil.User) tokenpolicy.Lifetime {
+	expiration, refreshExpiration := h.tokenExpiration()
+	return h.policies.ForAuthenticate(tokenpolicy.Request{
+		Agent:               &tokenpolicy.Agent{Name: req.Agent.Name, Version: req.Agent.Version},
+		ClientTokenProvided: req.ClientToken != "",
+		Role:                user.Role(),
+	}, expiration, refreshExpiration)
+}
+
+// refreshLifetime 根据令牌策略获取刷新签发的令牌有效期（不续期的策略不超过旧令牌的可刷新期限）
+// 旧令牌已没有剩余的可刷新期限时ok为false
+func (h *AuthHandler) refreshLifetime(token *yggdrasil.Token, user *yggdrasil.User) (lifetime tokenpolicy.Lifetime, ok bool) {
+	// 沿用旧令牌签发时的策略（非JWT令牌没有策略信息，重新匹配）
+	var policy string
+	if claims, err := utils.ValidateJWT(token.AccessToken); err == nil {
+		policy = claims.Policy
+	}
+
+	expiration, refreshExpiration := h.tokenExpiration()
+	lifetime = h.policies.ForRefresh(policy, tokenpolicy.Request{Role: user.Role()}, expiration, refreshExpiration)
+	if !lifetime.Sliding {
+		// RefreshExpiresAt为零值的旧令牌以ExpiresAt为可刷新期限
+		remaining := time.Until(token.RefreshDeadline())
+		if remaining <= 0 {
+			return lifetime, false
+		}
+		lifetime.RefreshExpiration = min(lifetime.RefreshExpiration, remaining)
+		lifetime.Expiration = min(lifetime.Expiration, lifetime.RefreshExpiration)
+	}
+	return lifetime, true
+}
+
+// newToken 签发新令牌（有效期内可正常使用，之后至可刷新期限前只能用于刷新）
+func (h *AuthHandler) newToken(userID, profileID, clientToken string, lifetime tokenpolicy.Lifetime) (*yggdrasil.Token, error) {
+	accessToken, err := utils.GenerateJWTWithPolicy(userID, profileID, lifetime.Policy, lifetime.Expiration, lifetime.RefreshExpiration)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return &yggdrasil.Token{
+		AccessToken:      accessToken,
+		ClientToken:      clientToken,
+		ProfileID:        profileID,
+		Owner:            userID, // 使用用户ID而不是邮箱
+		CreatedAt:        now,
+		ExpiresAt:        now.Add(lifetime.Expiration),
+		RefreshExpiresAt: now.Add(lifetime.RefreshExpiration),
+	}, nil
+}
+
 // Authenticate 用户登录认证
 func (h *AuthHandler) Authenticate(c *gin.Context) {
 	var req yggdrasil.AuthenticateRequest
@@ -42,10 +124,9 @@ func (h *AuthHandler) Authenticate(c *gin.Context) {
 		return
 	}
 
-	// 直接使用 AuthenticateUser 方法（已包含密码验证和单查询优化）
-	user, err := h.storage.AuthenticateUser(req.Username, req.Password)
-	if err != nil {
-		utils.RespondInvalidCredentials(c)
+	// 验证用户凭据（已启用两步验证的用户需在密码后附加验证码）
+	user, ok := h.credentials.authenticate(c, req.Username, req.Password)
+	if !ok {
 		return
 	}
 
@@ -69,10 +150,10 @@ func (h *AuthHandler) Authenticate(c *gin.Context) {
 		profileID = selectedProfile.ID
 	}
 
-	// 如果通过角色名登录，自动选择对应角色
+	// 如果通过角色名登录，自动选择对应角色（角色名不区分大小写）
 	if !strings.Contains(req.Username, "@") {
 		for i := range availableProfiles {
-			if availableProfiles[i].Name == req.Username {
+			if strings.EqualFold(availableProfiles[i].Name, req.Username) {
 				selectedProfile = &availableProfiles[i]
 				profileID = selectedProfile.ID
 				break
@@ -80,31 +161,22 @@ func (h *AuthHandler) Authenticate(c *gin.Context) {
 		}
 	}
 
-	// 生成访问令牌（使用配置中的过期时间）
-	accessToken, err := utils.GenerateJWT(user.ID, profileID, 3*24*time.Hour) // 使用默认3天有效期
+	// 生成访问令牌（有效期由令牌策略决定）
+	token, err := h.newToken(user.ID, profileID, clientToken, h.authenticateLifetime(&req, user))
 	if err != nil {
 		utils.RespondError(c, 500, "InternalServerError", "Failed to generate token")
 		return
 	}
 
-	// 存储令牌
-	token := &yggdrasil.Token{
-		AccessToken: accessToken,
-		ClientToken: clientToken,
-		ProfileID:   profileID,
-		Owner:       user.ID, // 使用用户ID而不是邮箱
-		CreatedAt:   time.Now(),
-		ExpiresAt:   time.Now().Add(3 * 24 * time.Hour),
-	}
-
-	if err := h.tokenCache.Store(token); err != nil {
+	// 存储令牌，超出数量限制时撤销最旧的令牌
+	if _, err := h.tokenCache.StoreWithLimit(token, h.tokensLimit()); err != nil {
 		utils.RespondError(c, 500, "InternalServerError", "Failed to store token")
 		return
 	}
 
 	// 构建响应
 	response := yggdrasil.AuthenticateResponse{
-		AccessToken:       accessToken,
+		AccessToken:       token.AccessToken,
 		ClientToken:       clientToken,
 		AvailableProfiles: availableProfiles,
 		SelectedProfile:   selectedProfile,
@@ -112,10 +184,7 @@ func (h *AuthHandler) Authenticate(c *gin.Context) {
 
 	// 如果请求用户信息
 	if req.RequestUser {
-		response.User = &yggdrasil.UserInfo{
-			ID:         user.ID,
-			Properties: []yggdrasil.ProfileProperty{},
-		}
+		response.User = userInfo(h.storage, user.ID)
 	}
 
 	utils.RespondJSONFast(c, response)
@@ -129,9 +198,9 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 		return
 	}
 
-	// 获取并验证令牌
+	// 获取并验证令牌（暂时失效的令牌仍可刷新）
 	token, err := h.tokenCache.Get(req.AccessToken)
-	if err != nil || !token.IsValid() {
+	if err != nil || !token.IsRefreshable() {
 		utils.RespondInvalidToken(c)
 		return
 	}
@@ -143,85 +212,101 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 	}
 
 	// 获取用户信息
-	user, err := h.storage.GetUserByID(token.Owner)
+	user, err := getUserByID(h.storage, token.Owner)
 	if err != nil {
 		utils.RespondForbiddenOperation(c, utils.MsgUserNotExisted)
 		return
 	}
 
-	// 删除旧令牌
-	h.tokenCache.Delete(req.AccessToken)
-
 	// 确定新令牌的角色绑定
 	profileID := token.ProfileID
 	var selectedProfile *yggdrasil.Profile
 
-	// 如果请求中指定了角色选择
 	if req.SelectedProfile != nil {
-		// 验证角色是否属于用户
-		found := false
-		for _, profile := range user.Profiles {
-			if profile.ID == req.SelectedProfile.ID {
-				selectedProfile = req.SelectedProfile
-				profileID = req.SelectedProfile.ID
-				found = true
-				break
-			}
+		// 选择角色（规范只允许未绑定角色的令牌选择角色）
+		if token.ProfileID != "" && !h.config.Auth.ProfileReselection {
+			utils.RespondIllegalArgument(c, utils.MsgTokenAlreadyHasProfile)
+			return
 		}
-		if !found {
-			utils.RespondForbiddenOperation(c, "Selected profile does not belong to user")
+		profile, ok := selectProfile(c, user, req.SelectedProfile)
+		if !ok {
 			return
 		}
+		selectedProfile = profile
+		profileID = profile.ID
 	} else if profileID != "" {
 		// 保持原有的角色绑定
-		for _, profile := range user.Profiles {
-			if profile.ID == profileID {
-				selectedProfile = &profile
+		for i := range user.Profiles {
+			if user.Profiles[i].ID == profileID {
+				selectedProfile = &user.Profiles[i]
 				break
 			}
 		}
 	}
 
+	// 新令牌的有效期（不续期且已没有剩余可刷新期限时拒绝）
+	lifetime, ok := h.refreshLifetime(token, user)
+	if !ok {
+		utils.RespondInvalidToken(c)
+		return
+	}
+
+	// 删除旧令牌（请求被拒绝时旧令牌仍然有效）
+	h.tokenCache.Delete(req.AccessToken)
+
 	// 生成新的访问令牌
-	newAccessToken, err := utils.GenerateJWT(user.ID, profileID, 3*24*time.Hour)
+	newToken, err := h.newToken(user.ID, profileID, token.ClientToken, lifetime)
 	if err != nil {
 		utils.RespondError(c, 500, "InternalServerError", "Failed to generate token")
 		return
 	}
 
-	// 存储新令牌
-	newToken := &yggdrasil.Token{
-		AccessToken: newAccessToken,
-		ClientToken: token.ClientToken,
-		ProfileID:   profileID,
-		Owner:       user.ID, // 使用用户ID而不是邮箱
-		CreatedAt:   time.Now(),
-		ExpiresAt:   time.Now().Add(3 * 24 * time.Hour),
-	}
-
-	if err := h.tokenCache.Store(newToken); err != nil {
+	// 存储新令牌（超出数量限制时撤销最旧的令牌）
+	if _, err := h.tokenCache.StoreWithLimit(newToken, h.tokensLimit()); err != nil {
 		utils.RespondError(c, 500, "InternalServerError", "Failed to store token")
 		return
 	}
 
 	// 构建响应
 	response := yggdrasil.RefreshResponse{
-		AccessToken:     newAccessToken,
+		AccessToken:     newToken.AccessToken,
 		ClientToken:     token.ClientToken,
 		SelectedProfile: selectedProfile,
 	}
 
 	// 如果请求用户信息
 	if req.RequestUser {
-		response.User = &yggdrasil.UserInfo{
-			ID:         user.ID,
-			Properties: []yggdrasil.ProfileProperty{},
-		}
+		response.User = userInfo(h.storage, user.ID)
 	}
 
 	utils.RespondJSONFast(c, response)
 }
 
+// selectProfile 查找刷新时选择的角色，返回服务端的角色信息而不是客户端提交的，失败时直接写入错误响应
+// 角色ID必须属于用户，角色名称必须与ID一致（不区分大小写）
+func selectProfile(c *gin.Context, user *yggdrasil.User, selected *yggdrasil.Profile) (*yggdrasil.Profile, bool) {
+	if selected.ID == "" || selected.Name == "" {
+		utils.RespondIllegalArgument(c, utils.MsgInvalidSelectedProfile)
+		return nil, false
+	}
+
+	id := utils.RemoveUUIDHyphens(selected.ID)
+	for i := range user.Profiles {
+		profile := &user.Profiles[i]
+		if !strings.EqualFold(profile.ID, id) {
+			continue
+		}
+		if !strings.EqualFold(profile.Name, selected.Name) {
+			utils.RespondIllegalArgument(c, utils.MsgInvalidSelectedProfile)
+			return nil, false
+		}
+		return profile, true
+	}
+
+	utils.RespondForbiddenOperation(c, utils.MsgProfileNotOwned)
+	return nil, false
+}
+
 // Validate 验证令牌
 func (h *AuthHandler) Validate(c *gin.Context) {
 	var req yggdrasil.ValidateRequest
@@ -268,10 +353,9 @@ func (h *AuthHandler) Signout(c *gin.Context) {
 		return
 	}
 
-	// 验证用户凭据（使用统一的认证方法）
-	user, err := h.storage.AuthenticateUser(req.Username, req.Password)
-	if err != nil {
-		utils.RespondInvalidCredentials(c)
+	// 验证用户凭据（使用统一的认证方法，包括两步验证）
+	user, ok := h.credentials.authenticate(c, req.Username, req.Password)
+	if !ok {
 		return
 	}
 
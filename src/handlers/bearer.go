@@ -0,0 +1,38 @@
+// Package handlers Bearer令牌认证
+package handlers
+
+import (
+	"strings"
+
+	"yggdrasil-api-go/src/cache"
+	storage "yggdrasil-api-go/src/storage/interface"
+	"yggdrasil-api-go/src/utils"
+	"yggdrasil-api-go/src/yggdrasil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// authenticateBearer 从Authorization头解析并验证访问令牌，失败时直接写入401响应
+func authenticateBearer(c *gin.Context, tokenCache cache.TokenCache) (*yggdrasil.Token, bool) {
+	header := c.GetHeader("Authorization")
+	accessToken, found := strings.CutPrefix(header, "Bearer ")
+	if !found || accessToken == "" {
+		utils.RespondUnauthorized(c, utils.MsgInvalidToken)
+		return nil, false
+	}
+
+	token, err := tokenCache.Get(strings.TrimSpace(accessToken))
+	if err != nil || !token.IsValid() {
+		utils.RespondUnauthorized(c, utils.MsgInvalidToken)
+		return nil, false
+	}
+
+	return token, true
+}
+
+// getUserByID 按ID查询用户（经过用户缓存，密码、封禁或角色变更时缓存失效）
+func getUserByID(store storage.Storage, userID string) (*yggdrasil.User, error) {
+	return cache.CachedUserLookup(cache.UserIDCacheKey(userID), func() (*yggdrasil.User, error) {
+		return store.GetUserByID(userID)
+	})
+}
@@ -0,0 +1,96 @@
+// Package handlers 用户名密码认证
+package handlers
+
+import (
+	"errors"
+	"math"
+	"strconv"
+
+	"yggdrasil-api-go/src/lockout"
+	storage "yggdrasil-api-go/src/storage/interface"
+	"yggdrasil-api-go/src/twofactor"
+	"yggdrasil-api-go/src/utils"
+	"yggdrasil-api-go/src/yggdrasil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// credentials 用户名密码认证（检查登录失败锁定和两步验证），认证处理器和两步验证处理器共用
+type credentials struct {
+	storage   storage.Storage
+	twoFactor *twofactor.Service // 两步验证（未启用时为nil）
+	lockout   *lockout.Guard     // 登录失败锁定（未启用时为nil）
+}
+
+// lockoutKey 解析用户名对应的失败记录键（每个请求解析一次，未启用锁定时为空）
+func (cr *credentials) lockoutKey(username string) string {
+	if cr.lockout == nil {
+		return ""
+	}
+	return cr.lockout.Key(username)
+}
+
+// authenticate 验证用户名、密码和两步验证码（验证码以"密码:验证码"的形式附加在密码后），失败时直接写入错误响应
+// 全部验证通过后才清除失败记录
+func (cr *credentials) authenticate(c *gin.Context, username, password string) (*yggdrasil.User, bool) {
+	key := cr.lockoutKey(username)
+	user, code, ok := cr.checkPassword(c, key, username, password)
+	if !ok {
+		return nil, false
+	}
+
+	if cr.twoFactor != nil {
+		if err := cr.twoFactor.Verify(user, code); err != nil {
+			cr.codeFailed(key, err)
+			respondTwoFactorError(c, err)
+			return nil, false
+		}
+	}
+
+	cr.succeeded(key)
+	return user, true
+}
+
+// checkPassword 检查登录失败锁定并验证用户名和密码，返回用户和附加在密码后的验证码，失败时直接写入错误响应
+// key为lockoutKey解析的失败记录键；只验证了密码，不清除失败记录（账户启用两步验证时还需验证验证码）
+func (cr *credentials) checkPassword(c *gin.Context, key, username, password string) (*yggdrasil.User, string, bool) {
+	// 锁定检查在验证密码之前，账户不存在时响应相同
+	if cr.lockout != nil {
+		if remaining := cr.lockout.Check(key); remaining > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(remaining.Seconds()))))
+			utils.RespondForbiddenOperation(c, utils.MsgAccountLocked)
+			return nil, "", false
+		}
+	}
+
+	var user *yggdrasil.User
+	var code string
+	var err error
+	if cr.twoFactor != nil {
+		user, code, err = twofactor.Authenticate(cr.storage, username, password)
+	} else {
+		user, err = cr.storage.AuthenticateUser(username, password)
+	}
+	if err != nil {
+		if cr.lockout != nil {
+			cr.lockout.RecordFailure(key)
+		}
+		utils.RespondInvalidCredentials(c)
+		return nil, "", false
+	}
+	return user, code, true
+}
+
+// codeFailed 两步验证失败时记录登录失败（只有验证码错误计入，缺少验证码等不计入）
+func (cr *credentials) codeFailed(key string, err error) {
+	if cr.lockout != nil && errors.Is(err, twofactor.ErrInvalidCode) {
+		cr.lockout.RecordFailure(key)
+	}
+}
+
+// succeeded 认证成功后清除失败记录
+func (cr *credentials) succeeded(key string) {
+	if cr.lockout != nil {
+		cr.lockout.Reset(key)
+	}
+}
@@ -29,17 +29,19 @@ func NewMetaHandler(storage storage.Storage, cfg *config.Config) *MetaHandler {
 
 // GetAPIMetadata 获取API元数据（启用响应缓存）
 func (h *MetaHandler) GetAPIMetadata(c *gin.Context) {
-	// 尝试从缓存获取响应
-	cacheKey := "api_metadata_" + c.Request.Host
-	if cached, exists := utils.GetCachedResponse(cacheKey); exists {
-		c.Data(200, "application/json", cached)
-		return
-	}
-
 	// 获取请求的Host头
-	host := c.GetHeader("Host")
-	if host == "" {
-		host = c.Request.Host
+	host := c.Request.Host
+
+	// 尝试从缓存获取响应（Host由客户端控制，只缓存格式有效的Host，且数量有限）
+	cacheHost, cacheable := utils.APIMetadataCacheHost(h.config, host)
+	if cacheable {
+		if cached, exists := utils.GetCachedMetadataResponse(cacheHost); exists {
+			c.Data(200, "application/json", cached)
+			return
+		}
+		if cacheHost != "" {
+			host = cacheHost
+		}
 	}
 
 	// 动态生成链接
@@ -77,8 +79,9 @@ func (h *MetaHandler) GetAPIMetadata(c *gin.Context) {
 
 	// 使用高性能JSON响应并缓存结果
 	if jsonData, err := utils.FastMarshal(metadata); err == nil {
-		// 缓存响应（5分钟）
-		utils.SetCachedResponse(cacheKey, jsonData)
+		if cacheable {
+			utils.SetCachedMetadataResponse(cacheHost, jsonData)
+		}
 		c.Data(200, "application/json", jsonData)
 	} else {
 		// 降级到标准JSON
@@ -150,6 +153,18 @@ func (h *MetaHandler) loadSignatureKeyPair() (privateKey string, publicKey strin
 	return privateKey, publicKey, nil
 }
 
+// ResetKeyPairCache 清除缓存的密钥对（签名密钥变更后调用）
+func ResetKeyPairCache() {
+	keyPairMutex.Lock()
+	defer keyPairMutex.Unlock()
+
+	cachedPrivateKey = ""
+	cachedPublicKey = ""
+	cachedRSAPrivateKey = nil
+	cachedRSAPublicKey = nil
+	keyPairCached = false
+}
+
 // GetCachedRSAKeyPair 获取缓存的RSA密钥对（高性能版本）
 func GetCachedRSAKeyPair() (privateKey *rsa.PrivateKey, publicKey *rsa.PublicKey, err error) {
 	keyPairMutex.RLock()
@@ -1,27 +1,34 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
+	"yggdrasil-api-go/src/cache"
 	"yggdrasil-api-go/src/config"
 	storage "yggdrasil-api-go/src/storage/interface"
 	"yggdrasil-api-go/src/utils"
+	"yggdrasil-api-go/src/yggdrasil"
 
 	"github.com/gin-gonic/gin"
 )
 
 // ProfileHandler 角色处理器
 type ProfileHandler struct {
-	storage storage.Storage
-	config  *config.Config
+	storage    storage.Storage
+	tokenCache cache.TokenCache
+	config     *config.Config
 }
 
 // NewProfileHandler 创建新的角色处理器
-func NewProfileHandler(storage storage.Storage, cfg *config.Config) *ProfileHandler {
+func NewProfileHandler(storage storage.Storage, tokenCache cache.TokenCache, cfg *config.Config) *ProfileHandler {
 	return &ProfileHandler{
-		storage: storage,
-		config:  cfg,
+		storage:    storage,
+		tokenCache: tokenCache,
+		config:     cfg,
 	}
 }
 
@@ -41,6 +48,16 @@ func (h *ProfileHandler) GetProfileByUUID(c *gin.Context) {
 		}
 	}
 
+	// 尝试从响应缓存获取（只缓存存在的角色，材质或名称变更时按角色失效）
+	cacheKey := fmt.Sprintf("profile:%s:unsigned=%t", strings.ToLower(utils.RemoveUUIDHyphens(uuid)), unsigned)
+	cacheable := utils.ProfileResponsesCached()
+	if cacheable {
+		if cached, exists := utils.GetCachedResponse(cacheKey); exists {
+			c.Data(200, "application/json", cached)
+			return
+		}
+	}
+
 	// 获取角色信息
 	profile, err := h.storage.GetProfileByUUID(uuid)
 	if err != nil {
@@ -71,9 +88,25 @@ func (h *ProfileHandler) GetProfileByUUID(c *gin.Context) {
 		}
 	}
 
+	if cacheable {
+		respondCachedJSON(c, cacheKey, profile, utils.ProfileResponseTag(profile.ID))
+		return
+	}
 	utils.RespondJSONFast(c, profile)
 }
 
+// respondCachedJSON 序列化并返回响应，同时写入响应缓存
+func respondCachedJSON(c *gin.Context, cacheKey string, data any, tags ...string) {
+	jsonData, err := utils.FastMarshal(data)
+	if err != nil {
+		// 降级到标准JSON
+		utils.RespondJSON(c, data)
+		return
+	}
+	utils.SetCachedResponse(cacheKey, jsonData, tags...)
+	c.Data(200, "application/json", jsonData)
+}
+
 // generateSignature 生成属性值的数字签名（高性能版本）
 func (h *ProfileHandler) generateSignature(value string) (string, error) {
 	// 尝试获取缓存的RSA密钥对
@@ -148,8 +181,31 @@ func (h *ProfileHandler) SearchSingleProfile(c *gin.Context) {
 		return
 	}
 
-	// 获取角色信息
-	profile, err := h.storage.GetProfileByName(username)
+	// 获取角色信息（指定at参数时按该时间点使用的名称查询）
+	var profile *yggdrasil.Profile
+	var err error
+	atParam := c.Query("at")
+
+	// 当前名称的查询结果可以缓存（改名时按角色失效）
+	cacheKey := "name:" + strings.ToLower(username)
+	cacheable := atParam == "" && utils.ProfileResponsesCached()
+	if cacheable {
+		if cached, exists := utils.GetCachedResponse(cacheKey); exists {
+			c.Data(200, "application/json", cached)
+			return
+		}
+	}
+
+	if atParam != "" {
+		timestamp, parseErr := strconv.ParseInt(atParam, 10, 64)
+		if parseErr != nil {
+			utils.RespondIllegalArgument(c, "Invalid timestamp")
+			return
+		}
+		profile, err = h.storage.GetProfileByNameAt(username, time.Unix(timestamp, 0))
+	} else {
+		profile, err = h.storage.GetProfileByName(username)
+	}
 	if err != nil {
 		// 角色不存在，返回204
 		utils.RespondNoContent(c)
@@ -162,5 +218,101 @@ func (h *ProfileHandler) SearchSingleProfile(c *gin.Context) {
 		"name": profile.Name,
 	}
 
+	if cacheable {
+		respondCachedJSON(c, cacheKey, result, utils.ProfileResponseTag(profile.ID), utils.ProfileNameResponseTag(profile.Name))
+		return
+	}
 	utils.RespondJSONFast(c, result)
 }
+
+// GetNameHistory 获取角色名称历史
+func (h *ProfileHandler) GetNameHistory(c *gin.Context) {
+	// 角色ID为无符号小写UUID，同时接受带连字符的格式
+	uuid := strings.ToLower(utils.RemoveUUIDHyphens(c.Param("uuid")))
+	if !utils.ValidateUUIDInput(uuid) {
+		utils.RespondIllegalArgument(c, "Invalid UUID format")
+		return
+	}
+
+	history, err := h.storage.GetProfileNameHistory(uuid)
+	if err != nil {
+		// 角色不存在，返回204
+		utils.RespondNoContent(c)
+		return
+	}
+
+	utils.RespondJSONFast(c, history)
+}
+
+// RenameProfile 角色改名（需要Bearer令牌，保持UUID不变）
+func (h *ProfileHandler) RenameProfile(c *gin.Context) {
+	if !h.config.Profile.RenameEnabled {
+		utils.RespondForbiddenOperation(c, utils.MsgRenameDisabled)
+		return
+	}
+
+	token, ok := authenticateBearer(c, h.tokenCache)
+	if !ok {
+		return
+	}
+
+	// 角色ID为无符号小写UUID，同时接受带连字符的格式
+	uuid := strings.ToLower(utils.RemoveUUIDHyphens(c.Param("uuid")))
+	if !utils.ValidateUUIDInput(uuid) {
+		utils.RespondIllegalArgument(c, "Invalid UUID format")
+		return
+	}
+
+	var req yggdrasil.RenameProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondIllegalArgument(c, "Invalid request format")
+		return
+	}
+
+	// 验证角色名格式
+	if !utils.ValidatePlayerNameInput(req.Name) {
+		utils.RespondIllegalArgument(c, utils.MsgInvalidPlayerName)
+		return
+	}
+
+	// 验证角色是否属于令牌所有者
+	user, err := getUserByID(h.storage, token.Owner)
+	if err != nil {
+		utils.RespondForbiddenOperation(c, utils.MsgUserNotExisted)
+		return
+	}
+
+	owned := false
+	for _, profile := range user.Profiles {
+		if profile.ID == uuid {
+			owned = true
+			break
+		}
+	}
+	if !owned {
+		utils.RespondForbiddenOperation(c, utils.MsgProfileNotOwned)
+		return
+	}
+
+	// 执行改名（冷却时间由存储在改名事务内检查）
+	if err := h.storage.RenameProfile(uuid, req.Name, h.config.Profile.RenameCooldown); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrRenameCooldown):
+			utils.RespondForbiddenOperation(c, utils.MsgRenameCooldown)
+		case errors.Is(err, storage.ErrProfileNameExists):
+			utils.RespondForbiddenOperation(c, utils.MsgPlayerNameExists)
+		case errors.Is(err, storage.ErrProfileNotFound):
+			utils.RespondForbiddenOperation(c, utils.MsgPlayerNotExisted)
+		default:
+			utils.RespondError(c, 500, "InternalServerError", "Failed to rename profile")
+		}
+		return
+	}
+	utils.InvalidateCachedResponses(utils.ProfileResponseTag(uuid), utils.ProfileNameResponseTag(req.Name))
+	cache.GlobalUserCache.DeleteUser(user.ID)
+
+	utils.RespondJSONFast(c, map[string]string{
+		"id":   uuid,
+		"name": req.Name,
+	})
+}
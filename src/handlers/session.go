@@ -2,9 +2,12 @@ package handlers
 
 import (
 	"fmt"
+	"log"
 	"time"
+	"unicode/utf8"
 
 	"yggdrasil-api-go/src/cache"
+	"yggdrasil-api-go/src/cache/revocation"
 	"yggdrasil-api-go/src/config"
 	storage "yggdrasil-api-go/src/storage/interface"
 	"yggdrasil-api-go/src/utils"
@@ -19,15 +22,23 @@ type SessionHandler struct {
 	tokenCache   cache.TokenCache
 	sessionCache cache.SessionCache
 	config       *config.Config
+	revoked      *revocation.Set // 已撤销的令牌（由Token缓存的撤销事件维护）
+	lookupTokens bool            // 进入服务器时查询Token缓存（令牌可能由其他程序签发或删除）
 }
 
 // NewSessionHandler 创建新的会话处理器
 func NewSessionHandler(storage storage.Storage, tokenCache cache.TokenCache, sessionCache cache.SessionCache, cfg *config.Config) *SessionHandler {
+	revoked := revocation.NewSet()
+	tokenCache.SubscribeRevocations(revoked.Add)
+	managed, ok := tokenCache.(cache.ExternallyManaged)
+
 	return &SessionHandler{
 		storage:      storage,
 		tokenCache:   tokenCache,
 		sessionCache: sessionCache,
 		config:       cfg,
+		revoked:      revoked,
+		lookupTokens: ok && managed.ExternallyManaged(),
 	}
 }
 
@@ -39,25 +50,49 @@ func (h *SessionHandler) Join(c *gin.Context) {
 		return
 	}
 
-	// 第一步：验证JWT（本地计算，极快）
-	claims, err := utils.ValidateJWT(req.AccessToken)
-	if err != nil {
-		utils.RespondInvalidToken(c)
-		return
+	var profileID, tokenID string
+	if h.lookupTokens {
+		// 令牌可能由其他程序签发（非JWT）或删除，以Token缓存为准
+		token, err := h.tokenCache.Get(req.AccessToken)
+		if err != nil || !token.IsValid() {
+			utils.RespondInvalidToken(c)
+			return
+		}
+		profileID = token.ProfileID
+		if claims, err := utils.ValidateJWT(req.AccessToken); err == nil {
+			tokenID = claims.TokenID
+		}
+	} else {
+		// 第一步：验证JWT（本地计算，极快），暂时失效的令牌不能用于进入服务器
+		claims, err := utils.ValidateJWT(req.AccessToken)
+		if err != nil || !claims.IsValid() {
+			utils.RespondInvalidToken(c)
+			return
+		}
+
+		// 第二步：检查令牌是否已被撤销（invalidate、signout、refresh或数量限制淘汰，进程内查询）
+		if h.revoked.Contains(claims.TokenID) {
+			utils.RespondInvalidToken(c)
+			return
+		}
+		profileID = claims.ProfileID
+		tokenID = claims.TokenID
 	}
 
-	// 第二步：验证选中的角色是否与JWT中的角色一致
-	if claims.ProfileID == "" || claims.ProfileID != req.SelectedProfile {
+	// 第三步：验证选中的角色是否与令牌中的角色一致
+	if profileID == "" || profileID != req.SelectedProfile {
 		utils.RespondForbiddenOperation(c, "Selected profile does not match token")
 		return
 	}
 
-	// 创建会话记录（使用JWT中的信息，无需查询数据库）
+	// 创建会话记录（使用令牌中的信息，无需查询数据库）
 	session := &yggdrasil.Session{
 		ServerID:    req.ServerID,
 		AccessToken: req.AccessToken, // session缓存会从中提取用户信息
-		ProfileID:   claims.ProfileID,
+		TokenID:     tokenID,
+		ProfileID:   profileID,
 		ClientIP:    c.ClientIP(),
+		UserAgent:   truncateUserAgent(c.Request.UserAgent()),
 		CreatedAt:   time.Now(),
 	}
 
@@ -67,6 +102,11 @@ func (h *SessionHandler) Join(c *gin.Context) {
 		return
 	}
 
+	if h.config.Auth.SessionAuditLog {
+		log.Printf("🎮 join: server=%s profile=%s token=%s ip=%s ua=%q",
+			req.ServerID, profileID, tokenID, session.ClientIP, session.UserAgent)
+	}
+
 	utils.RespondNoContent(c)
 }
 
@@ -89,7 +129,15 @@ func (h *SessionHandler) HasJoined(c *gin.Context) {
 		return
 	}
 
-	// 通过用户名获取角色信息
+	// 进入服务器之后令牌被撤销（登出、刷新或数量限制淘汰）的会话不再有效
+	if session.TokenID != "" && h.revoked.Contains(session.TokenID) {
+		h.sessionCache.Delete(serverID)
+		h.auditHasJoined(c, session, "token revoked")
+		utils.RespondNoContent(c)
+		return
+	}
+
+	// 通过用户名获取角色信息（不区分大小写，以UUID与会话比对）
 	profile, err := h.storage.GetProfileByName(username)
 	if err != nil {
 		utils.RespondNoContent(c)
@@ -104,12 +152,14 @@ func (h *SessionHandler) HasJoined(c *gin.Context) {
 
 	// 如果提供了IP参数，验证IP是否匹配
 	if clientIP != "" && session.ClientIP != clientIP {
+		h.auditHasJoined(c, session, "ip mismatch "+clientIP)
 		utils.RespondNoContent(c)
 		return
 	}
 
 	// 验证成功，删除会话（一次性使用）
 	h.sessionCache.Delete(serverID)
+	h.auditHasJoined(c, session, "ok")
 
 	// 为角色属性生成数字签名（根据Yggdrasil规范要求）
 	for i := range profile.Properties {
@@ -128,6 +178,28 @@ func (h *SessionHandler) HasJoined(c *gin.Context) {
 	utils.RespondJSON(c, profile)
 }
 
+// auditHasJoined 记录hasJoined验证结果，与join记录按服务器ID和令牌ID配对（未启用审计日志时不记录）
+func (h *SessionHandler) auditHasJoined(c *gin.Context, session *yggdrasil.Session, result string) {
+	if !h.config.Auth.SessionAuditLog {
+		return
+	}
+	log.Printf("🎮 hasJoined: server=%s profile=%s token=%s ip=%s ua=%q result=%s by=%s after=%v",
+		session.ServerID, session.ProfileID, session.TokenID, session.ClientIP, session.UserAgent,
+		result, c.ClientIP(), time.Since(session.CreatedAt).Round(time.Millisecond))
+}
+
+// truncateUserAgent 截断User-Agent（不截断UTF-8多字节字符）
+func truncateUserAgent(userAgent string) string {
+	if len(userAgent) <= yggdrasil.MaxUserAgentLength {
+		return userAgent
+	}
+	cut := yggdrasil.MaxUserAgentLength
+	for cut > 0 && !utf8.RuneStart(userAgent[cut]) {
+		cut--
+	}
+	return userAgent[:cut]
+}
+
 // generateSignature 生成属性值的数字签名（高性能版本）
 func (h *SessionHandler) generateSignature(value string) (string, error) {
 	// 尝试获取缓存的RSA密钥对
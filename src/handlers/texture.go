@@ -84,6 +84,7 @@ func (h *TextureHandler) UploadTexture(c *gin.Context) {
 		utils.RespondError(c, 500, "InternalServerError", fmt.Sprintf("Failed to upload texture: %v", err))
 		return
 	}
+	utils.InvalidateCachedResponses(utils.ProfileResponseTag(uuid))
 
 	// 返回成功响应
 	response := gin.H{
@@ -181,6 +182,7 @@ func (h *TextureHandler) uploadTexture(c *gin.Context, textureType storage.Textu
 		utils.RespondError(c, 500, "InternalServerError", fmt.Sprintf("Failed to upload texture: %v", err))
 		return
 	}
+	utils.InvalidateCachedResponses(utils.ProfileResponseTag(playerUUID))
 
 	// 返回成功响应
 	utils.RespondJSON(c, gin.H{
@@ -244,6 +246,7 @@ func (h *TextureHandler) DeleteTexture(c *gin.Context) {
 		utils.RespondError(c, 500, "InternalServerError", fmt.Sprintf("Failed to delete texture: %v", err))
 		return
 	}
+	utils.InvalidateCachedResponses(utils.ProfileResponseTag(playerUUID))
 
 	// 返回成功响应
 	utils.RespondJSON(c, gin.H{
@@ -0,0 +1,156 @@
+// Package handlers 两步验证处理器
+package handlers
+
+import (
+	"errors"
+
+	"yggdrasil-api-go/src/cache"
+	"yggdrasil-api-go/src/lockout"
+	storage "yggdrasil-api-go/src/storage/interface"
+	"yggdrasil-api-go/src/twofactor"
+	"yggdrasil-api-go/src/utils"
+	"yggdrasil-api-go/src/yggdrasil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TwoFactorHandler 两步验证处理器
+// 使用用户名和密码认证而不是Bearer令牌：必须启用两步验证的用户在登记前无法登录获取令牌
+type TwoFactorHandler struct {
+	tokenCache  cache.TokenCache
+	twoFactor   *twofactor.Service
+	credentials *credentials
+}
+
+// NewTwoFactorHandler 创建新的两步验证处理器（guard为nil时不检查登录失败锁定）
+func NewTwoFactorHandler(storage storage.Storage, tokenCache cache.TokenCache, twoFactor *twofactor.Service, guard *lockout.Guard) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		tokenCache:  tokenCache,
+		twoFactor:   twoFactor,
+		credentials: &credentials{storage: storage, twoFactor: twoFactor, lockout: guard},
+	}
+}
+
+// Enroll 登记两步验证，返回TOTP密钥（提交验证码确认后才会启用）
+func (h *TwoFactorHandler) Enroll(c *gin.Context) {
+	var req yggdrasil.TwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondIllegalArgument(c, "Invalid request format")
+		return
+	}
+
+	// 只验证了密码，不清除失败记录
+	user, _, ok := h.credentials.checkPassword(c, h.credentials.lockoutKey(req.Username), req.Username, req.Password)
+	if !ok {
+		return
+	}
+
+	enrollment, err := h.twoFactor.Enroll(user)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	utils.RespondJSONFast(c, yggdrasil.TwoFactorEnrollResponse{
+		Secret: enrollment.Secret,
+		URI:    enrollment.URI,
+	})
+}
+
+// Confirm 提交验证码确认登记，启用两步验证并返回恢复码（同时撤销用户现有的令牌）
+func (h *TwoFactorHandler) Confirm(c *gin.Context) {
+	var req yggdrasil.TwoFactorConfirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondIllegalArgument(c, "Invalid request format")
+		return
+	}
+
+	key := h.credentials.lockoutKey(req.Username)
+	user, code, ok := h.credentials.checkPassword(c, key, req.Username, req.Password)
+	if !ok {
+		return
+	}
+	if req.Code != "" {
+		code = req.Code
+	}
+	if code == "" {
+		utils.RespondForbiddenOperation(c, utils.MsgTwoFactorRequired)
+		return
+	}
+
+	recoveryCodes, err := h.twoFactor.Confirm(user, code)
+	if err != nil {
+		h.credentials.codeFailed(key, err)
+		respondTwoFactorError(c, err)
+		return
+	}
+	h.credentials.succeeded(key)
+
+	// 启用前签发的令牌未经两步验证，全部撤销
+	h.tokenCache.DeleteUserTokens(user.ID)
+	utils.RespondJSONFast(c, yggdrasil.TwoFactorRecoveryCodesResponse{RecoveryCodes: recoveryCodes})
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码（密码需附加验证码或未使用的恢复码）
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	user, ok := h.verify(c)
+	if !ok {
+		return
+	}
+
+	recoveryCodes, err := h.twoFactor.RegenerateRecoveryCodes(user)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	utils.RespondJSONFast(c, yggdrasil.TwoFactorRecoveryCodesResponse{RecoveryCodes: recoveryCodes})
+}
+
+// Disable 停用两步验证（密码需附加验证码或未使用的恢复码）
+func (h *TwoFactorHandler) Disable(c *gin.Context) {
+	user, ok := h.verify(c)
+	if !ok {
+		return
+	}
+
+	if err := h.twoFactor.Disable(user); err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	utils.RespondNoContent(c)
+}
+
+// verify 验证用户凭据和两步验证码，失败时直接写入错误响应
+func (h *TwoFactorHandler) verify(c *gin.Context) (*yggdrasil.User, bool) {
+	var req yggdrasil.TwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondIllegalArgument(c, "Invalid request format")
+		return nil, false
+	}
+
+	return h.credentials.authenticate(c, req.Username, req.Password)
+}
+
+// respondTwoFactorError 根据两步验证错误写入响应
+func respondTwoFactorError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, twofactor.ErrCodeRequired):
+		utils.RespondForbiddenOperation(c, utils.MsgTwoFactorRequired)
+	case errors.Is(err, twofactor.ErrInvalidCode):
+		utils.RespondForbiddenOperation(c, utils.MsgInvalidTwoFactorCode)
+	case errors.Is(err, twofactor.ErrEnrollmentRequired):
+		utils.RespondForbiddenOperation(c, utils.MsgTwoFactorEnrollmentRequired)
+	case errors.Is(err, twofactor.ErrNotEnrolled):
+		utils.RespondForbiddenOperation(c, utils.MsgTwoFactorNotEnrolled)
+	case errors.Is(err, twofactor.ErrAlreadyEnabled):
+		utils.RespondForbiddenOperation(c, utils.MsgTwoFactorAlreadyEnabled)
+	case errors.Is(err, twofactor.ErrDisableNotAllowed):
+		utils.RespondForbiddenOperation(c, utils.MsgTwoFactorDisableNotAllowed)
+	case errors.Is(err, storage.ErrUserNotFound):
+		utils.RespondForbiddenOperation(c, utils.MsgUserNotExisted)
+	default:
+		utils.RespondError(c, 500, "InternalServerError", "Two-factor authentication failed")
+	}
+}
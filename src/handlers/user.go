@@ -0,0 +1,91 @@
+// Package handlers 用户属性处理器
+package handlers
+
+import (
+	"errors"
+
+	"yggdrasil-api-go/src/cache"
+	storage "yggdrasil-api-go/src/storage/interface"
+	"yggdrasil-api-go/src/utils"
+	"yggdrasil-api-go/src/yggdrasil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserHandler 用户处理器
+type UserHandler struct {
+	storage    storage.Storage
+	tokenCache cache.TokenCache
+}
+
+// NewUserHandler 创建新的用户处理器
+func NewUserHandler(storage storage.Storage, tokenCache cache.TokenCache) *UserHandler {
+	return &UserHandler{
+		storage:    storage,
+		tokenCache: tokenCache,
+	}
+}
+
+// UpdateProperties 更新当前用户的属性（需要Bearer令牌，只允许修改preferredLanguage）
+func (h *UserHandler) UpdateProperties(c *gin.Context) {
+	token, ok := authenticateBearer(c, h.tokenCache)
+	if !ok {
+		return
+	}
+
+	propertiesStorage, ok := h.storage.(storage.UserPropertiesStorage)
+	if !ok {
+		utils.RespondError(c, 501, "NotImplemented", "User properties are not supported")
+		return
+	}
+
+	var req yggdrasil.UpdateUserPropertiesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondIllegalArgument(c, "Invalid request format")
+		return
+	}
+
+	// 验证属性名和属性值
+	for name, value := range req.Properties {
+		switch name {
+		case yggdrasil.UserPropertyPreferredLanguage:
+			if value != "" && !utils.IsValidLocale(value) {
+				utils.RespondIllegalArgument(c, utils.MsgInvalidUserProperty)
+				return
+			}
+		default:
+			utils.RespondForbiddenOperation(c, utils.MsgUserPropertyNotAllowed)
+			return
+		}
+	}
+
+	if err := propertiesStorage.UpdateUserProperties(token.Owner, req.Properties); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrUserNotFound):
+			utils.RespondForbiddenOperation(c, utils.MsgUserNotExisted)
+		case errors.Is(err, storage.ErrUserPropertyNotSupported):
+			utils.RespondForbiddenOperation(c, utils.MsgUserPropertyNotAllowed)
+		default:
+			utils.RespondError(c, 500, "InternalServerError", "Failed to update user properties")
+		}
+		return
+	}
+
+	utils.RespondJSONFast(c, userInfo(h.storage, token.Owner))
+}
+
+// userInfo 构建requestUser响应中的用户信息（存储不支持用户属性或查询失败时属性为空）
+func userInfo(store storage.Storage, userID string) *yggdrasil.UserInfo {
+	info := &yggdrasil.UserInfo{
+		ID:         userID,
+		Properties: []yggdrasil.ProfileProperty{},
+	}
+
+	if propertiesStorage, ok := store.(storage.UserPropertiesStorage); ok {
+		// 查询失败不影响登录或刷新，返回空属性
+		if properties, err := propertiesStorage.GetUserProperties(userID); err == nil {
+			info.Properties = properties
+		}
+	}
+	return info
+}
@@ -0,0 +1,151 @@
+// Package lockout 账户登录失败锁定（按账户记录失败次数，与客户端IP无关，更换IP无法绕过）
+package lockout
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"yggdrasil-api-go/src/cache"
+	"yggdrasil-api-go/src/config"
+	storage "yggdrasil-api-go/src/storage/interface"
+)
+
+// 默认配置（与LockoutConfig的说明一致）
+const (
+	defaultMaxFailures = 5
+	defaultDuration    = 1 * time.Minute
+	defaultMaxDuration = 1 * time.Hour
+	defaultResetAfter  = 24 * time.Hour
+)
+
+// Status 账户的登录失败状态
+type Status struct {
+	Key         string    // 失败记录的键（账户存在时为user:{用户ID}，否则为规范化的用户名）
+	Failures    int       // 连续失败次数
+	LastFailure time.Time // 最近一次失败时间（没有失败记录时为零值）
+	LockedUntil time.Time // 锁定截止时间（未锁定时为零值）
+}
+
+// Guard 登录失败锁定
+// 失败次数达到阈值后锁定，之后每次失败锁定时间翻倍（不超过最长锁定时间）；登录成功后清除记录
+type Guard struct {
+	attempts    cache.LoginAttemptCache
+	users       storage.UserStorage
+	maxFailures int
+	duration    time.Duration
+	maxDuration time.Duration
+	resetAfter  time.Duration
+}
+
+// New 创建登录失败锁定（未设置的项使用默认值），users用于将邮箱和角色名解析为同一账户
+func New(cfg config.LockoutConfig, attempts cache.LoginAttemptCache, users storage.UserStorage) *Guard {
+	g := &Guard{
+		attempts:    attempts,
+		users:       users,
+		maxFailures: cfg.MaxFailures,
+		duration:    cfg.Duration,
+		maxDuration: cfg.MaxDuration,
+		resetAfter:  cfg.ResetAfter,
+	}
+	if g.maxFailures == 0 {
+		g.maxFailures = defaultMaxFailures
+	}
+	if g.duration == 0 {
+		g.duration = defaultDuration
+	}
+	if g.maxDuration == 0 {
+		g.maxDuration = max(defaultMaxDuration, g.duration)
+	}
+	if g.resetAfter == 0 {
+		g.resetAfter = defaultResetAfter
+	}
+	return g
+}
+
+// normalize 规范化用户名（邮箱和角色名都不区分大小写），账户不存在时作为失败记录的键
+func normalize(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// Key 解析用户名对应的失败记录键（每个请求解析一次，传给Check、RecordFailure、Reset和Status）
+// 账户存在时按用户ID记录，邮箱和各个角色名共用同一失败次数；账户不存在时按规范化的用户名记录
+// 无论用户名是邮箱还是角色名、账户是否存在，都同样查询邮箱和角色名，避免通过响应时间判断账户是否存在
+func (g *Guard) Key(username string) string {
+	username = strings.TrimSpace(username)
+
+	byEmail, emailErr := g.users.GetUserByEmail(username)
+	byPlayer, playerErr := g.users.GetUserByPlayerName(username)
+
+	user, err := byPlayer, playerErr
+	if strings.Contains(username, "@") {
+		user, err = byEmail, emailErr
+	}
+	if err != nil || user == nil {
+		return normalize(username)
+	}
+	return "user:" + user.ID
+}
+
+// Check 获取失败记录键对应账户剩余的锁定时间（未锁定时为0）
+// 不存在的账户同样会被锁定，响应不会泄露账户是否存在；缓存出错时不锁定
+func (g *Guard) Check(key string) time.Duration {
+	attempts, err := g.attempts.Get(key)
+	if err != nil {
+		log.Printf("⚠️  Failed to get login attempts: %v", err)
+		return 0
+	}
+	if attempts == nil {
+		return 0
+	}
+	return max(time.Until(g.lockedUntil(attempts.Failures, attempts.LastFailure)), 0)
+}
+
+// RecordFailure 记录一次登录失败
+func (g *Guard) RecordFailure(key string) {
+	// 记录至少保留到最长锁定结束，锁定期间不会因过期而提前解除
+	if _, err := g.attempts.RecordFailure(key, max(g.resetAfter, g.maxDuration)); err != nil {
+		log.Printf("⚠️  Failed to record login failure: %v", err)
+	}
+}
+
+// Reset 清除失败记录（登录成功或管理员解除锁定）
+func (g *Guard) Reset(key string) error {
+	return g.attempts.Reset(key)
+}
+
+// Status 获取失败记录键对应账户的登录失败状态
+func (g *Guard) Status(key string) (*Status, error) {
+	attempts, err := g.attempts.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &Status{Key: key}
+	if attempts == nil {
+		return status, nil
+	}
+	status.Failures = attempts.Failures
+	status.LastFailure = attempts.LastFailure
+	if lockedUntil := g.lockedUntil(attempts.Failures, attempts.LastFailure); time.Now().Before(lockedUntil) {
+		status.LockedUntil = lockedUntil
+	}
+	return status, nil
+}
+
+// lockedUntil 计算锁定截止时间（失败次数未达到阈值时为零值）
+func (g *Guard) lockedUntil(failures int, lastFailure time.Time) time.Time {
+	if failures < g.maxFailures {
+		return time.Time{}
+	}
+	return lastFailure.Add(g.lockDuration(failures))
+}
+
+// lockDuration 计算锁定时间：达到阈值时为首次锁定时间，之后每次失败翻倍，不超过最长锁定时间
+func (g *Guard) lockDuration(failures int) time.Duration {
+	duration := g.duration
+	for i := g.maxFailures; i < failures && duration < g.maxDuration; i++ {
+		duration *= 2
+	}
+	return min(duration, g.maxDuration)
+}
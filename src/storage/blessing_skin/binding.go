@@ -0,0 +1,138 @@
+// Package blessing_skin 角色UUID绑定管理
+package blessing_skin
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// upsertPlayerBinding 写入或更新角色UUID绑定
+func upsertPlayerBinding(tx *gorm.DB, pid uint, uuid, name string) error {
+	binding := PlayerUUIDBinding{
+		PID:       pid,
+		UUID:      uuid,
+		Name:      name,
+		UpdatedAt: time.Now(),
+	}
+	return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&binding).Error
+}
+
+// bindPlayerIfUnbound 角色尚无绑定时记录pid到UUID的绑定（已有绑定时保持不变，交由找回或对账处理）
+func bindPlayerIfUnbound(tx *gorm.DB, pid uint, uuid, name string) error {
+	binding := PlayerUUIDBinding{
+		PID:       pid,
+		UUID:      uuid,
+		Name:      name,
+		UpdatedAt: time.Now(),
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&binding).Error
+}
+
+// createUUIDMappings 创建UUID映射，并在同一会话中为对应的角色记录UUID绑定
+// 之后角色在网站改名时可通过绑定找回原UUID
+func createUUIDMappings(tx *gorm.DB, mappings []UUIDMapping) error {
+	if len(mappings) == 0 {
+		return nil
+	}
+	if err := tx.Create(&mappings).Error; err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(mappings))
+	uuids := make(map[string]string, len(mappings))
+	for _, mapping := range mappings {
+		names = append(names, mapping.Name)
+		uuids[mapping.Name] = mapping.UUID
+	}
+
+	var players []Player
+	if err := tx.Select("pid, name").Where("name IN ?", names).Find(&players).Error; err != nil {
+		return err
+	}
+	for _, player := range players {
+		uuid, ok := uuids[player.Name]
+		if !ok {
+			continue
+		}
+		if err := bindPlayerIfUnbound(tx, player.PID, uuid, player.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rebindPlayerUUID 将角色名重新映射到绑定的UUID，并记录网站上发生的改名
+// 会删除该UUID的旧映射以及该角色名上被重新生成的映射
+func rebindPlayerUUID(tx *gorm.DB, player *Player, binding *PlayerUUIDBinding) error {
+	err := tx.Where("uuid = ? OR name = ?", binding.UUID, player.Name).Delete(&UUIDMapping{}).Error
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Create(&UUIDMapping{Name: player.Name, UUID: binding.UUID}).Error; err != nil {
+		return err
+	}
+
+	if binding.Name != player.Name {
+		// 以角色最后修改时间作为改名时间（缺失时使用当前时间）
+		changedAt := player.LastModified
+		if changedAt.IsZero() {
+			changedAt = time.Now()
+		}
+		if err := recordNameChange(tx, binding.UUID, binding.Name, player.Name, changedAt); err != nil {
+			return err
+		}
+	}
+
+	return upsertPlayerBinding(tx, player.PID, binding.UUID, player.Name)
+}
+
+// recoverBoundUUID 角色在网站改名后，根据pid绑定找回原UUID并迁移映射（避免生成新UUID）
+func (g *UUIDGenerator) recoverBoundUUID(playerName string) (string, bool, error) {
+	db := g.storage.db
+
+	var player Player
+	err := db.Select("pid, name, last_modified").Where("name = ?", playerName).First(&player).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+
+	var binding PlayerUUIDBinding
+	if err := db.Where("pid = ?", player.PID).First(&binding).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+
+	// 绑定的UUID已被其他在用角色占用时不迁移，交由对账任务处理
+	var inUse int64
+	err = db.Table("uuid u").
+		Joins("JOIN players p ON u.name = p.name").
+		Where("u.uuid = ? AND p.pid <> ?", binding.UUID, player.PID).
+		Count(&inUse).Error
+	if err != nil {
+		return "", false, err
+	}
+	if inUse > 0 {
+		return "", false, nil
+	}
+
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		return rebindPlayerUUID(tx, &player, &binding)
+	}); err != nil {
+		return "", false, fmt.Errorf("failed to recover bound UUID: %w", err)
+	}
+
+	g.cache.DeleteMapping(binding.Name, binding.UUID)
+	g.cache.PutMapping(player.Name, binding.UUID)
+
+	return binding.UUID, true, nil
+}
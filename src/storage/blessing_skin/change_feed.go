@@ -0,0 +1,366 @@
+// Package blessing_skin 数据变更检测（轮询BlessingSkin数据库并发出缓存失效事件）
+package blessing_skin
+
+import (
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	storage "yggdrasil-api-go/src/storage/interface"
+)
+
+// ChangeFeed 数据变更检测器
+// BlessingSkin网站直接修改数据库，通过轮询players.last_modified、textures新增行和options表检测变更
+type ChangeFeed struct {
+	storage  *Storage
+	interval time.Duration
+	handlers []func(storage.ChangeEvent)
+	mutex    sync.RWMutex
+
+	playerWatermark  time.Time       // 已处理的最大last_modified
+	playersAtMark    map[uint]bool   // 在水位时间点已处理的角色（last_modified精度为秒，避免同一秒内的变更遗漏）
+	textureWatermark uint            // 已处理的最大tid
+	userFingerprints map[uint]uint64 // 被检测用户的密码和权限指纹（users表没有修改时间，通过比对指纹检测变更）
+	watchUsers       func() []string // 需要检测变更的用户ID来源（用户缓存中的用户，为nil时不检测）
+
+	stop chan struct{}
+}
+
+// NewChangeFeed 创建数据变更检测器（以当前数据为基线，仅报告之后的变更）
+func NewChangeFeed(s *Storage, interval time.Duration) (*ChangeFeed, error) {
+	feed := &ChangeFeed{
+		storage:          s,
+		interval:         interval,
+		playersAtMark:    make(map[uint]bool),
+		userFingerprints: make(map[uint]uint64),
+	}
+
+	var latest Player
+	err := s.db.Select("pid, last_modified").Order("last_modified DESC").Limit(1).Find(&latest).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load players watermark: %w", err)
+	}
+	feed.playerWatermark = latest.LastModified
+
+	var pids []uint
+	err = s.db.Model(&Player{}).Where("last_modified = ?", feed.playerWatermark).Pluck("pid", &pids).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load players watermark: %w", err)
+	}
+	for _, pid := range pids {
+		feed.playersAtMark[pid] = true
+	}
+
+	err = s.db.Model(&Texture{}).Select("COALESCE(MAX(tid), 0)").Scan(&feed.textureWatermark).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load textures watermark: %w", err)
+	}
+
+	return feed, nil
+}
+
+// WatchUsers 设置需要检测密码、权限变更的用户ID来源
+func (f *ChangeFeed) WatchUsers(userIDs func() []string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.watchUsers = userIDs
+}
+
+// Subscribe 订阅变更事件
+func (f *ChangeFeed) Subscribe(handler func(storage.ChangeEvent)) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.handlers = append(f.handlers, handler)
+}
+
+// Start 启动轮询协程
+func (f *ChangeFeed) Start() {
+	f.stop = make(chan struct{})
+	go f.run()
+}
+
+// Stop 停止轮询协程
+func (f *ChangeFeed) Stop() {
+	if f.stop != nil {
+		close(f.stop)
+		f.stop = nil
+	}
+}
+
+// run 按间隔轮询数据库
+func (f *ChangeFeed) run() {
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	stop := f.stop
+	for {
+		select {
+		case <-ticker.C:
+			f.Poll()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Poll 执行一次变更检测并分发事件
+func (f *ChangeFeed) Poll() {
+	var events []storage.ChangeEvent
+
+	optionEvents, err := f.pollOptions()
+	if err != nil {
+		fmt.Printf("⚠️  Change feed: %v\n", err)
+	}
+	events = append(events, optionEvents...)
+
+	userEvents, err := f.pollUsers()
+	if err != nil {
+		fmt.Printf("⚠️  Change feed: %v\n", err)
+	}
+	events = append(events, userEvents...)
+
+	playerEvents, err := f.pollPlayers()
+	if err != nil {
+		fmt.Printf("⚠️  Change feed: %v\n", err)
+	}
+	events = append(events, playerEvents...)
+
+	textureEvents, err := f.pollTextures()
+	if err != nil {
+		fmt.Printf("⚠️  Change feed: %v\n", err)
+	}
+	events = append(events, textureEvents...)
+
+	if len(events) == 0 {
+		return
+	}
+
+	f.mutex.RLock()
+	handlers := slices.Clone(f.handlers)
+	f.mutex.RUnlock()
+
+	for _, event := range events {
+		for _, handler := range handlers {
+			handler(event)
+		}
+	}
+}
+
+// pollPlayers 检测角色变更（改名、更换皮肤或披风都会更新last_modified）
+func (f *ChangeFeed) pollPlayers() ([]storage.ChangeEvent, error) {
+	var players []Player
+	err := f.storage.db.Select("pid, uid, name, last_modified").
+		Where("last_modified >= ?", f.playerWatermark).
+		Order("last_modified ASC, pid ASC").
+		Find(&players).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to poll players: %w", err)
+	}
+
+	var events []storage.ChangeEvent
+	for i := range players {
+		player := &players[i]
+
+		if player.LastModified.After(f.playerWatermark) {
+			f.playerWatermark = player.LastModified
+			f.playersAtMark = make(map[uint]bool)
+		} else if f.playersAtMark[player.PID] {
+			continue
+		}
+		f.playersAtMark[player.PID] = true
+
+		events = append(events, storage.ChangeEvent{
+			Type:        storage.ChangeProfile,
+			ProfileID:   f.invalidatePlayer(player),
+			ProfileName: player.Name,
+			UserID:      strconv.Itoa(player.UID),
+			ChangedAt:   player.LastModified,
+		})
+	}
+
+	return events, nil
+}
+
+// pollUsers 检测用户密码、权限变更和用户删除（网站上修改密码或封禁用户后缓存的用户信息失效）
+// 只查询被检测的用户（用户缓存中的用户），首次检测到的用户以当前数据为基线
+func (f *ChangeFeed) pollUsers() ([]storage.ChangeEvent, error) {
+	f.mutex.RLock()
+	watchUsers := f.watchUsers
+	f.mutex.RUnlock()
+	if watchUsers == nil {
+		return nil, nil
+	}
+
+	var uids []uint
+	for _, id := range watchUsers() {
+		if uid, err := strconv.ParseUint(id, 10, 64); err == nil {
+			uids = append(uids, uint(uid))
+		}
+	}
+
+	fingerprints, err := f.loadUserFingerprints(uids)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []storage.ChangeEvent
+	now := time.Now()
+	for _, uid := range uids {
+		current, exists := fingerprints[uid]
+		old, known := f.userFingerprints[uid]
+		if !exists || (known && current != old) {
+			events = append(events, storage.ChangeEvent{
+				Type:      storage.ChangeUser,
+				UserID:    strconv.FormatUint(uint64(uid), 10),
+				ChangedAt: now,
+			})
+		}
+	}
+	f.userFingerprints = fingerprints
+
+	return events, nil
+}
+
+// userPollBatchSize 查询用户指纹时每批的用户数（避免IN列表过长）
+const userPollBatchSize = 500
+
+// loadUserFingerprints 计算指定用户的密码和权限指纹（不存在的用户不包含在结果中）
+func (f *ChangeFeed) loadUserFingerprints(uids []uint) (map[uint]uint64, error) {
+	fingerprints := make(map[uint]uint64, len(uids))
+	for batch := range slices.Chunk(uids, userPollBatchSize) {
+		var users []User
+		if err := f.storage.db.Select("uid, password, permission").Where("uid IN ?", batch).Find(&users).Error; err != nil {
+			return nil, fmt.Errorf("failed to poll users: %w", err)
+		}
+
+		for _, user := range users {
+			h := fnv.New64a()
+			fmt.Fprintf(h, "%s|%d", user.Password, user.Permission)
+			fingerprints[user.UID] = h.Sum64()
+		}
+	}
+	return fingerprints, nil
+}
+
+// invalidatePlayer 清除角色在UUID缓存中的过期映射，返回角色当前UUID
+func (f *ChangeFeed) invalidatePlayer(player *Player) string {
+	db := f.storage.db
+	cache := f.storage.uuidGen.cache
+
+	var uuid string
+	var mapping UUIDMapping
+	if err := db.Where("name = ?", player.Name).Limit(1).Find(&mapping).Error; err == nil && mapping.ID != 0 {
+		uuid = mapping.UUID
+	}
+
+	if cached, found := cache.GetUUIDByName(player.Name); found && cached != uuid {
+		cache.DeleteMapping(player.Name, cached)
+	}
+
+	// 角色在网站改名：旧名称的缓存已失效
+	var binding PlayerUUIDBinding
+	if err := db.Where("pid = ?", player.PID).Limit(1).Find(&binding).Error; err == nil && binding.PID != 0 {
+		if binding.Name != player.Name {
+			cache.DeleteMapping(binding.Name, binding.UUID)
+		}
+		if uuid == "" {
+			uuid = binding.UUID
+		}
+	} else if err == nil && uuid != "" {
+		// 角色尚无绑定（映射在引入绑定前创建），补充记录以便之后网站改名时找回UUID
+		if err := bindPlayerIfUnbound(db, player.PID, uuid, player.Name); err != nil {
+			fmt.Printf("⚠️  Failed to record UUID binding for player %s: %v\n", player.Name, err)
+		}
+	}
+
+	return uuid
+}
+
+// pollTextures 检测新上传的材质
+func (f *ChangeFeed) pollTextures() ([]storage.ChangeEvent, error) {
+	var textures []Texture
+	err := f.storage.db.Select("tid, hash, upload_at").
+		Where("tid > ?", f.textureWatermark).
+		Order("tid ASC").
+		Find(&textures).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to poll textures: %w", err)
+	}
+
+	if len(textures) == 0 {
+		return nil, nil
+	}
+
+	// 已在使用新材质的角色（材质的tid可能被删除后重新分配）
+	tids := make([]int, len(textures))
+	for i, texture := range textures {
+		tids[i] = int(texture.TID)
+	}
+	var players []Player
+	err = f.storage.db.Select("pid, uid, name, tid_skin, tid_cape").
+		Where("tid_skin IN ? OR tid_cape IN ?", tids, tids).
+		Order("pid ASC").
+		Find(&players).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to poll texture players: %w", err)
+	}
+	usedBy := make(map[int][]*Player)
+	for i := range players {
+		player := &players[i]
+		usedBy[player.TIDSkin] = append(usedBy[player.TIDSkin], player)
+		if player.TIDCape != player.TIDSkin {
+			usedBy[player.TIDCape] = append(usedBy[player.TIDCape], player)
+		}
+	}
+
+	// 每个使用材质的角色一个事件，没有角色使用时只报告材质
+	var events []storage.ChangeEvent
+	for _, texture := range textures {
+		f.textureWatermark = texture.TID
+		if len(usedBy[int(texture.TID)]) == 0 {
+			events = append(events, storage.ChangeEvent{
+				Type:        storage.ChangeTexture,
+				TextureHash: texture.Hash,
+				ChangedAt:   texture.UploadAt,
+			})
+			continue
+		}
+		for _, player := range usedBy[int(texture.TID)] {
+			events = append(events, storage.ChangeEvent{
+				Type:        storage.ChangeTexture,
+				TextureHash: texture.Hash,
+				ProfileID:   f.invalidatePlayer(player),
+				ProfileName: player.Name,
+				UserID:      strconv.Itoa(player.UID),
+				ChangedAt:   texture.UploadAt,
+			})
+		}
+	}
+
+	return events, nil
+}
+
+// pollOptions 检测站点配置变更（options表没有修改时间，通过比对内存中的配置检测）
+func (f *ChangeFeed) pollOptions() ([]storage.ChangeEvent, error) {
+	changed, err := f.storage.optionsMgr.Reload()
+	if err != nil {
+		return nil, err
+	}
+	if len(changed) == 0 {
+		return nil, nil
+	}
+
+	// 签名密钥变更，清除缓存的密钥对
+	if slices.Contains(changed, "ygg_private_key") {
+		f.storage.textureSigner.ResetKeyPair()
+	}
+
+	return []storage.ChangeEvent{{
+		Type:        storage.ChangeOptions,
+		OptionNames: changed,
+		ChangedAt:   time.Now(),
+	}}, nil
+}
@@ -101,6 +101,46 @@ func (YggLog) TableName() string {
 	return "ygg_log"
 }
 
+// NameHistory 角色名称历史模型（对应ygg_name_history表，由本服务创建）
+type NameHistory struct {
+	ID          uint      `gorm:"primaryKey;column:id;autoIncrement"`
+	UUID        string    `gorm:"column:uuid;size:32;not null;index"`
+	Name        string    `gorm:"column:name;size:255;not null;index"`
+	ChangedToAt int64     `gorm:"column:changed_to_at;not null;default:0"` // 毫秒时间戳，0表示初始名称
+	CreatedAt   time.Time `gorm:"column:created_at;not null"`
+}
+
+func (NameHistory) TableName() string {
+	return "ygg_name_history"
+}
+
+// PlayerUUIDBinding 角色UUID绑定模型（对应ygg_player_uuid表，由本服务创建）
+// 记录players.pid与UUID的对应关系，角色在网站改名后可据此找回原UUID
+type PlayerUUIDBinding struct {
+	PID       uint      `gorm:"primaryKey;column:pid;autoIncrement:false"`
+	UUID      string    `gorm:"column:uuid;size:32;not null;index"`
+	Name      string    `gorm:"column:name;size:255;not null"` // 绑定时的角色名
+	UpdatedAt time.Time `gorm:"column:updated_at;not null"`
+}
+
+func (PlayerUUIDBinding) TableName() string {
+	return "ygg_player_uuid"
+}
+
+// TwoFactor 两步验证模型（对应ygg_two_factor表，由本服务创建）
+type TwoFactor struct {
+	UID           uint      `gorm:"primaryKey;column:uid;autoIncrement:false"`
+	Secret        string    `gorm:"column:secret;type:text;not null"`         // 加密后的TOTP密钥
+	Enabled       bool      `gorm:"column:enabled;not null;default:false"`
+	RecoveryCodes string    `gorm:"column:recovery_codes;type:text;not null"` // 未使用的恢复码哈希（逗号分隔）
+	LastStep      int64     `gorm:"column:last_step;not null;default:0"`
+	UpdatedAt     time.Time `gorm:"column:updated_at;not null"`
+}
+
+func (TwoFactor) TableName() string {
+	return "ygg_two_factor"
+}
+
 // MojangVerification Mojang验证模型（对应mojang_verifications表）
 type MojangVerification struct {
 	ID        uint       `gorm:"primaryKey;column:id;autoIncrement"`
@@ -0,0 +1,281 @@
+// Package blessing_skin BlessingSkin角色名称历史管理
+package blessing_skin
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	storage "yggdrasil-api-go/src/storage/interface"
+	"yggdrasil-api-go/src/yggdrasil"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RenameProfile 角色改名（保持UUID不变并记录名称历史）
+func (s *Storage) RenameProfile(uuid, newName string, cooldown time.Duration) error {
+	player, err := s.GetPlayerByUUID(uuid)
+	if err != nil {
+		return storage.ErrProfileNotFound
+	}
+
+	oldName := player.Name
+	if oldName == newName {
+		return nil
+	}
+
+	var staleMapping UUIDMapping
+	hasStale := false
+	now := time.Now()
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// 检查新名称是否已被其他角色使用（不区分大小写，允许仅修改大小写）
+		// 使用加锁读并先于锁定自身角色行执行，并发改名为同一名称的请求依次检查，后者能看到前者提交的改名
+		var taken []Player
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("pid").
+			Where("LOWER(name) = ? AND pid <> ?", strings.ToLower(newName), player.PID).
+			Find(&taken).Error
+		if err != nil {
+			return err
+		}
+		if len(taken) > 0 {
+			return storage.ErrProfileNameExists
+		}
+
+		// 锁定角色行，串行化同一角色的并发改名，并在锁内检查改名冷却时间
+		var locked Player
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("pid, name").
+			Where("pid = ?", player.PID).
+			First(&locked).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return storage.ErrProfileNotFound
+			}
+			return err
+		}
+		oldName = locked.Name
+
+		if cooldown > 0 {
+			var lastChangedAt int64
+			err := tx.Model(&NameHistory{}).
+				Select("COALESCE(MAX(changed_to_at), 0)").
+				Where("uuid = ?", uuid).
+				Scan(&lastChangedAt).Error
+			if err != nil {
+				return err
+			}
+			if lastChangedAt > 0 && now.Sub(time.UnixMilli(lastChangedAt)) < cooldown {
+				return storage.ErrRenameCooldown
+			}
+		}
+
+		// 查找已删除角色遗留的UUID映射（名称已无角色使用，可以释放）
+		err = tx.Where("name = ? AND uuid <> ?", newName, uuid).Limit(1).Find(&staleMapping).Error
+		if err != nil {
+			return err
+		}
+		if hasStale = staleMapping.ID != 0; hasStale {
+			if err := tx.Delete(&staleMapping).Error; err != nil {
+				return err
+			}
+		}
+
+		// 更新角色名称
+		err = tx.Model(&Player{}).Where("pid = ?", player.PID).Updates(map[string]any{
+			"name":          newName,
+			"last_modified": now,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		// 迁移UUID映射，保持UUID不变
+		if _, err := s.uuidGen.updateUUIDMapping(tx, oldName, newName); err != nil {
+			return err
+		}
+
+		// 更新角色UUID绑定
+		if err := upsertPlayerBinding(tx, player.PID, uuid, newName); err != nil {
+			return err
+		}
+
+		return recordNameChange(tx, uuid, oldName, newName, now)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to rename profile: %w", err)
+	}
+
+	// 更新UUID缓存
+	if hasStale {
+		s.uuidGen.cache.DeleteMapping(staleMapping.Name, staleMapping.UUID)
+	}
+	s.uuidGen.cache.DeleteMapping(oldName, uuid)
+	s.uuidGen.cache.PutMapping(newName, uuid)
+
+	return nil
+}
+
+// recordNameChange 记录一次改名（首次改名时补录初始名称）
+func recordNameChange(tx *gorm.DB, uuid, oldName, newName string, changedAt time.Time) error {
+	var historyCount int64
+	if err := tx.Model(&NameHistory{}).Where("uuid = ?", uuid).Count(&historyCount).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var records []NameHistory
+	if historyCount == 0 {
+		records = append(records, NameHistory{UUID: uuid, Name: oldName, CreatedAt: now})
+	}
+	records = append(records, NameHistory{UUID: uuid, Name: newName, ChangedToAt: changedAt.UnixMilli(), CreatedAt: now})
+	return tx.Create(&records).Error
+}
+
+// GetProfileNameHistory 获取角色名称历史
+func (s *Storage) GetProfileNameHistory(uuid string) ([]yggdrasil.NameHistory, error) {
+	player, err := s.GetPlayerByUUID(uuid)
+	if err != nil {
+		return nil, storage.ErrProfileNotFound
+	}
+
+	return s.getNameHistory(uuid, player.Name)
+}
+
+// GetProfileByNameAt 根据指定时间点使用的名称获取角色
+// 多个角色匹配时优先有改名历史的角色，其次取最早创建的角色，保证结果稳定
+func (s *Storage) GetProfileByNameAt(name string, at time.Time) (*yggdrasil.Profile, error) {
+	// 候选角色：曾经使用过该名称的角色（不区分大小写）
+	var candidates []string
+	err := s.db.Model(&NameHistory{}).
+		Distinct("uuid").
+		Where("LOWER(name) = ?", strings.ToLower(name)).
+		Pluck("uuid", &candidates).Error
+	if err != nil {
+		return nil, err
+	}
+	renamed := make(map[string]bool, len(candidates))
+	for _, uuid := range candidates {
+		renamed[uuid] = true
+	}
+
+	// 当前使用该名称的角色（可能从未改名，没有历史记录）
+	if storedName, err := s.resolvePlayerName(name); err == nil {
+		if uuid, err := s.uuidGen.GetUUIDByName(storedName); err == nil && !renamed[uuid] {
+			candidates = append(candidates, uuid)
+		}
+	}
+
+	var found *Player
+	var foundUUID string
+	for _, uuid := range candidates {
+		player, err := s.GetPlayerByUUID(uuid)
+		if err != nil {
+			continue
+		}
+
+		history, err := s.getNameHistory(uuid, player.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		// players表不记录创建时间，从未改名的角色以最后修改时间为界，避免后来注册的角色匹配其存在之前的时间点
+		var since time.Time
+		if !renamed[uuid] {
+			since = player.LastModified
+		}
+		if !strings.EqualFold(yggdrasil.NameAt(history, since, at), name) {
+			continue
+		}
+
+		if found == nil || (renamed[uuid] && !renamed[foundUUID]) ||
+			(renamed[uuid] == renamed[foundUUID] && player.PID < found.PID) {
+			found = player
+			foundUUID = uuid
+		}
+	}
+
+	if found == nil {
+		return nil, fmt.Errorf("profile not found")
+	}
+	return s.GetProfileByUUID(foundUUID)
+}
+
+// getNameHistory 查询角色名称历史，未改过名的角色只有当前名称
+func (s *Storage) getNameHistory(uuid, currentName string) ([]yggdrasil.NameHistory, error) {
+	var records []NameHistory
+	err := s.db.Where("uuid = ?", uuid).
+		Order("changed_to_at ASC, id ASC").
+		Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return []yggdrasil.NameHistory{{Name: currentName}}, nil
+	}
+
+	history := make([]yggdrasil.NameHistory, len(records))
+	for i, record := range records {
+		history[i] = yggdrasil.NameHistory{
+			Name:        record.Name,
+			ChangedToAt: record.ChangedToAt,
+		}
+	}
+	return history, nil
+}
+
+// FindProfileNameConflicts 查找仅大小写不同的重名角色（只读，不为未映射的角色生成UUID）
+func (s *Storage) FindProfileNameConflicts() ([]*storage.ProfileNameConflict, error) {
+	var conflictNames []string
+	err := s.db.Model(&Player{}).
+		Select("LOWER(name)").
+		Group("LOWER(name)").
+		Having("COUNT(*) > 1").
+		Pluck("LOWER(name)", &conflictNames).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if len(conflictNames) == 0 {
+		return []*storage.ProfileNameConflict{}, nil
+	}
+
+	// 只读取已有的UUID映射（诊断报告不创建映射），未映射的角色UUID为空
+	var players []struct {
+		PID  uint
+		Name string
+		UUID string
+	}
+	err = s.db.Table("players").
+		Select("players.pid, players.name, COALESCE(uuid.uuid, '') AS uuid").
+		Joins("LEFT JOIN uuid ON uuid.name = players.name").
+		Where("LOWER(players.name) IN ?", conflictNames).
+		Order("players.pid ASC").
+		Scan(&players).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// 按小写名称分组（保持pid顺序）
+	groups := make(map[string]*storage.ProfileNameConflict)
+	var conflicts []*storage.ProfileNameConflict
+	for _, player := range players {
+		key := strings.ToLower(player.Name)
+		conflict, exists := groups[key]
+		if !exists {
+			conflict = &storage.ProfileNameConflict{Name: key}
+			groups[key] = conflict
+			conflicts = append(conflicts, conflict)
+		}
+		conflict.Profiles = append(conflict.Profiles, &yggdrasil.Profile{
+			ID:         player.UUID,
+			Name:       player.Name,
+			Properties: []yggdrasil.ProfileProperty{},
+		})
+	}
+
+	return conflicts, nil
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"sync"
 
 	"gorm.io/gorm"
@@ -32,23 +33,62 @@ func NewOptionsManager(storage *Storage) *OptionsManager {
 
 // loadAllOptions 启动时批量加载所有Yggdrasil配置
 func (om *OptionsManager) loadAllOptions() {
+	options, err := om.fetchOptions()
+	if err != nil {
+		log.Printf("⚠️  Failed to load options: %v", err)
+		return
+	}
+
+	// 存储到内存中
 	om.mutex.Lock()
-	defer om.mutex.Unlock()
+	om.options = options
+	om.mutex.Unlock()
 
-	// 获取所有Yggdrasil相关配置
+	log.Printf("✅ Loaded %d options into memory", len(options))
+}
+
+// fetchOptions 从数据库读取所有Yggdrasil相关配置
+func (om *OptionsManager) fetchOptions() (map[string]string, error) {
 	var options []Option
 	err := om.storage.db.Where("option_name LIKE 'ygg_%' OR option_name = 'site_url'").Find(&options).Error
 	if err != nil {
-		log.Printf("⚠️  Failed to load options: %v", err)
-		return
+		return nil, err
 	}
 
-	// 存储到内存中
+	result := make(map[string]string, len(options))
 	for _, option := range options {
-		om.options[option.OptionName] = option.OptionValue
+		result[option.OptionName] = option.OptionValue
 	}
+	return result, nil
+}
 
-	log.Printf("✅ Loaded %d options into memory", len(om.options))
+// Reload 重新加载配置，返回发生变化的配置项名称
+func (om *OptionsManager) Reload() ([]string, error) {
+	options, err := om.fetchOptions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to reload options: %w", err)
+	}
+
+	om.mutex.Lock()
+	defer om.mutex.Unlock()
+
+	var changed []string
+	for name, value := range options {
+		if old, exists := om.options[name]; !exists || old != value {
+			changed = append(changed, name)
+		}
+	}
+	for name := range om.options {
+		if _, exists := options[name]; !exists {
+			changed = append(changed, name)
+		}
+	}
+
+	if len(changed) > 0 {
+		om.options = options
+		sort.Strings(changed)
+	}
+	return changed, nil
 }
 
 // YggdrasilOptions Yggdrasil配置项及其默认值（仅包含实际存在的配置项）
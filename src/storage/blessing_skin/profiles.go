@@ -4,6 +4,8 @@ package blessing_skin
 import (
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 
 	storage "yggdrasil-api-go/src/storage/interface"
 	"yggdrasil-api-go/src/yggdrasil"
@@ -71,25 +73,126 @@ func (s *Storage) GetProfileByUUID(uuid string) (*yggdrasil.Profile, error) {
 	}, nil
 }
 
-// GetProfileByName 根据名称获取角色（单查询优化版）
+// resolvePlayerName 解析角色名的存储形式（不区分大小写，优先精确匹配）
+func (s *Storage) resolvePlayerName(name string) (string, error) {
+	names, err := s.resolvePlayerNames([]string{name})
+	if err != nil {
+		return "", err
+	}
+	if len(names) == 0 {
+		return "", gorm.ErrRecordNotFound
+	}
+	return names[0], nil
+}
+
+// resolvePlayerNames 批量解析角色名的存储形式（不区分大小写，优先精确匹配，结果按请求顺序去重）
+func (s *Storage) resolvePlayerNames(names []string) ([]string, error) {
+	if len(names) == 0 {
+		return []string{}, nil
+	}
+
+	requested := make(map[string]bool, len(names))
+	for _, name := range names {
+		requested[name] = true
+	}
+
+	// 小写名称 -> 存储形式
+	resolved := make(map[string]string, len(names))
+	collect := func(storedNames []string) {
+		for _, stored := range storedNames {
+			key := strings.ToLower(stored)
+			current, exists := resolved[key]
+			switch {
+			case !exists:
+				// 默认取最早创建的角色（查询已按pid排序）
+				resolved[key] = stored
+			case requested[stored] && !requested[current]:
+				// 精确匹配优先于大小写变体
+				resolved[key] = stored
+			}
+		}
+	}
+
+	// 先精确匹配（可以使用索引；大小写不敏感的排序规则下也会返回大小写变体）
+	var exactNames []string
+	err := s.db.Model(&Player{}).
+		Where("name IN ?", names).
+		Order("pid ASC").
+		Pluck("name", &exactNames).Error
+	if err != nil {
+		return nil, err
+	}
+	collect(exactNames)
+
+	// 未命中的名称再按小写匹配（兼容大小写敏感的排序规则）
+	var missing []string
+	for _, name := range names {
+		if _, exists := resolved[strings.ToLower(name)]; !exists {
+			missing = append(missing, strings.ToLower(name))
+		}
+	}
+	if len(missing) > 0 {
+		var foldedNames []string
+		err := s.db.Model(&Player{}).
+			Where("LOWER(name) IN ?", missing).
+			Order("pid ASC").
+			Pluck("name", &foldedNames).Error
+		if err != nil {
+			return nil, err
+		}
+		collect(foldedNames)
+	}
+
+	result := make([]string, 0, len(resolved))
+	seen := make(map[string]bool, len(resolved))
+	for _, name := range names {
+		key := strings.ToLower(name)
+		if stored, exists := resolved[key]; exists && !seen[key] {
+			result = append(result, stored)
+			seen[key] = true
+		}
+	}
+	return result, nil
+}
+
+// GetProfileByName 根据名称获取角色（单查询优化版，不区分大小写）
 func (s *Storage) GetProfileByName(name string) (*yggdrasil.Profile, error) {
+	// 解析角色名的存储形式
+	name, err := s.resolvePlayerName(name)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("profile not found")
+		}
+		return nil, err
+	}
+
 	// 一次性查询角色信息和UUID映射
-	var result struct {
+	var results []struct {
 		PlayerName string `gorm:"column:name"`
 		UUID       string `gorm:"column:uuid"`
 	}
 
-	err := s.db.Table("players p").
+	err = s.db.Table("players p").
 		Select("p.name, u.uuid").
 		Joins("LEFT JOIN uuid u ON p.name = u.name").
 		Where("p.name = ?", name).
-		Take(&result).Error
+		Order("p.pid ASC").
+		Find(&results).Error
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, fmt.Errorf("profile not found")
-		}
 		return nil, err
 	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("profile not found")
+	}
+
+	// 大小写不敏感的排序规则下可能返回大小写变体，优先取存储形式完全一致的角色
+	result := results[0]
+	for _, r := range results {
+		if r.PlayerName == name {
+			result = r
+			break
+		}
+	}
 
 	// 如果UUID不存在，创建它
 	uuid := result.UUID
@@ -140,19 +243,35 @@ func (s *Storage) GetProfileByName(name string) (*yggdrasil.Profile, error) {
 	}, nil
 }
 
-// GetProfilesByNames 根据名称列表批量获取角色（优化版，自动创建UUID）
+// GetProfilesByNames 根据名称列表批量获取角色（优化版，自动创建UUID，不区分大小写）
 func (s *Storage) GetProfilesByNames(names []string) ([]*yggdrasil.Profile, error) {
 	if len(names) == 0 {
 		return []*yggdrasil.Profile{}, nil
 	}
 
-	// 1. 批量查询角色是否存在
-	var players []Player
-	err := s.db.Where("name IN ?", names).Find(&players).Error
+	// 1. 解析角色名的存储形式并批量查询角色
+	names, err := s.resolvePlayerNames(names)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return []*yggdrasil.Profile{}, nil
+	}
+
+	var found []Player
+	err = s.db.Where("name IN ?", names).Find(&found).Error
 	if err != nil {
 		return nil, err
 	}
 
+	// 过滤大小写不敏感的排序规则返回的大小写变体
+	var players []Player
+	for _, player := range found {
+		if slices.Contains(names, player.Name) {
+			players = append(players, player)
+		}
+	}
+
 	if len(players) == 0 {
 		return []*yggdrasil.Profile{}, nil
 	}
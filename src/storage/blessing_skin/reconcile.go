@@ -0,0 +1,475 @@
+// Package blessing_skin 角色与UUID映射对账
+package blessing_skin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"gorm.io/gorm"
+)
+
+// ReconcileActionType 对账动作类型
+type ReconcileActionType string
+
+const (
+	ReconcileBind            ReconcileActionType = "bind"             // 记录角色与UUID的绑定
+	ReconcileRenamed         ReconcileActionType = "renamed"          // 角色已在网站改名，将原UUID迁移到新名称
+	ReconcileMissing         ReconcileActionType = "missing"          // 角色没有UUID映射，生成新映射
+	ReconcileOrphan          ReconcileActionType = "orphan"           // 映射对应的角色已删除
+	ReconcileDuplicateName   ReconcileActionType = "duplicate_name"   // 同一角色名存在多条映射
+	ReconcileDuplicateUUID   ReconcileActionType = "duplicate_uuid"   // 同一UUID被多个在用角色使用
+	ReconcileDuplicatePlayer ReconcileActionType = "duplicate_player" // 仅大小写不同的重名角色
+)
+
+// ReconcileOptions 对账选项
+type ReconcileOptions struct {
+	Apply        bool // 是否应用修复（否则仅生成报告）
+	PruneOrphans bool // 是否删除已删除角色的遗留映射（默认保留，角色重建时可复用UUID）
+}
+
+// ReconcileAction 对账发现的问题及修复动作
+type ReconcileAction struct {
+	Type      ReconcileActionType `json:"type"`
+	PID       uint                `json:"pid,omitempty"`
+	Name      string              `json:"name"`
+	OldName   string              `json:"old_name,omitempty"`
+	UUID      string              `json:"uuid,omitempty"`
+	MappingID uint                `json:"mapping_id,omitempty"`
+	Detail    string              `json:"detail"`
+	Fixable   bool                `json:"fixable"` // 是否可自动修复
+	Applied   bool                `json:"applied"` // 是否已应用修复
+
+	apply func(tx *gorm.DB) error
+}
+
+// ReconcileReport 对账报告
+type ReconcileReport struct {
+	StartedAt  time.Time                   `json:"started_at"`
+	FinishedAt time.Time                   `json:"finished_at"`
+	Apply      bool                        `json:"apply"`
+	Players    int                         `json:"players"`
+	Mappings   int                         `json:"mappings"`
+	Summary    map[ReconcileActionType]int `json:"summary"`
+	Actions    []*ReconcileAction          `json:"actions"`
+}
+
+// reconcileState 对账过程中的内存状态
+type reconcileState struct {
+	players       []*Player
+	playersByKey  map[string][]*Player // 小写角色名 -> 角色（按pid排序）
+	mappingsByKey map[string][]*UUIDMapping
+	mappingsByID  map[uint]*UUIDMapping
+	bindings      map[uint]*PlayerUUIDBinding
+	claimed       map[uint]uint // 映射ID -> 使用该映射的角色pid
+	uuidOwner     map[string]uint
+	usedUUIDs     map[string]bool // 已被映射或绑定使用的UUID（包括本次对账生成的UUID）
+}
+
+// Reconcile 对账players表与uuid表，检测改名、删除和重复的角色
+func (s *Storage) Reconcile(opts ReconcileOptions) (*ReconcileReport, error) {
+	report := &ReconcileReport{
+		StartedAt: time.Now(),
+		Apply:     opts.Apply,
+		Summary:   make(map[ReconcileActionType]int),
+		Actions:   []*ReconcileAction{},
+	}
+
+	state, err := s.loadReconcileState()
+	if err != nil {
+		return nil, err
+	}
+
+	report.Players = len(state.players)
+	for _, mappings := range state.mappingsByKey {
+		report.Mappings += len(mappings)
+	}
+
+	// 1. 逐个角色检查映射与绑定
+	for _, player := range state.players {
+		if action := s.reconcilePlayer(state, player); action != nil {
+			report.Actions = append(report.Actions, action)
+		}
+	}
+
+	// 2. 检查未被任何角色使用的映射
+	report.Actions = append(report.Actions, s.reconcileUnclaimed(state, opts)...)
+
+	// 3. 检查重复使用的UUID和大小写重名的角色（仅报告）
+	report.Actions = append(report.Actions, reconcileDuplicates(state)...)
+
+	// 应用修复
+	if opts.Apply {
+		if err := s.applyReconcileActions(report.Actions); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, action := range report.Actions {
+		report.Summary[action.Type]++
+	}
+	report.FinishedAt = time.Now()
+
+	return report, nil
+}
+
+// loadReconcileState 加载对账所需的全部数据
+func (s *Storage) loadReconcileState() (*reconcileState, error) {
+	var players []*Player
+	if err := s.db.Select("pid, uid, name, last_modified").Order("pid ASC").Find(&players).Error; err != nil {
+		return nil, fmt.Errorf("failed to load players: %w", err)
+	}
+
+	var mappings []*UUIDMapping
+	if err := s.db.Order("id ASC").Find(&mappings).Error; err != nil {
+		return nil, fmt.Errorf("failed to load uuid mappings: %w", err)
+	}
+
+	var bindings []*PlayerUUIDBinding
+	if err := s.db.Find(&bindings).Error; err != nil {
+		return nil, fmt.Errorf("failed to load uuid bindings: %w", err)
+	}
+
+	state := &reconcileState{
+		players:       players,
+		playersByKey:  make(map[string][]*Player),
+		mappingsByKey: make(map[string][]*UUIDMapping),
+		mappingsByID:  make(map[uint]*UUIDMapping, len(mappings)),
+		bindings:      make(map[uint]*PlayerUUIDBinding, len(bindings)),
+		claimed:       make(map[uint]uint),
+		uuidOwner:     make(map[string]uint),
+		usedUUIDs:     make(map[string]bool, len(mappings)+len(bindings)),
+	}
+
+	for _, player := range players {
+		key := strings.ToLower(player.Name)
+		state.playersByKey[key] = append(state.playersByKey[key], player)
+	}
+	for _, mapping := range mappings {
+		key := strings.ToLower(mapping.Name)
+		state.mappingsByKey[key] = append(state.mappingsByKey[key], mapping)
+		state.mappingsByID[mapping.ID] = mapping
+		state.usedUUIDs[mapping.UUID] = true
+	}
+	for _, binding := range bindings {
+		state.bindings[binding.PID] = binding
+		state.usedUUIDs[binding.UUID] = true
+	}
+
+	// 预先确定每个角色当前使用的映射（精确匹配优先，同名映射不被重复认领）
+	for _, player := range players {
+		if mapping := state.findMapping(player); mapping != nil {
+			state.claimed[mapping.ID] = player.PID
+			if _, exists := state.uuidOwner[mapping.UUID]; !exists {
+				state.uuidOwner[mapping.UUID] = player.PID
+			}
+		}
+	}
+
+	return state, nil
+}
+
+// findMapping 查找角色可用的映射（尚未被其他角色认领）
+func (st *reconcileState) findMapping(player *Player) *UUIDMapping {
+	var fallback *UUIDMapping
+	for _, mapping := range st.mappingsByKey[strings.ToLower(player.Name)] {
+		if pid, claimed := st.claimed[mapping.ID]; claimed && pid != player.PID {
+			continue
+		}
+		if mapping.Name == player.Name {
+			return mapping
+		}
+		if fallback == nil {
+			fallback = mapping
+		}
+	}
+	return fallback
+}
+
+// reconcilePlayer 检查单个角色的映射与绑定
+func (s *Storage) reconcilePlayer(state *reconcileState, player *Player) *ReconcileAction {
+	mapping := state.findMapping(player)
+	binding := state.bindings[player.PID]
+
+	switch {
+	case mapping != nil && (binding == nil || binding.UUID == mapping.UUID):
+		// 映射正常，补充或更新绑定
+		if binding != nil && binding.Name == player.Name {
+			return nil
+		}
+		pid, uuid, name := player.PID, mapping.UUID, player.Name
+		return &ReconcileAction{
+			Type:      ReconcileBind,
+			PID:       pid,
+			Name:      name,
+			UUID:      uuid,
+			MappingID: mapping.ID,
+			Detail:    "record pid to UUID binding",
+			Fixable:   true,
+			apply: func(tx *gorm.DB) error {
+				return upsertPlayerBinding(tx, pid, uuid, name)
+			},
+		}
+
+	case binding != nil && state.uuidOwner[binding.UUID] != 0 && state.uuidOwner[binding.UUID] != player.PID:
+		// 绑定的UUID已被其他在用角色占用，无法自动修复
+		return &ReconcileAction{
+			Type:    ReconcileDuplicateUUID,
+			PID:     player.PID,
+			Name:    player.Name,
+			OldName: binding.Name,
+			UUID:    binding.UUID,
+			Detail:  fmt.Sprintf("bound UUID is now used by player pid %d", state.uuidOwner[binding.UUID]),
+		}
+
+	case binding != nil:
+		// 角色已在网站改名：映射缺失或被重新生成了UUID，恢复绑定的原UUID
+		detail := "player renamed on website, move mapping to new name"
+		if mapping != nil {
+			detail = fmt.Sprintf("player renamed on website and re-minted as %s, restore bound UUID", mapping.UUID)
+		}
+		p, b := *player, *binding
+		return &ReconcileAction{
+			Type:    ReconcileRenamed,
+			PID:     player.PID,
+			Name:    player.Name,
+			OldName: binding.Name,
+			UUID:    binding.UUID,
+			Detail:  detail,
+			Fixable: true,
+			apply: func(tx *gorm.DB) error {
+				return rebindPlayerUUID(tx, &p, &b)
+			},
+		}
+
+	default:
+		// 从未生成过映射
+		uuid, err := s.uuidGen.GenerateUUID(player.Name)
+		if err != nil {
+			return &ReconcileAction{
+				Type:   ReconcileMissing,
+				PID:    player.PID,
+				Name:   player.Name,
+				Detail: fmt.Sprintf("failed to generate UUID: %v", err),
+			}
+		}
+		detail := "player has no UUID mapping, create one"
+
+		// v3算法下，改名后的旧名称被新角色使用时，生成的UUID会与改名角色的映射或绑定冲突，回退为随机UUID
+		if state.usedUUIDs[uuid] {
+			uuid = s.uuidGen.generateUUIDV4()
+			detail = "player has no UUID mapping and the generated UUID is already in use, create one with a random UUID"
+		}
+		state.usedUUIDs[uuid] = true
+
+		pid, name := player.PID, player.Name
+		return &ReconcileAction{
+			Type:    ReconcileMissing,
+			PID:     pid,
+			Name:    name,
+			UUID:    uuid,
+			Detail:  detail,
+			Fixable: true,
+			apply: func(tx *gorm.DB) error {
+				if err := tx.Create(&UUIDMapping{Name: name, UUID: uuid}).Error; err != nil {
+					return err
+				}
+				return upsertPlayerBinding(tx, pid, uuid, name)
+			},
+		}
+	}
+}
+
+// reconcileUnclaimed 检查未被任何角色使用的映射（遗留映射或重复映射）
+func (s *Storage) reconcileUnclaimed(state *reconcileState, opts ReconcileOptions) []*ReconcileAction {
+	// 已绑定的UUID会在改名修复时迁移，不作为遗留映射处理
+	boundUUIDs := make(map[string]bool, len(state.bindings))
+	for pid, binding := range state.bindings {
+		if state.uuidOwner[binding.UUID] == 0 || state.uuidOwner[binding.UUID] == pid {
+			boundUUIDs[binding.UUID] = true
+		}
+	}
+
+	keys := make([]string, 0, len(state.mappingsByKey))
+	for key := range state.mappingsByKey {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var actions []*ReconcileAction
+	for _, key := range keys {
+		for _, mapping := range state.mappingsByKey[key] {
+			if _, claimed := state.claimed[mapping.ID]; claimed || boundUUIDs[mapping.UUID] {
+				continue
+			}
+
+			id := mapping.ID
+			deleteMapping := func(tx *gorm.DB) error {
+				return tx.Delete(&UUIDMapping{}, id).Error
+			}
+
+			if len(state.playersByKey[key]) > 0 {
+				// 角色存在但已认领了另一条映射：多余映射可能才是角色原来的UUID（如仅大小写不同），仅报告，需人工处理
+				actions = append(actions, &ReconcileAction{
+					Type:      ReconcileDuplicateName,
+					Name:      mapping.Name,
+					UUID:      mapping.UUID,
+					MappingID: id,
+					Detail:    "extra mapping for an existing player, review which UUID to keep",
+				})
+				continue
+			}
+
+			action := &ReconcileAction{
+				Type:      ReconcileOrphan,
+				Name:      mapping.Name,
+				UUID:      mapping.UUID,
+				MappingID: id,
+				Detail:    "player no longer exists, keep mapping so the UUID is reused if the name returns",
+			}
+			if opts.PruneOrphans {
+				action.Detail = "player no longer exists, remove mapping"
+				action.Fixable = true
+				action.apply = deleteMapping
+			}
+			actions = append(actions, action)
+		}
+	}
+
+	return actions
+}
+
+// reconcileDuplicates 检查重复使用的UUID和大小写重名的角色（仅报告，需人工处理）
+func reconcileDuplicates(state *reconcileState) []*ReconcileAction {
+	var actions []*ReconcileAction
+
+	// 同一UUID被多个在用角色使用
+	uuidPlayers := make(map[string][]uint)
+	for mappingID, pid := range state.claimed {
+		uuid := state.mappingsByID[mappingID].UUID
+		uuidPlayers[uuid] = append(uuidPlayers[uuid], pid)
+	}
+	for uuid, pids := range uuidPlayers {
+		if len(pids) < 2 {
+			continue
+		}
+		sort.Slice(pids, func(i, j int) bool { return pids[i] < pids[j] })
+		actions = append(actions, &ReconcileAction{
+			Type:   ReconcileDuplicateUUID,
+			UUID:   uuid,
+			Detail: fmt.Sprintf("UUID is mapped to multiple players: pid %v", pids),
+		})
+	}
+
+	// 仅大小写不同的重名角色
+	for key, players := range state.playersByKey {
+		if len(players) < 2 {
+			continue
+		}
+		names := make([]string, len(players))
+		for i, player := range players {
+			names[i] = fmt.Sprintf("%s (pid %d)", player.Name, player.PID)
+		}
+		actions = append(actions, &ReconcileAction{
+			Type:   ReconcileDuplicatePlayer,
+			Name:   key,
+			Detail: "players differ only by case: " + strings.Join(names, ", "),
+		})
+	}
+
+	sort.SliceStable(actions, func(i, j int) bool {
+		if actions[i].Type != actions[j].Type {
+			return actions[i].Type < actions[j].Type
+		}
+		return actions[i].Name+actions[i].UUID < actions[j].Name+actions[j].UUID
+	})
+
+	return actions
+}
+
+// applyReconcileActions 在事务中应用所有可修复的动作
+func (s *Storage) applyReconcileActions(actions []*ReconcileAction) error {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for _, action := range actions {
+			if !action.Fixable || action.apply == nil {
+				continue
+			}
+			if err := action.apply(tx); err != nil {
+				return fmt.Errorf("failed to apply %s fix for %s: %w", action.Type, action.Name, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, action := range actions {
+		if action.Fixable && action.apply != nil {
+			action.Applied = true
+		}
+	}
+
+	// 映射已变更，清空UUID缓存
+	s.uuidGen.ClearCache()
+	return nil
+}
+
+// reconcileLoop 定时执行对账任务
+func (s *Storage) reconcileLoop() {
+	ticker := time.NewTicker(s.config.ReconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.runScheduledReconcile()
+		case <-s.stopReconcile:
+			return
+		}
+	}
+}
+
+// runScheduledReconcile 执行一次定时对账并输出报告
+func (s *Storage) runScheduledReconcile() {
+	report, err := s.Reconcile(ReconcileOptions{
+		Apply:        s.config.ReconcileAutoApply,
+		PruneOrphans: s.config.ReconcilePruneOrphans,
+	})
+	if err != nil {
+		fmt.Printf("⚠️  UUID reconcile failed: %v\n", err)
+		return
+	}
+
+	if len(report.Actions) > 0 {
+		fmt.Printf("🔍 UUID reconcile: %d players, %d mappings, issues: %v (applied: %v)\n",
+			report.Players, report.Mappings, report.Summary, report.Apply)
+	}
+
+	if s.config.ReconcileReportDir != "" {
+		name := fmt.Sprintf("reconcile-%s.json", report.StartedAt.Format("20060102-150405"))
+		if err := WriteReconcileReport(report, filepath.Join(s.config.ReconcileReportDir, name)); err != nil {
+			fmt.Printf("⚠️  Failed to write reconcile report: %v\n", err)
+		}
+	}
+}
+
+// WriteReconcileReport 将对账报告写入JSON文件
+func WriteReconcileReport(report *ReconcileReport, path string) error {
+	data, err := sonic.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
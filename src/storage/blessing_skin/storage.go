@@ -3,6 +3,8 @@ package blessing_skin
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	storage "yggdrasil-api-go/src/storage/interface"
@@ -20,6 +22,8 @@ type Storage struct {
 	uuidGen       *UUIDGenerator
 	optionsMgr    *OptionsManager
 	textureSigner *TextureSigner
+	stopReconcile chan struct{} // 停止定时对账任务
+	changeFeed    *ChangeFeed   // 数据变更检测器
 }
 
 // TextureConfig 材质配置（从全局配置传入）
@@ -35,6 +39,15 @@ type Config struct {
 	Salt                   string // 密码加密盐值 (对应BlessingSkin的SALT)
 	PwdMethod              string // 密码加密方法 (对应BlessingSkin的PWD_METHOD)
 	AppKey                 string // 应用密钥 (对应BlessingSkin的APP_KEY)
+
+	ReconcileEnabled      bool          // 是否启用定时UUID对账
+	ReconcileInterval     time.Duration // 对账间隔
+	ReconcileAutoApply    bool          // 是否自动应用修复（否则仅生成报告）
+	ReconcilePruneOrphans bool          // 是否删除已删除角色的遗留映射
+	ReconcileReportDir    string        // 对账报告输出目录（为空则不写文件）
+
+	ChangeFeedEnabled  bool          // 是否启用数据变更检测
+	ChangeFeedInterval time.Duration // 变更检测轮询间隔
 }
 
 // NewStorage 创建BlessingSkin存储实例
@@ -74,6 +87,40 @@ func NewStorage(options map[string]any, textureConfig *TextureConfig) (storage.S
 		cfg.AppKey = "base64:your_app_key_here" // 默认应用密钥
 	}
 
+	// 解析对账配置
+	if enabled, ok := options["reconcile_enabled"].(bool); ok {
+		cfg.ReconcileEnabled = enabled
+	}
+
+	if interval, ok := options["reconcile_interval"].(time.Duration); ok && interval > 0 {
+		cfg.ReconcileInterval = interval
+	} else {
+		cfg.ReconcileInterval = 6 * time.Hour // 默认每6小时对账一次
+	}
+
+	if autoApply, ok := options["reconcile_auto_apply"].(bool); ok {
+		cfg.ReconcileAutoApply = autoApply
+	}
+
+	if pruneOrphans, ok := options["reconcile_prune_orphans"].(bool); ok {
+		cfg.ReconcilePruneOrphans = pruneOrphans
+	}
+
+	if reportDir, ok := options["reconcile_report_dir"].(string); ok {
+		cfg.ReconcileReportDir = reportDir
+	}
+
+	// 解析变更检测配置
+	if enabled, ok := options["change_feed_enabled"].(bool); ok {
+		cfg.ChangeFeedEnabled = enabled
+	}
+
+	if interval, ok := options["change_feed_interval"].(time.Duration); ok && interval > 0 {
+		cfg.ChangeFeedInterval = interval
+	} else {
+		cfg.ChangeFeedInterval = 5 * time.Second // 默认每5秒检测一次
+	}
+
 	// 连接数据库
 	gormConfig := &gorm.Config{
 		DisableForeignKeyConstraintWhenMigrating: true,
@@ -110,17 +157,48 @@ func NewStorage(options map[string]any, textureConfig *TextureConfig) (storage.S
 
 	// 配置管理器已在NewOptionsManager中初始化，无需重复调用
 
+	// 创建角色名称历史表、UUID绑定表和两步验证表（BlessingSkin原生不包含这些表）
+	if err := db.AutoMigrate(&NameHistory{}, &PlayerUUIDBinding{}, &TwoFactor{}); err != nil {
+		// 建表失败不影响启动，只记录警告（名称历史、UUID对账和两步验证功能不可用）
+		fmt.Printf("⚠️  Extension table migration failed: %v\n", err)
+	}
+
 	// UUID缓存预热
 	if err := storage.preloadUUIDs(); err != nil {
 		// 预热失败不影响启动，只记录警告
 		fmt.Printf("⚠️  UUID cache preload failed: %v\n", err)
 	}
 
+	// 启动数据变更检测（网站上的修改通过事件通知缓存失效）
+	if cfg.ChangeFeedEnabled {
+		feed, err := NewChangeFeed(storage, cfg.ChangeFeedInterval)
+		if err != nil {
+			// 启动失败不影响服务，只是缓存无法及时失效
+			fmt.Printf("⚠️  Change feed disabled: %v\n", err)
+		} else {
+			storage.changeFeed = feed
+			feed.Start()
+		}
+	}
+
+	// 启动定时对账任务
+	if cfg.ReconcileEnabled {
+		storage.stopReconcile = make(chan struct{})
+		go storage.reconcileLoop()
+	}
+
 	return storage, nil
 }
 
 // Close 关闭存储连接
 func (s *Storage) Close() error {
+	if s.changeFeed != nil {
+		s.changeFeed.Stop()
+	}
+	if s.stopReconcile != nil {
+		close(s.stopReconcile)
+		s.stopReconcile = nil
+	}
 	if s.db != nil {
 		sqlDB, err := s.db.DB()
 		if err == nil {
@@ -130,6 +208,20 @@ func (s *Storage) Close() error {
 	return nil
 }
 
+// Subscribe 订阅数据变更事件（未启用变更检测时不会收到事件）
+func (s *Storage) Subscribe(handler func(storage.ChangeEvent)) {
+	if s.changeFeed != nil {
+		s.changeFeed.Subscribe(handler)
+	}
+}
+
+// WatchUsers 设置需要检测密码、权限变更的用户ID来源（未启用变更检测时无效果）
+func (s *Storage) WatchUsers(userIDs func() []string) {
+	if s.changeFeed != nil {
+		s.changeFeed.WatchUsers(userIDs)
+	}
+}
+
 // Ping 检查存储连接
 func (s *Storage) Ping() error {
 	if s.db == nil {
@@ -220,6 +312,45 @@ func (s *Storage) GetOptionsManager() *OptionsManager {
 	return s.optionsMgr
 }
 
+// GetTokensLimit 获取每用户令牌数量限制（ygg_tokens_limit配置项）
+func (s *Storage) GetTokensLimit() (int, bool) {
+	value, err := s.optionsMgr.GetOption("ygg_tokens_limit")
+	if err != nil {
+		return 0, false
+	}
+	limit, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, false
+	}
+	return limit, true
+}
+
+// GetTokenExpiration 获取令牌有效期和可刷新期限（ygg_token_expire_1和ygg_token_expire_2配置项，单位秒）
+func (s *Storage) GetTokenExpiration() (time.Duration, time.Duration, bool) {
+	expiration, ok := s.durationOption("ygg_token_expire_1")
+	if !ok {
+		return 0, 0, false
+	}
+	refreshExpiration, ok := s.durationOption("ygg_token_expire_2")
+	if !ok {
+		refreshExpiration = expiration
+	}
+	return expiration, refreshExpiration, true
+}
+
+// durationOption 读取以秒为单位的配置项
+func (s *Storage) durationOption(name string) (time.Duration, bool) {
+	value, err := s.optionsMgr.GetOption(name)
+	if err != nil {
+		return 0, false
+	}
+	seconds, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || seconds <= 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
 // GetTextureSigner 获取材质签名器（内部使用）
 func (s *Storage) GetTextureSigner() *TextureSigner {
 	return s.textureSigner
@@ -274,6 +274,16 @@ func (ts *TextureSigner) getCachedRSAKeyPair() (*rsa.PrivateKey, *rsa.PublicKey,
 	return privateKey, publicKey, nil
 }
 
+// ResetKeyPair 清除缓存的密钥对（ygg_private_key变更后调用）
+func (ts *TextureSigner) ResetKeyPair() {
+	ts.keyPairMutex.Lock()
+	defer ts.keyPairMutex.Unlock()
+
+	ts.cachedPrivateKey = nil
+	ts.cachedPublicKey = nil
+	ts.keyPairCached = false
+}
+
 // VerifySignature 验证签名（用于测试）
 func (ts *TextureSigner) VerifySignature(data, signature string) error {
 	publicKeyPEM, err := ts.GetPublicKey()
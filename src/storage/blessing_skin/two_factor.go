@@ -0,0 +1,78 @@
+// Package blessing_skin BlessingSkin两步验证管理
+package blessing_skin
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	storage "yggdrasil-api-go/src/storage/interface"
+
+	"gorm.io/gorm/clause"
+)
+
+// GetTwoFactor 获取用户的两步验证信息（ygg_two_factor表）
+func (s *Storage) GetTwoFactor(userID string) (*storage.TwoFactor, error) {
+	var record TwoFactor
+	err := s.db.Where("uid = ?", userID).Limit(1).Find(&record).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get two-factor record: %w", err)
+	}
+	if record.UID == 0 {
+		return nil, storage.ErrTwoFactorNotFound
+	}
+
+	var recoveryCodes []string
+	if record.RecoveryCodes != "" {
+		recoveryCodes = strings.Split(record.RecoveryCodes, ",")
+	}
+	return &storage.TwoFactor{
+		UserID:        userID,
+		Secret:        record.Secret,
+		Enabled:       record.Enabled,
+		RecoveryCodes: recoveryCodes,
+		LastStep:      record.LastStep,
+		UpdatedAt:     record.UpdatedAt,
+	}, nil
+}
+
+// SaveTwoFactor 保存用户的两步验证信息（已存在时覆盖）
+func (s *Storage) SaveTwoFactor(twoFactor *storage.TwoFactor) error {
+	uid, err := strconv.ParseUint(twoFactor.UserID, 10, 64)
+	if err != nil || uid == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	var count int64
+	if err := s.db.Model(&User{}).Where("uid = ?", uid).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to save two-factor record: %w", err)
+	}
+	if count == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	record := TwoFactor{
+		UID:           uint(uid),
+		Secret:        twoFactor.Secret,
+		Enabled:       twoFactor.Enabled,
+		RecoveryCodes: strings.Join(twoFactor.RecoveryCodes, ","),
+		LastStep:      twoFactor.LastStep,
+		UpdatedAt:     twoFactor.UpdatedAt,
+	}
+	if record.UpdatedAt.IsZero() {
+		record.UpdatedAt = time.Now()
+	}
+	if err := s.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&record).Error; err != nil {
+		return fmt.Errorf("failed to save two-factor record: %w", err)
+	}
+	return nil
+}
+
+// DeleteTwoFactor 删除用户的两步验证信息
+func (s *Storage) DeleteTwoFactor(userID string) error {
+	if err := s.db.Where("uid = ?", userID).Delete(&TwoFactor{}).Error; err != nil {
+		return fmt.Errorf("failed to delete two-factor record: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+// Package blessing_skin BlessingSkin用户属性管理
+package blessing_skin
+
+import (
+	"fmt"
+
+	storage "yggdrasil-api-go/src/storage/interface"
+	"yggdrasil-api-go/src/yggdrasil"
+)
+
+// GetUserProperties 获取用户属性（preferredLanguage对应users.locale）
+func (s *Storage) GetUserProperties(userID string) ([]yggdrasil.ProfileProperty, error) {
+	var user User
+	err := s.db.Select("uid, locale").Where("uid = ?", userID).Limit(1).Find(&user).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user properties: %w", err)
+	}
+	if user.UID == 0 {
+		return nil, storage.ErrUserNotFound
+	}
+
+	properties := []yggdrasil.ProfileProperty{}
+	if user.Locale != nil && *user.Locale != "" {
+		properties = append(properties, yggdrasil.ProfileProperty{
+			Name:  yggdrasil.UserPropertyPreferredLanguage,
+			Value: *user.Locale,
+		})
+	}
+	return properties, nil
+}
+
+// UpdateUserProperties 更新用户属性（只支持preferredLanguage，值为空时将users.locale置为NULL）
+func (s *Storage) UpdateUserProperties(userID string, properties map[string]string) error {
+	updates := make(map[string]any, len(properties))
+	for name, value := range properties {
+		switch name {
+		case yggdrasil.UserPropertyPreferredLanguage:
+			if value == "" {
+				updates["locale"] = nil
+			} else {
+				updates["locale"] = value
+			}
+		default:
+			return fmt.Errorf("%w: %s", storage.ErrUserPropertyNotSupported, name)
+		}
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+
+	result := s.db.Model(&User{}).Where("uid = ?", userID).Updates(updates)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update user properties: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		// 值未变化时MySQL也返回0行，确认用户是否存在
+		var count int64
+		if err := s.db.Model(&User{}).Where("uid = ?", userID).Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to update user properties: %w", err)
+		}
+		if count == 0 {
+			return storage.ErrUserNotFound
+		}
+	}
+	return nil
+}
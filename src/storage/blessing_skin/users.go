@@ -7,6 +7,7 @@ import (
 	"crypto/sha512"
 	"crypto/subtle"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -15,6 +16,7 @@ import (
 
 	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 // GetUserByID 根据用户ID获取用户（单查询优化版）
@@ -23,12 +25,13 @@ func (s *Storage) GetUserByID(userID string) (*yggdrasil.User, error) {
 	var results []struct {
 		UID        uint   `gorm:"column:uid"`
 		Email      string `gorm:"column:email"`
+		Permission int    `gorm:"column:permission"`
 		PlayerName string `gorm:"column:player_name"`
 		UUID       string `gorm:"column:uuid"`
 	}
 
 	err := s.db.Table("users u").
-		Select("u.uid, u.email, p.name as player_name, uuid.uuid").
+		Select("u.uid, u.email, u.permission, p.name as player_name, uuid.uuid").
 		Joins("LEFT JOIN players p ON u.uid = p.uid").
 		Joins("LEFT JOIN uuid ON p.name = uuid.name").
 		Where("u.uid = ?", userID).
@@ -81,10 +84,11 @@ func (s *Storage) GetUserByID(userID string) (*yggdrasil.User, error) {
 	}
 
 	return &yggdrasil.User{
-		ID:       fmt.Sprintf("%d", userInfo.UID),
-		Email:    userInfo.Email,
-		Password: "", // 不返回密码
-		Profiles: profiles,
+		ID:         fmt.Sprintf("%d", userInfo.UID),
+		Email:      userInfo.Email,
+		Password:   "", // 不返回密码
+		Profiles:   profiles,
+		Permission: userInfo.Permission,
 	}, nil
 }
 
@@ -94,12 +98,13 @@ func (s *Storage) GetUserByEmail(email string) (*yggdrasil.User, error) {
 	var results []struct {
 		UID        uint   `gorm:"column:uid"`
 		Email      string `gorm:"column:email"`
+		Permission int    `gorm:"column:permission"`
 		PlayerName string `gorm:"column:player_name"`
 		UUID       string `gorm:"column:uuid"`
 	}
 
 	err := s.db.Table("users u").
-		Select("u.uid, u.email, p.name as player_name, uuid.uuid").
+		Select("u.uid, u.email, u.permission, p.name as player_name, uuid.uuid").
 		Joins("LEFT JOIN players p ON u.uid = p.uid").
 		Joins("LEFT JOIN uuid ON p.name = uuid.name").
 		Where("u.email = ?", email).
@@ -152,25 +157,36 @@ func (s *Storage) GetUserByEmail(email string) (*yggdrasil.User, error) {
 	}
 
 	return &yggdrasil.User{
-		ID:       fmt.Sprintf("%d", userInfo.UID),
-		Email:    userInfo.Email,
-		Password: "", // 不返回密码
-		Profiles: profiles,
+		ID:         fmt.Sprintf("%d", userInfo.UID),
+		Email:      userInfo.Email,
+		Password:   "", // 不返回密码
+		Profiles:   profiles,
+		Permission: userInfo.Permission,
 	}, nil
 }
 
-// GetUserByPlayerName 根据角色名获取用户（单查询优化版）
+// GetUserByPlayerName 根据角色名获取用户（单查询优化版，不区分大小写）
 func (s *Storage) GetUserByPlayerName(playerName string) (*yggdrasil.User, error) {
+	// 解析角色名的存储形式
+	playerName, err := s.resolvePlayerName(playerName)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("player not found")
+		}
+		return nil, err
+	}
+
 	// 一次性查询用户信息、所有角色和UUID映射
 	var results []struct {
 		UID        uint   `gorm:"column:uid"`
 		Email      string `gorm:"column:email"`
+		Permission int    `gorm:"column:permission"`
 		PlayerName string `gorm:"column:player_name"`
 		UUID       string `gorm:"column:uuid"`
 	}
 
-	err := s.db.Table("players p1").
-		Select("u.uid, u.email, p2.name as player_name, uuid.uuid").
+	err = s.db.Table("players p1").
+		Select("u.uid, u.email, u.permission, p2.name as player_name, uuid.uuid").
 		Joins("JOIN users u ON p1.uid = u.uid").
 		Joins("LEFT JOIN players p2 ON u.uid = p2.uid").
 		Joins("LEFT JOIN uuid ON p2.name = uuid.name").
@@ -224,10 +240,11 @@ func (s *Storage) GetUserByPlayerName(playerName string) (*yggdrasil.User, error
 	}
 
 	return &yggdrasil.User{
-		ID:       fmt.Sprintf("%d", userInfo.UID),
-		Email:    userInfo.Email,
-		Password: "", // 不返回密码
-		Profiles: profiles,
+		ID:         fmt.Sprintf("%d", userInfo.UID),
+		Email:      userInfo.Email,
+		Password:   "", // 不返回密码
+		Profiles:   profiles,
+		Permission: userInfo.Permission,
 	}, nil
 }
 
@@ -237,12 +254,13 @@ func (s *Storage) GetUserByUUID(uuid string) (*yggdrasil.User, error) {
 	var results []struct {
 		UID        uint   `gorm:"column:uid"`
 		Email      string `gorm:"column:email"`
+		Permission int    `gorm:"column:permission"`
 		PlayerName string `gorm:"column:player_name"`
 		UUID       string `gorm:"column:uuid"`
 	}
 
 	err := s.db.Table("uuid u1").
-		Select("users.uid, users.email, p.name as player_name, u2.uuid").
+		Select("users.uid, users.email, users.permission, p.name as player_name, u2.uuid").
 		Joins("JOIN players p1 ON u1.name = p1.name").
 		Joins("JOIN users ON p1.uid = users.uid").
 		Joins("LEFT JOIN players p ON users.uid = p.uid").
@@ -297,10 +315,11 @@ func (s *Storage) GetUserByUUID(uuid string) (*yggdrasil.User, error) {
 	}
 
 	return &yggdrasil.User{
-		ID:       fmt.Sprintf("%d", userInfo.UID),
-		Email:    userInfo.Email,
-		Password: "", // 不返回密码
-		Profiles: profiles,
+		ID:         fmt.Sprintf("%d", userInfo.UID),
+		Email:      userInfo.Email,
+		Password:   "", // 不返回密码
+		Profiles:   profiles,
+		Permission: userInfo.Permission,
 	}, nil
 }
 
@@ -327,7 +346,15 @@ func (s *Storage) AuthenticateUser(username, password string) (*yggdrasil.User,
 			Where("u.email = ?", username).
 			Find(&results).Error
 	} else {
-		// 角色名登录
+		// 角色名登录（不区分大小写）
+		username, err = s.resolvePlayerName(username)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, fmt.Errorf("user not found")
+			}
+			return nil, err
+		}
+
 		err = s.db.Table("players p1").
 			Select("u.uid, u.email, u.password, u.permission, u.verified, p2.name as player_name, uuid.uuid").
 			Joins("JOIN users u ON p1.uid = u.uid").
@@ -401,10 +428,11 @@ func (s *Storage) AuthenticateUser(username, password string) (*yggdrasil.User,
 	}
 
 	return &yggdrasil.User{
-		ID:       fmt.Sprintf("%d", userInfo.UID),
-		Email:    userInfo.Email,
-		Password: "", // 认证后不返回密码
-		Profiles: profiles,
+		ID:         fmt.Sprintf("%d", userInfo.UID),
+		Email:      userInfo.Email,
+		Password:   "", // 认证后不返回密码
+		Profiles:   profiles,
+		Permission: userInfo.Permission,
 	}, nil
 }
 
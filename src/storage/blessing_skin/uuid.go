@@ -81,6 +81,15 @@ func (g *UUIDGenerator) GetOrCreateUUID(playerName string) (string, error) {
 		return "", err
 	}
 
+	// 角色可能在网站改过名，优先找回绑定的原UUID
+	boundUUID, recovered, err := g.recoverBoundUUID(playerName)
+	if err != nil {
+		return "", err
+	}
+	if recovered {
+		return boundUUID, nil
+	}
+
 	// 生成新UUID
 	newUUID, err := g.GenerateUUID(playerName)
 	if err != nil {
@@ -93,7 +102,10 @@ func (g *UUIDGenerator) GetOrCreateUUID(playerName string) (string, error) {
 		UUID: newUUID,
 	}
 
-	if err := g.storage.db.Create(&mapping).Error; err != nil {
+	err = g.storage.db.Transaction(func(tx *gorm.DB) error {
+		return createUUIDMappings(tx, []UUIDMapping{mapping})
+	})
+	if err != nil {
 		return "", err
 	}
 
@@ -149,28 +161,43 @@ func (g *UUIDGenerator) GetNameByUUID(uuid string) (string, error) {
 
 // UpdateUUIDMapping 更新UUID映射（仅在角色改名时使用）
 func (g *UUIDGenerator) UpdateUUIDMapping(oldName, newName string) error {
+	uuid, err := g.updateUUIDMapping(g.storage.db, oldName, newName)
+	if err != nil {
+		return err
+	}
+
+	g.cache.DeleteMapping(oldName, uuid)
+	g.cache.PutMapping(newName, uuid)
+	return nil
+}
+
+// updateUUIDMapping 在指定会话（可为事务）中更新UUID映射，返回被迁移的UUID（不更新缓存）
+func (g *UUIDGenerator) updateUUIDMapping(db *gorm.DB, oldName, newName string) (string, error) {
 	var mapping UUIDMapping
-	err := g.storage.db.Where("name = ?", oldName).First(&mapping).Error
+	err := db.Where("name = ?", oldName).First(&mapping).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return fmt.Errorf("UUID mapping not found for player: %s", oldName)
+			return "", fmt.Errorf("UUID mapping not found for player: %s", oldName)
 		}
-		return err
+		return "", err
 	}
 
-	// 检查新名称是否已被使用
+	// 检查新名称是否已被使用（排除自身，允许仅修改大小写）
 	var existingMapping UUIDMapping
-	err = g.storage.db.Where("name = ?", newName).First(&existingMapping).Error
+	err = db.Where("name = ? AND id <> ?", newName, mapping.ID).First(&existingMapping).Error
 	if err == nil {
-		return fmt.Errorf("player name already exists: %s", newName)
+		return "", fmt.Errorf("player name already exists: %s", newName)
 	}
 	if !errors.Is(err, gorm.ErrRecordNotFound) {
-		return err
+		return "", err
 	}
 
 	// 更新映射
 	mapping.Name = newName
-	return g.storage.db.Save(&mapping).Error
+	if err := db.Save(&mapping).Error; err != nil {
+		return "", err
+	}
+	return mapping.UUID, nil
 }
 
 // GetUUIDsByNames 批量获取UUID映射（带缓存，自动创建缺失的UUID）
@@ -223,6 +250,12 @@ func (g *UUIDGenerator) GetUUIDsByNames(names []string) (map[string]string, erro
 	if len(needCreateNames) > 0 {
 		var newMappings []UUIDMapping
 		for _, name := range needCreateNames {
+			// 角色可能在网站改过名，优先找回绑定的原UUID
+			if boundUUID, recovered, err := g.recoverBoundUUID(name); err == nil && recovered {
+				result[name] = boundUUID
+				continue
+			}
+
 			newUUID, err := g.GenerateUUID(name)
 			if err != nil {
 				continue // 跳过生成失败的UUID
@@ -240,7 +273,9 @@ func (g *UUIDGenerator) GetUUIDsByNames(names []string) (map[string]string, erro
 
 		// 批量插入到数据库
 		if len(newMappings) > 0 {
-			err = g.storage.db.Create(&newMappings).Error
+			err = g.storage.db.Transaction(func(tx *gorm.DB) error {
+				return createUUIDMappings(tx, newMappings)
+			})
 			if err != nil {
 				// 记录错误但不影响返回结果
 				fmt.Printf("⚠️  Failed to batch create UUID mappings: %v\n", err)
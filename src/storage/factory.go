@@ -40,7 +40,8 @@ func (f *DefaultStorageFactory) GetSupportedTypes() []string {
 // createFileStorage 创建文件存储
 func (f *DefaultStorageFactory) createFileStorage(config *config.StorageConfig, textureConfig *config.TextureConfig) (storage.Storage, error) {
 	options := map[string]any{
-		"data_dir": config.FileOptions.DataDir,
+		"data_dir":       config.FileOptions.DataDir,
+		"uuid_algorithm": config.FileOptions.UUIDAlgorithm,
 	}
 	return file.NewStorage(options, textureConfig)
 }
@@ -60,6 +61,13 @@ func (f *DefaultStorageFactory) createBlessingSkinStorage(config *config.Storage
 		"salt":                      config.BlessingSkinOptions.Security.Salt,
 		"pwd_method":                config.BlessingSkinOptions.Security.PwdMethod,
 		"app_key":                   config.BlessingSkinOptions.Security.AppKey,
+		"reconcile_enabled":         config.BlessingSkinOptions.Reconcile.Enabled,
+		"reconcile_interval":        config.BlessingSkinOptions.Reconcile.Interval,
+		"reconcile_auto_apply":      config.BlessingSkinOptions.Reconcile.AutoApply,
+		"reconcile_prune_orphans":   config.BlessingSkinOptions.Reconcile.PruneOrphans,
+		"reconcile_report_dir":      config.BlessingSkinOptions.Reconcile.ReportDir,
+		"change_feed_enabled":       config.BlessingSkinOptions.ChangeFeed.Enabled,
+		"change_feed_interval":      config.BlessingSkinOptions.ChangeFeed.Interval,
 	}
 
 	// 准备材质配置
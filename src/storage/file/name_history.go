@@ -0,0 +1,241 @@
+// Package file 文件存储角色名称历史管理
+package file
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	storage "yggdrasil-api-go/src/storage/interface"
+	"yggdrasil-api-go/src/utils"
+	"yggdrasil-api-go/src/yggdrasil"
+
+	"github.com/bytedance/sonic"
+)
+
+// FileNameHistory 文件存储的角色名称历史结构
+type FileNameHistory struct {
+	UUID        string `json:"uuid"`
+	Name        string `json:"name"`
+	ChangedToAt int64  `json:"changed_to_at"` // 毫秒时间戳，0表示初始名称
+}
+
+// loadNameHistory 加载角色名称历史数据
+func (s *Storage) loadNameHistory() error {
+	historyFile := filepath.Join(s.dataDir, "name_history.json")
+
+	// 如果文件不存在，使用空历史
+	if _, err := os.Stat(historyFile); os.IsNotExist(err) {
+		return nil
+	}
+
+	data, err := os.ReadFile(historyFile)
+	if err != nil {
+		return err
+	}
+
+	var records []*FileNameHistory
+	if err := sonic.Unmarshal(data, &records); err != nil {
+		return err
+	}
+
+	// 加载到缓存
+	for _, record := range records {
+		uuid := utils.RemoveUUIDHyphens(record.UUID)
+		s.nameHistory[uuid] = append(s.nameHistory[uuid], yggdrasil.NameHistory{
+			Name:        record.Name,
+			ChangedToAt: record.ChangedToAt,
+		})
+	}
+
+	// 按时间升序排列
+	for _, history := range s.nameHistory {
+		sort.SliceStable(history, func(i, j int) bool {
+			return history[i].ChangedToAt < history[j].ChangedToAt
+		})
+	}
+
+	return nil
+}
+
+// saveNameHistory 保存角色名称历史数据
+func (s *Storage) saveNameHistory() error {
+	records := make([]*FileNameHistory, 0, len(s.nameHistory))
+	for uuid, history := range s.nameHistory {
+		for _, entry := range history {
+			records = append(records, &FileNameHistory{
+				UUID:        uuid,
+				Name:        entry.Name,
+				ChangedToAt: entry.ChangedToAt,
+			})
+		}
+	}
+
+	data, err := sonic.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	historyFile := filepath.Join(s.dataDir, "name_history.json")
+	return os.WriteFile(historyFile, data, 0644)
+}
+
+// getNameHistory 获取角色名称历史，未改过名的角色只有当前名称（调用方需持有锁）
+func (s *Storage) getNameHistory(player *FilePlayer) []yggdrasil.NameHistory {
+	if history, exists := s.nameHistory[player.UUID]; exists && len(history) > 0 {
+		return history
+	}
+	return []yggdrasil.NameHistory{{Name: player.Name}}
+}
+
+// renamePlayer 角色改名并记录名称历史（调用方需持有写锁）
+func (s *Storage) renamePlayer(player *FilePlayer, newName string) error {
+	// 检查新名称是否与其他角色冲突（不区分大小写，允许仅修改大小写）
+	for uuid, p := range s.players {
+		if uuid != player.UUID && strings.EqualFold(p.Name, newName) {
+			return storage.ErrProfileNameExists
+		}
+	}
+
+	if player.Name == newName {
+		return nil
+	}
+
+	// 首次改名时补录初始名称
+	history := s.getNameHistory(player)
+	s.nameHistory[player.UUID] = append(history, yggdrasil.NameHistory{
+		Name:        newName,
+		ChangedToAt: time.Now().UnixMilli(),
+	})
+
+	// 迁移UUID映射，保持UUID不变
+	s.renameUUIDMapping(player.Name, newName, player.UUID)
+
+	player.Name = newName
+	player.LastModify = time.Now().Format("2006-01-02 15:04:05")
+
+	if err := s.saveNameHistory(); err != nil {
+		return err
+	}
+	if err := s.saveUUIDMappings(); err != nil {
+		return err
+	}
+	return s.savePlayers()
+}
+
+// RenameProfile 角色改名（保持UUID不变并记录名称历史）
+func (s *Storage) RenameProfile(uuid, newName string, cooldown time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	player, exists := s.players[utils.RemoveUUIDHyphens(uuid)]
+	if !exists {
+		return storage.ErrProfileNotFound
+	}
+
+	// 在写锁内检查改名冷却时间，避免并发请求同时通过检查
+	history := s.getNameHistory(player)
+	if last := history[len(history)-1]; cooldown > 0 && last.ChangedToAt > 0 {
+		if time.Since(time.UnixMilli(last.ChangedToAt)) < cooldown {
+			return storage.ErrRenameCooldown
+		}
+	}
+
+	return s.renamePlayer(player, newName)
+}
+
+// GetProfileNameHistory 获取角色名称历史
+func (s *Storage) GetProfileNameHistory(uuid string) ([]yggdrasil.NameHistory, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	player, exists := s.players[utils.RemoveUUIDHyphens(uuid)]
+	if !exists {
+		return nil, storage.ErrProfileNotFound
+	}
+
+	history := s.getNameHistory(player)
+	result := make([]yggdrasil.NameHistory, len(history))
+	copy(result, history)
+	return result, nil
+}
+
+// GetProfileByNameAt 根据指定时间点使用的名称获取角色
+// 多个角色匹配时优先有改名历史的角色，其次取最早创建的角色，保证结果稳定
+func (s *Storage) GetProfileByNameAt(name string, at time.Time) (*yggdrasil.Profile, error) {
+	s.mu.RLock()
+	var found *FilePlayer
+	foundRenamed := false
+	for _, player := range s.players {
+		renamed := len(s.nameHistory[player.UUID]) > 0
+		if !strings.EqualFold(yggdrasil.NameAt(s.getNameHistory(player), nameSince(player, renamed), at), name) {
+			continue
+		}
+		if found == nil || (renamed && !foundRenamed) || (renamed == foundRenamed && player.PID < found.PID) {
+			found = player
+			foundRenamed = renamed
+		}
+	}
+	s.mu.RUnlock()
+
+	if found == nil {
+		return nil, fmt.Errorf("profile not found")
+	}
+
+	return s.GetProfileByUUID(found.UUID)
+}
+
+// nameSince 角色当前名称可确认使用的最早时间
+// 文件存储不记录角色创建时间，从未改名的角色以最后修改时间为界，避免后来注册的角色匹配其存在之前的时间点
+func nameSince(player *FilePlayer, renamed bool) time.Time {
+	if renamed {
+		return time.Time{}
+	}
+	since, err := time.ParseInLocation("2006-01-02 15:04:05", player.LastModify, time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+	return since
+}
+
+// FindProfileNameConflicts 查找仅大小写不同的重名角色
+func (s *Storage) FindProfileNameConflicts() ([]*storage.ProfileNameConflict, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	groups := make(map[string][]*FilePlayer)
+	for _, player := range s.players {
+		key := strings.ToLower(player.Name)
+		groups[key] = append(groups[key], player)
+	}
+
+	var conflicts []*storage.ProfileNameConflict
+	for name, players := range groups {
+		if len(players) < 2 {
+			continue
+		}
+
+		sort.Slice(players, func(i, j int) bool {
+			return players[i].PID < players[j].PID
+		})
+
+		conflict := &storage.ProfileNameConflict{Name: name}
+		for _, player := range players {
+			conflict.Profiles = append(conflict.Profiles, &yggdrasil.Profile{
+				ID:         player.UUID,
+				Name:       player.Name,
+				Properties: []yggdrasil.ProfileProperty{},
+			})
+		}
+		conflicts = append(conflicts, conflict)
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Name < conflicts[j].Name
+	})
+
+	return conflicts, nil
+}
@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	storage "yggdrasil-api-go/src/storage/interface"
+	"yggdrasil-api-go/src/utils"
 	"yggdrasil-api-go/src/yggdrasil"
 
 	"github.com/bytedance/sonic"
@@ -32,10 +34,39 @@ func (s *Storage) loadPlayers() error {
 		return err
 	}
 
-	// 加载到缓存
+	// 规范化UUID：统一为无符号格式，先登记所有已有UUID，再为缺失UUID的角色按配置算法生成
+	// （分两遍处理，生成时才能检测到与文件中靠后角色的UUID冲突）
+	changed := false
+	var missing []*FilePlayer
 	for _, player := range players {
+		uuid := utils.RemoveUUIDHyphens(player.UUID)
+		if uuid == "" {
+			missing = append(missing, player)
+			continue
+		}
+		if mappedUUID, exists := s.uuidMappings[player.Name]; !exists || mappedUUID != uuid {
+			s.uuidMappings[player.Name] = uuid
+			changed = true
+		}
+		if uuid != player.UUID {
+			player.UUID = uuid
+			changed = true
+		}
+		s.players[uuid] = player
+	}
+
+	for _, player := range missing {
+		// 遗留映射的UUID已被其他角色使用时丢弃该映射，重新生成
+		if mappedUUID, exists := s.uuidMappings[player.Name]; exists && s.players[mappedUUID] != nil {
+			delete(s.uuidMappings, player.Name)
+		}
+		player.UUID = s.getOrCreateUUID(player.Name)
 		s.players[player.UUID] = player
-		// 更新用户角色映射
+		changed = true
+	}
+
+	// 更新用户角色映射
+	for _, player := range players {
 		for email, user := range s.users {
 			if user.UID == player.UID {
 				s.userProfiles[email] = append(s.userProfiles[email], player.UUID)
@@ -44,6 +75,14 @@ func (s *Storage) loadPlayers() error {
 		}
 	}
 
+	// 持久化规范化后的UUID
+	if changed {
+		if err := s.saveUUIDMappings(); err != nil {
+			return err
+		}
+		return s.savePlayers()
+	}
+
 	return nil
 }
 
@@ -71,7 +110,6 @@ func (s *Storage) createDefaultPlayers() error {
 			PID:        1,
 			UID:        1,
 			Name:       "TestPlayer",
-			UUID:       "550e8400-e29b-41d4-a716-446655440000",
 			SkinTID:    0,
 			CapeTID:    0,
 			LastModify: time.Now().Format("2006-01-02 15:04:05"),
@@ -80,7 +118,6 @@ func (s *Storage) createDefaultPlayers() error {
 			PID:        2,
 			UID:        2,
 			Name:       "User2Player",
-			UUID:       "550e8400-e29b-41d4-a716-446655440001",
 			SkinTID:    0,
 			CapeTID:    0,
 			LastModify: time.Now().Format("2006-01-02 15:04:05"),
@@ -89,7 +126,6 @@ func (s *Storage) createDefaultPlayers() error {
 			PID:        3,
 			UID:        3,
 			Name:       "AdminPlayer",
-			UUID:       "550e8400-e29b-41d4-a716-446655440002",
 			SkinTID:    0,
 			CapeTID:    0,
 			LastModify: time.Now().Format("2006-01-02 15:04:05"),
@@ -97,9 +133,14 @@ func (s *Storage) createDefaultPlayers() error {
 	}
 
 	for _, player := range testPlayers {
+		player.UUID = s.getOrCreateUUID(player.Name)
 		s.players[player.UUID] = player
 	}
 
+	if err := s.saveUUIDMappings(); err != nil {
+		return err
+	}
+
 	return s.savePlayers()
 }
 
@@ -108,6 +149,7 @@ func (s *Storage) GetProfileByUUID(uuid string) (*yggdrasil.Profile, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	uuid = utils.RemoveUUIDHyphens(uuid)
 	if player, exists := s.players[uuid]; exists {
 		// 获取角色的材质信息
 		textures, err := s.GetPlayerTextures(uuid)
@@ -152,52 +194,65 @@ func (s *Storage) GetProfileByUUID(uuid string) (*yggdrasil.Profile, error) {
 	return nil, fmt.Errorf("profile not found")
 }
 
-// GetProfileByName 根据角色名获取角色
+// findPlayerByName 根据角色名查找角色（不区分大小写，优先精确匹配；调用方需持有锁）
+func (s *Storage) findPlayerByName(name string) *FilePlayer {
+	var found *FilePlayer
+	for _, player := range s.players {
+		if player.Name == name {
+			return player
+		}
+		// 存在大小写冲突时取最早创建的角色，保证结果稳定
+		if strings.EqualFold(player.Name, name) && (found == nil || player.PID < found.PID) {
+			found = player
+		}
+	}
+	return found
+}
+
+// GetProfileByName 根据角色名获取角色（不区分大小写）
 func (s *Storage) GetProfileByName(name string) (*yggdrasil.Profile, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	for _, player := range s.players {
-		if player.Name == name {
-			// 获取角色的材质信息
-			textures, err := s.GetPlayerTextures(player.UUID)
-			if err != nil {
-				// 如果获取材质失败，仍然返回角色信息，但properties为空
-				return &yggdrasil.Profile{
-					ID:         player.UUID,
-					Name:       player.Name,
-					Properties: []yggdrasil.ProfileProperty{},
-				}, nil
-			}
-
-			// 提取皮肤和披风URL
-			var skinURL, capeURL string
-			var isSlim bool
+	if player := s.findPlayerByName(name); player != nil {
+		// 获取角色的材质信息
+		textures, err := s.GetPlayerTextures(player.UUID)
+		if err != nil {
+			// 如果获取材质失败，仍然返回角色信息，但properties为空
+			return &yggdrasil.Profile{
+				ID:         player.UUID,
+				Name:       player.Name,
+				Properties: []yggdrasil.ProfileProperty{},
+			}, nil
+		}
 
-			if skinInfo, exists := textures[storage.TextureTypeSkin]; exists {
-				skinURL = skinInfo.URL
-				if skinInfo.Metadata != nil {
-					isSlim = skinInfo.Metadata.Slim
-				}
-			}
+		// 提取皮肤和披风URL
+		var skinURL, capeURL string
+		var isSlim bool
 
-			if capeInfo, exists := textures[storage.TextureTypeCape]; exists {
-				capeURL = capeInfo.URL
+		if skinInfo, exists := textures[storage.TextureTypeSkin]; exists {
+			skinURL = skinInfo.URL
+			if skinInfo.Metadata != nil {
+				isSlim = skinInfo.Metadata.Slim
 			}
+		}
 
-			// 生成properties
-			properties, err := yggdrasil.GenerateProfileProperties(player.UUID, player.Name, skinURL, capeURL, isSlim)
-			if err != nil {
-				// 如果生成properties失败，返回空properties
-				properties = []yggdrasil.ProfileProperty{}
-			}
+		if capeInfo, exists := textures[storage.TextureTypeCape]; exists {
+			capeURL = capeInfo.URL
+		}
 
-			return &yggdrasil.Profile{
-				ID:         player.UUID,
-				Name:       player.Name,
-				Properties: properties,
-			}, nil
+		// 生成properties
+		properties, err := yggdrasil.GenerateProfileProperties(player.UUID, player.Name, skinURL, capeURL, isSlim)
+		if err != nil {
+			// 如果生成properties失败，返回空properties
+			properties = []yggdrasil.ProfileProperty{}
 		}
+
+		return &yggdrasil.Profile{
+			ID:         player.UUID,
+			Name:       player.Name,
+			Properties: properties,
+		}, nil
 	}
 
 	return nil, fmt.Errorf("profile not found")
@@ -210,15 +265,12 @@ func (s *Storage) GetProfilesByNames(names []string) ([]*yggdrasil.Profile, erro
 
 	var profiles []*yggdrasil.Profile
 	for _, name := range names {
-		for _, player := range s.players {
-			if player.Name == name {
-				profiles = append(profiles, &yggdrasil.Profile{
-					ID:         player.UUID,
-					Name:       player.Name,
-					Properties: []yggdrasil.ProfileProperty{}, // 初始化为空数组而不是nil
-				})
-				break
-			}
+		if player := s.findPlayerByName(name); player != nil {
+			profiles = append(profiles, &yggdrasil.Profile{
+				ID:         player.UUID,
+				Name:       player.Name,
+				Properties: []yggdrasil.ProfileProperty{}, // 初始化为空数组而不是nil
+			})
 		}
 	}
 
@@ -256,24 +308,32 @@ func (s *Storage) CreateProfile(userEmail string, profile *yggdrasil.Profile) er
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// 检查角色名是否已存在
+	// 检查角色名是否已存在（不区分大小写）
 	for _, player := range s.players {
-		if player.Name == profile.Name {
-			return fmt.Errorf("profile name already exists")
+		if strings.EqualFold(player.Name, profile.Name) {
+			return storage.ErrProfileNameExists
 		}
 	}
 
-	// 检查UUID是否已存在
-	if _, exists := s.players[profile.ID]; exists {
-		return fmt.Errorf("profile UUID already exists")
-	}
-
 	// 获取用户信息
 	user, exists := s.users[userEmail]
 	if !exists {
 		return fmt.Errorf("user not found")
 	}
 
+	// 确定角色UUID：未指定时按配置算法生成（同名角色复用已有映射）
+	profileID := utils.RemoveUUIDHyphens(profile.ID)
+	if profileID == "" {
+		profileID = s.getOrCreateUUID(profile.Name)
+	}
+
+	// 检查UUID是否已存在
+	if _, exists := s.players[profileID]; exists {
+		return fmt.Errorf("profile UUID already exists")
+	}
+	s.uuidMappings[profile.Name] = profileID
+	profile.ID = profileID
+
 	// 创建新角色
 	newPlayer := &FilePlayer{
 		PID:        len(s.players) + 1, // 简单的ID生成
@@ -288,6 +348,10 @@ func (s *Storage) CreateProfile(userEmail string, profile *yggdrasil.Profile) er
 	s.players[profile.ID] = newPlayer
 	s.userProfiles[userEmail] = append(s.userProfiles[userEmail], profile.ID)
 
+	if err := s.saveUUIDMappings(); err != nil {
+		return err
+	}
+
 	return s.savePlayers()
 }
 
@@ -296,22 +360,13 @@ func (s *Storage) UpdateProfile(profile *yggdrasil.Profile) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	profile.ID = utils.RemoveUUIDHyphens(profile.ID)
 	player, exists := s.players[profile.ID]
 	if !exists {
 		return fmt.Errorf("profile not found")
 	}
 
-	// 检查新名称是否与其他角色冲突
-	for uuid, p := range s.players {
-		if uuid != profile.ID && p.Name == profile.Name {
-			return fmt.Errorf("profile name already exists")
-		}
-	}
-
-	player.Name = profile.Name
-	player.LastModify = time.Now().Format("2006-01-02 15:04:05")
-
-	return s.savePlayers()
+	return s.renamePlayer(player, profile.Name)
 }
 
 // DeleteProfile 删除角色
@@ -319,6 +374,8 @@ func (s *Storage) DeleteProfile(uuid string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	uuid = utils.RemoveUUIDHyphens(uuid)
+
 	if _, exists := s.players[uuid]; !exists {
 		return fmt.Errorf("profile not found")
 	}
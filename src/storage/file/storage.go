@@ -7,12 +7,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"sync"
 	"time"
 
 	"yggdrasil-api-go/src/config"
 	storage "yggdrasil-api-go/src/storage/interface"
+	"yggdrasil-api-go/src/utils"
 	"yggdrasil-api-go/src/yggdrasil"
 
 	"github.com/bytedance/sonic"
@@ -22,6 +24,7 @@ import (
 type Storage struct {
 	dataDir       string                // 数据目录
 	textureConfig *config.TextureConfig // 材质配置
+	uuidAlgorithm string                // UUID生成算法：v3(离线模式兼容) | v4(随机)
 	mu            sync.RWMutex          // 读写锁
 
 	// 数据文件（仿照BlessingSkin表结构）
@@ -30,7 +33,13 @@ type Storage struct {
 	textures map[string]*FileTexture // 材质数据 (textures.json)
 
 	// 缓存映射
-	userProfiles map[string][]string // 用户角色映射缓存
+	userProfiles map[string][]string                // 用户角色映射缓存
+	uuidMappings map[string]string                  // 角色名到UUID的映射 (uuid.json)
+	nameHistory  map[string][]yggdrasil.NameHistory // 角色名称历史 (name_history.json)
+	twoFactor    map[string]*FileTwoFactor          // 用户ID到两步验证信息 (two_factor.json)
+
+	handlers   []func(storage.ChangeEvent) // 数据变更订阅者
+	handlersMu sync.RWMutex
 }
 
 // FileUser 文件存储的用户结构（对应BlessingSkin的users表）
@@ -41,6 +50,7 @@ type FileUser struct {
 	Nickname   string `json:"nickname"`
 	Score      int    `json:"score"`
 	Permission int    `json:"permission"`
+	Locale     string `json:"locale,omitempty"` // 首选语言（用户属性preferredLanguage）
 	Verified   bool   `json:"verified"`
 	RegisterAt string `json:"register_at"`
 	LastSignAt string `json:"last_sign_at"`
@@ -83,10 +93,11 @@ func (s *Storage) convertFileUserToYggdrasilUser(fileUser *FileUser) (*yggdrasil
 	}
 
 	return &yggdrasil.User{
-		ID:       fmt.Sprintf("%d", fileUser.UID),
-		Email:    fileUser.Email,
-		Password: fileUser.Password,
-		Profiles: profiles,
+		ID:         fmt.Sprintf("%d", fileUser.UID),
+		Email:      fileUser.Email,
+		Password:   fileUser.Password,
+		Profiles:   profiles,
+		Permission: fileUser.Permission,
 	}, nil
 }
 
@@ -105,8 +116,8 @@ func (s *Storage) convertYggdrasilUserToFileUser(user *yggdrasil.User) (*FileUse
 		Password:   user.Password,
 		Nickname:   user.Email, // 默认使用邮箱作为昵称
 		Score:      1000,       // 默认积分
-		Permission: 0,          // 默认权限
-		Verified:   true,       // 默认已验证
+		Permission: user.Permission,
+		Verified:   true, // 默认已验证
 		RegisterAt: time.Now().Format("2006-01-02 15:04:05"),
 		LastSignAt: time.Now().Format("2006-01-02 15:04:05"),
 	}, nil
@@ -119,13 +130,22 @@ func NewStorage(options map[string]any, textureConfig *config.TextureConfig) (*S
 		dataDir = dir
 	}
 
+	uuidAlgorithm := "v3" // 默认使用v3算法
+	if algorithm, ok := options["uuid_algorithm"].(string); ok && algorithm == "v4" {
+		uuidAlgorithm = algorithm
+	}
+
 	storage := &Storage{
 		dataDir:       dataDir,
 		textureConfig: textureConfig,
+		uuidAlgorithm: uuidAlgorithm,
 		users:         make(map[string]*FileUser),
 		players:       make(map[string]*FilePlayer),
 		textures:      make(map[string]*FileTexture),
 		userProfiles:  make(map[string][]string),
+		uuidMappings:  make(map[string]string),
+		nameHistory:   make(map[string][]yggdrasil.NameHistory),
+		twoFactor:     make(map[string]*FileTwoFactor),
 	}
 
 	// 创建必要的目录
@@ -165,6 +185,21 @@ func (s *Storage) loadData() error {
 		return err
 	}
 
+	// 加载UUID映射数据
+	if err := s.loadUUIDMappings(); err != nil {
+		return err
+	}
+
+	// 加载角色名称历史
+	if err := s.loadNameHistory(); err != nil {
+		return err
+	}
+
+	// 加载两步验证数据
+	if err := s.loadTwoFactor(); err != nil {
+		return err
+	}
+
 	// 加载角色数据
 	if err := s.loadPlayers(); err != nil {
 		return err
@@ -269,6 +304,8 @@ func (s *Storage) GetUserByUUID(uuid string) (*yggdrasil.User, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	uuid = utils.RemoveUUIDHyphens(uuid)
+
 	// 先通过UUID找到对应的角色
 	var targetPlayer *FilePlayer
 	for _, player := range s.players {
@@ -310,6 +347,8 @@ func (s *Storage) GetUserProfiles(userUUID string) ([]*yggdrasil.Profile, error)
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	userUUID = utils.RemoveUUIDHyphens(userUUID)
+
 	// 先通过UUID找到对应的角色，获取UID
 	var targetUID int
 	for _, player := range s.players {
@@ -346,7 +385,7 @@ func (s *Storage) GetPlayerTextures(playerUUID string) (map[storage.TextureType]
 	textures := make(map[storage.TextureType]*storage.TextureInfo)
 
 	// 查找角色
-	player, exists := s.players[playerUUID]
+	player, exists := s.players[utils.RemoveUUIDHyphens(playerUUID)]
 	if !exists {
 		return textures, nil // 角色不存在，返回空材质
 	}
@@ -402,3 +441,30 @@ func parseTime(timeStr string) time.Time {
 func (s *Storage) GetSignatureKeyPair() (privateKey string, publicKey string, err error) {
 	return "", "", fmt.Errorf("signature key pair not available in file storage, use config file")
 }
+
+// Subscribe 订阅数据变更事件（用户更新或删除时通知，回调在修改数据的协程中同步执行）
+func (s *Storage) Subscribe(handler func(storage.ChangeEvent)) {
+	s.handlersMu.Lock()
+	defer s.handlersMu.Unlock()
+	s.handlers = append(s.handlers, handler)
+}
+
+// notify 分发数据变更事件（调用方不应持有s.mu，订阅者可能回调存储）
+func (s *Storage) notify(event storage.ChangeEvent) {
+	s.handlersMu.RLock()
+	handlers := slices.Clone(s.handlers)
+	s.handlersMu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
+
+// notifyUserChanged 通知订阅者用户已变更（缓存的用户信息失效）
+func (s *Storage) notifyUserChanged(uid int) {
+	s.notify(storage.ChangeEvent{
+		Type:      storage.ChangeUser,
+		UserID:    strconv.Itoa(uid),
+		ChangedAt: time.Now(),
+	})
+}
@@ -0,0 +1,120 @@
+// Package file 文件存储两步验证管理
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	storage "yggdrasil-api-go/src/storage/interface"
+
+	"github.com/bytedance/sonic"
+)
+
+// FileTwoFactor 文件存储的两步验证结构
+type FileTwoFactor struct {
+	UID           string   `json:"uid"`
+	Secret        string   `json:"secret"` // 加密后的TOTP密钥
+	Enabled       bool     `json:"enabled"`
+	RecoveryCodes []string `json:"recovery_codes"` // 未使用的恢复码哈希
+	LastStep      int64    `json:"last_step"`
+	UpdatedAt     string   `json:"updated_at"`
+}
+
+// loadTwoFactor 加载两步验证数据
+func (s *Storage) loadTwoFactor() error {
+	twoFactorFile := filepath.Join(s.dataDir, "two_factor.json")
+
+	// 如果文件不存在，没有用户登记两步验证
+	if _, err := os.Stat(twoFactorFile); os.IsNotExist(err) {
+		return nil
+	}
+
+	data, err := os.ReadFile(twoFactorFile)
+	if err != nil {
+		return err
+	}
+
+	var records []*FileTwoFactor
+	if err := sonic.Unmarshal(data, &records); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		s.twoFactor[record.UID] = record
+	}
+	return nil
+}
+
+// saveTwoFactor 保存两步验证数据（调用方需持有写锁）
+func (s *Storage) saveTwoFactor() error {
+	records := make([]*FileTwoFactor, 0, len(s.twoFactor))
+	for _, record := range s.twoFactor {
+		records = append(records, record)
+	}
+
+	data, err := sonic.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// 文件包含加密的密钥和恢复码哈希，只允许所有者读写
+	twoFactorFile := filepath.Join(s.dataDir, "two_factor.json")
+	return os.WriteFile(twoFactorFile, data, 0600)
+}
+
+// GetTwoFactor 获取用户的两步验证信息
+func (s *Storage) GetTwoFactor(userID string) (*storage.TwoFactor, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, exists := s.twoFactor[userID]
+	if !exists {
+		return nil, storage.ErrTwoFactorNotFound
+	}
+
+	return &storage.TwoFactor{
+		UserID:        record.UID,
+		Secret:        record.Secret,
+		Enabled:       record.Enabled,
+		RecoveryCodes: append([]string(nil), record.RecoveryCodes...),
+		LastStep:      record.LastStep,
+		UpdatedAt:     parseTime(record.UpdatedAt),
+	}, nil
+}
+
+// SaveTwoFactor 保存用户的两步验证信息
+func (s *Storage) SaveTwoFactor(twoFactor *storage.TwoFactor) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.findUserByID(twoFactor.UserID) == nil {
+		return storage.ErrUserNotFound
+	}
+
+	updatedAt := twoFactor.UpdatedAt
+	if updatedAt.IsZero() {
+		updatedAt = time.Now()
+	}
+	s.twoFactor[twoFactor.UserID] = &FileTwoFactor{
+		UID:           twoFactor.UserID,
+		Secret:        twoFactor.Secret,
+		Enabled:       twoFactor.Enabled,
+		RecoveryCodes: append([]string{}, twoFactor.RecoveryCodes...),
+		LastStep:      twoFactor.LastStep,
+		UpdatedAt:     updatedAt.Format("2006-01-02 15:04:05"),
+	}
+	return s.saveTwoFactor()
+}
+
+// DeleteTwoFactor 删除用户的两步验证信息
+func (s *Storage) DeleteTwoFactor(userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.twoFactor[userID]; !exists {
+		return nil
+	}
+	delete(s.twoFactor, userID)
+	return s.saveTwoFactor()
+}
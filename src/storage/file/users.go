@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 
+	storage "yggdrasil-api-go/src/storage/interface"
 	"yggdrasil-api-go/src/yggdrasil"
 
 	"github.com/bytedance/sonic"
@@ -119,15 +121,8 @@ func (s *Storage) GetUserByPlayerName(playerName string) (*yggdrasil.User, error
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	// 先通过角色名找到对应的角色
-	var targetPlayer *FilePlayer
-	for _, player := range s.players {
-		if player.Name == playerName {
-			targetPlayer = player
-			break
-		}
-	}
-
+	// 先通过角色名找到对应的角色（不区分大小写）
+	targetPlayer := s.findPlayerByName(playerName)
 	if targetPlayer == nil {
 		return nil, fmt.Errorf("player not found")
 	}
@@ -177,33 +172,55 @@ func (s *Storage) CreateUser(user *yggdrasil.User) error {
 	return s.saveUsers()
 }
 
-// UpdateUser 更新用户信息
+// UpdateUser 更新用户信息（通知订阅者用户已变更）
 func (s *Storage) UpdateUser(user *yggdrasil.User) error {
+	uid, err := s.updateUser(user)
+	if err != nil {
+		return err
+	}
+	s.notifyUserChanged(uid)
+	return nil
+}
+
+// updateUser 更新用户信息，返回用户UID
+func (s *Storage) updateUser(user *yggdrasil.User) (int, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, exists := s.users[user.Email]; !exists {
-		return fmt.Errorf("user not found")
+	existing, exists := s.users[user.Email]
+	if !exists {
+		return 0, fmt.Errorf("user not found")
 	}
 
 	fileUser, err := s.convertYggdrasilUserToFileUser(user)
 	if err != nil {
-		return err
+		return 0, err
 	}
+	fileUser.Locale = existing.Locale // yggdrasil.User不包含用户属性
 
 	s.users[user.Email] = fileUser
-	return s.saveUsers()
+	return fileUser.UID, s.saveUsers()
 }
 
-// DeleteUser 删除用户
+// DeleteUser 删除用户（通知订阅者用户已变更）
 func (s *Storage) DeleteUser(email string) error {
+	uid, err := s.deleteUser(email)
+	if err != nil {
+		return err
+	}
+	s.notifyUserChanged(uid)
+	return nil
+}
+
+// deleteUser 删除用户及其角色，返回用户UID
+func (s *Storage) deleteUser(email string) (int, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	// 获取用户信息
 	user, exists := s.users[email]
 	if !exists {
-		return fmt.Errorf("user not found")
+		return 0, fmt.Errorf("user not found")
 	}
 
 	// 删除用户的所有角色
@@ -219,10 +236,69 @@ func (s *Storage) DeleteUser(email string) error {
 	// 删除用户
 	delete(s.users, email)
 
+	// 删除用户的两步验证信息
+	if _, exists := s.twoFactor[strconv.Itoa(user.UID)]; exists {
+		delete(s.twoFactor, strconv.Itoa(user.UID))
+		if err := s.saveTwoFactor(); err != nil {
+			return user.UID, err
+		}
+	}
+
 	// 保存数据
+	return user.UID, s.saveUsers()
+}
+
+// GetUserProperties 获取用户属性
+func (s *Storage) GetUserProperties(userID string) ([]yggdrasil.ProfileProperty, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user := s.findUserByID(userID)
+	if user == nil {
+		return nil, storage.ErrUserNotFound
+	}
+
+	properties := []yggdrasil.ProfileProperty{}
+	if user.Locale != "" {
+		properties = append(properties, yggdrasil.ProfileProperty{
+			Name:  yggdrasil.UserPropertyPreferredLanguage,
+			Value: user.Locale,
+		})
+	}
+	return properties, nil
+}
+
+// UpdateUserProperties 更新用户属性（只支持preferredLanguage）
+func (s *Storage) UpdateUserProperties(userID string, properties map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user := s.findUserByID(userID)
+	if user == nil {
+		return storage.ErrUserNotFound
+	}
+
+	for name := range properties {
+		if name != yggdrasil.UserPropertyPreferredLanguage {
+			return fmt.Errorf("%w: %s", storage.ErrUserPropertyNotSupported, name)
+		}
+	}
+	if locale, ok := properties[yggdrasil.UserPropertyPreferredLanguage]; ok {
+		user.Locale = locale
+	}
 	return s.saveUsers()
 }
 
+// findUserByID 根据UID查找用户（调用方需持有锁）
+func (s *Storage) findUserByID(userID string) *FileUser {
+	for _, user := range s.users {
+		if strconv.Itoa(user.UID) == userID {
+			return user
+		}
+	}
+	return nil
+}
+
 // ListUsers 列出所有用户（分页）
 func (s *Storage) ListUsers(offset, limit int) ([]*yggdrasil.User, int, error) {
 	s.mu.RLock()
@@ -0,0 +1,99 @@
+// Package file 文件存储UUID映射管理
+package file
+
+import (
+	"os"
+	"path/filepath"
+
+	"yggdrasil-api-go/src/utils"
+
+	"github.com/bytedance/sonic"
+)
+
+// FileUUIDMapping 文件存储的UUID映射结构（对应BlessingSkin的uuid表）
+type FileUUIDMapping struct {
+	Name string `json:"name"`
+	UUID string `json:"uuid"`
+}
+
+// loadUUIDMappings 加载UUID映射数据
+func (s *Storage) loadUUIDMappings() error {
+	uuidFile := filepath.Join(s.dataDir, "uuid.json")
+
+	// 如果文件不存在，使用空映射（加载角色时补全）
+	if _, err := os.Stat(uuidFile); os.IsNotExist(err) {
+		return nil
+	}
+
+	data, err := os.ReadFile(uuidFile)
+	if err != nil {
+		return err
+	}
+
+	var mappings []*FileUUIDMapping
+	if err := sonic.Unmarshal(data, &mappings); err != nil {
+		return err
+	}
+
+	// 加载到缓存（统一为无符号格式）
+	for _, mapping := range mappings {
+		s.uuidMappings[mapping.Name] = utils.RemoveUUIDHyphens(mapping.UUID)
+	}
+
+	return nil
+}
+
+// saveUUIDMappings 保存UUID映射数据
+func (s *Storage) saveUUIDMappings() error {
+	mappings := make([]*FileUUIDMapping, 0, len(s.uuidMappings))
+	for name, uuid := range s.uuidMappings {
+		mappings = append(mappings, &FileUUIDMapping{
+			Name: name,
+			UUID: uuid,
+		})
+	}
+
+	data, err := sonic.MarshalIndent(mappings, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	uuidFile := filepath.Join(s.dataDir, "uuid.json")
+	return os.WriteFile(uuidFile, data, 0644)
+}
+
+// getOrCreateUUID 获取或创建角色名对应的UUID（调用方需持有写锁）
+func (s *Storage) getOrCreateUUID(playerName string) string {
+	if uuid, exists := s.uuidMappings[playerName]; exists {
+		return uuid
+	}
+
+	uuid := utils.GenerateProfileUUIDWithAlgorithm(playerName, s.uuidAlgorithm)
+
+	// v3算法下，改名后的旧名称可能被新角色使用，此时生成的UUID会与改名角色冲突，回退为随机UUID
+	if s.isUUIDInUse(uuid) {
+		uuid = utils.GenerateRandomUUID()
+	}
+
+	s.uuidMappings[playerName] = uuid
+	return uuid
+}
+
+// isUUIDInUse 检查UUID是否已被映射或角色占用（调用方需持有锁）
+func (s *Storage) isUUIDInUse(uuid string) bool {
+	if _, exists := s.players[uuid]; exists {
+		return true
+	}
+	for _, mappedUUID := range s.uuidMappings {
+		if mappedUUID == uuid {
+			return true
+		}
+	}
+	return false
+}
+
+// renameUUIDMapping 角色改名时迁移UUID映射，保持UUID不变（调用方需持有写锁）
+func (s *Storage) renameUUIDMapping(oldName, newName, uuid string) {
+	delete(s.uuidMappings, oldName)
+	s.uuidMappings[newName] = uuid
+}
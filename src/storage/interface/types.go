@@ -2,12 +2,24 @@
 package storage
 
 import (
+	"errors"
 	"time"
 
 	"yggdrasil-api-go/src/config"
 	"yggdrasil-api-go/src/yggdrasil"
 )
 
+// 预定义的存储错误
+var (
+	ErrProfileNotFound   = errors.New("profile not found")
+	ErrProfileNameExists = errors.New("profile name already exists")
+	ErrRenameCooldown    = errors.New("profile was renamed recently")
+	ErrUserNotFound      = errors.New("user not found")
+
+	ErrUserPropertyNotSupported = errors.New("user property not supported")
+	ErrTwoFactorNotFound        = errors.New("two-factor authentication not enrolled")
+)
+
 // UserStorage 用户存储接口
 type UserStorage interface {
 	// GetUserByEmail 根据邮箱获取用户
@@ -44,6 +56,104 @@ type ProfileStorage interface {
 	GetUserProfiles(userUUID string) ([]*yggdrasil.Profile, error)
 }
 
+// ProfileNameStorage 角色名称管理接口
+type ProfileNameStorage interface {
+	// RenameProfile 角色改名（保持UUID不变并记录名称历史）
+	// cooldown>0时，距上次改名不足cooldown返回ErrRenameCooldown（与改名在同一事务或锁内检查）
+	RenameProfile(uuid, newName string, cooldown time.Duration) error
+
+	// GetProfileNameHistory 获取角色名称历史（按时间升序，首条为初始名称）
+	GetProfileNameHistory(uuid string) ([]yggdrasil.NameHistory, error)
+
+	// GetProfileByNameAt 根据指定时间点使用的名称获取角色
+	GetProfileByNameAt(name string, at time.Time) (*yggdrasil.Profile, error)
+
+	// FindProfileNameConflicts 查找仅大小写不同的重名角色
+	FindProfileNameConflicts() ([]*ProfileNameConflict, error)
+}
+
+// ProfileNameConflict 仅大小写不同的重名角色组
+type ProfileNameConflict struct {
+	Name     string               `json:"name"`     // 规范化（小写）后的角色名
+	Profiles []*yggdrasil.Profile `json:"profiles"` // 冲突的角色（按创建顺序，尚无UUID映射的角色ID为空）
+}
+
+// ChangeEventType 数据变更事件类型
+type ChangeEventType string
+
+const (
+	ChangeProfile ChangeEventType = "profile" // 角色信息或材质变更
+	ChangeTexture ChangeEventType = "texture" // 新增材质
+	ChangeOptions ChangeEventType = "options" // 站点配置变更
+	ChangeUser    ChangeEventType = "user"    // 用户密码、权限（封禁）变更或用户删除
+)
+
+// ChangeEvent 数据变更事件（用于缓存失效）
+type ChangeEvent struct {
+	Type        ChangeEventType
+	ProfileID   string    // 角色UUID（profile事件和使用该材质的texture事件，未生成映射时为空）
+	ProfileName string    // 角色名（profile事件和使用该材质的texture事件）
+	UserID      string    // 所属用户ID（profile、user事件和使用该材质的texture事件）
+	TextureHash string    // 材质哈希（texture事件）
+	OptionNames []string  // 变更的配置项（options事件）
+	ChangedAt   time.Time // 变更时间
+}
+
+// ChangeNotifier 数据变更通知接口（由能检测外部修改的存储实现，如blessing_skin）
+type ChangeNotifier interface {
+	// Subscribe 订阅变更事件（回调在轮询协程中同步执行，不应阻塞）
+	Subscribe(handler func(ChangeEvent))
+}
+
+// UserWatcher 限定用户变更检测范围的接口（由ChangeNotifier实现，只检测缓存中的用户，避免每次轮询扫描整个用户表）
+type UserWatcher interface {
+	// WatchUsers 设置需要检测变更的用户ID来源（每次轮询时调用，未设置时不检测用户变更）
+	WatchUsers(userIDs func() []string)
+}
+
+// TokensLimitProvider 提供每用户令牌数量限制的存储接口（由自带该配置的存储实现，如blessing_skin的ygg_tokens_limit）
+type TokensLimitProvider interface {
+	// GetTokensLimit 获取每用户令牌数量限制，未配置时返回false
+	GetTokensLimit() (int, bool)
+}
+
+// TokenExpirationProvider 提供令牌有效期的存储接口（如blessing_skin的ygg_token_expire_1/ygg_token_expire_2）
+type TokenExpirationProvider interface {
+	// GetTokenExpiration 获取令牌有效期和可刷新期限，未配置时返回false
+	GetTokenExpiration() (expiration, refreshExpiration time.Duration, ok bool)
+}
+
+// UserPropertiesStorage 用户属性存储接口（由支持用户属性的存储实现，如blessing_skin的users.locale）
+type UserPropertiesStorage interface {
+	// GetUserProperties 获取用户属性（未设置的属性不返回）
+	GetUserProperties(userID string) ([]yggdrasil.ProfileProperty, error)
+
+	// UpdateUserProperties 更新用户属性（属性名到属性值，值为空时删除该属性）
+	UpdateUserProperties(userID string, properties map[string]string) error
+}
+
+// TwoFactor 用户的两步验证信息
+type TwoFactor struct {
+	UserID        string
+	Secret        string    // 加密后的TOTP密钥
+	Enabled       bool      // 是否已启用（登记后需提交一次验证码确认）
+	RecoveryCodes []string  // 未使用的恢复码哈希
+	LastStep      int64     // 最近一次通过验证的TOTP时间步（同一验证码不能重复使用）
+	UpdatedAt     time.Time // 更新时间
+}
+
+// TwoFactorStorage 两步验证存储接口（由支持两步验证的存储实现）
+type TwoFactorStorage interface {
+	// GetTwoFactor 获取用户的两步验证信息，未登记时返回ErrTwoFactorNotFound
+	GetTwoFactor(userID string) (*TwoFactor, error)
+
+	// SaveTwoFactor 保存用户的两步验证信息（已存在时覆盖）
+	SaveTwoFactor(twoFactor *TwoFactor) error
+
+	// DeleteTwoFactor 删除用户的两步验证信息（不存在时不报错）
+	DeleteTwoFactor(userID string) error
+}
+
 // TextureStorage 材质存储接口
 type TextureStorage interface {
 	// UploadTexture 上传材质文件
@@ -94,6 +204,7 @@ type TextureInfo struct {
 type Storage interface {
 	UserStorage
 	ProfileStorage
+	ProfileNameStorage
 	TextureStorage
 
 	// Close 关闭存储连接
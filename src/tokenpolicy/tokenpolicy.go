@@ -0,0 +1,126 @@
+// Package tokenpolicy 令牌策略（根据客户端、clientToken和账户角色决定令牌有效期）
+package tokenpolicy
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"yggdrasil-api-go/src/config"
+)
+
+// Request 签发令牌的请求信息
+type Request struct {
+	Agent               *Agent // 客户端信息（刷新请求不包含，为nil，此时以agent或clientToken为条件的策略不匹配）
+	ClientTokenProvided bool   // 登录时客户端是否提供了clientToken
+	Role                string // 账户角色
+}
+
+// Agent 客户端信息
+type Agent struct {
+	Name    string
+	Version int
+}
+
+// Lifetime 令牌有效期
+type Lifetime struct {
+	Policy            string        // 使用的策略名称（未匹配任何策略时为空）
+	Expiration        time.Duration // 有效期
+	RefreshExpiration time.Duration // 可刷新期限（不小于有效期）
+	Sliding           bool          // 刷新时是否重新计算可刷新期限
+}
+
+// Engine 令牌策略引擎
+type Engine struct {
+	policies []config.TokenPolicy
+}
+
+// New 创建令牌策略引擎（策略按顺序匹配）
+func New(policies []config.TokenPolicy) *Engine {
+	return &Engine{policies: policies}
+}
+
+// ForAuthenticate 获取登录签发的令牌有效期（使用第一条匹配的策略，未设置的有效期使用默认值）
+func (e *Engine) ForAuthenticate(req Request, expiration, refreshExpiration time.Duration) Lifetime {
+	for i := range e.policies {
+		if matches(&e.policies[i], req) {
+			return lifetime(&e.policies[i], expiration, refreshExpiration)
+		}
+	}
+	return lifetime(nil, expiration, refreshExpiration)
+}
+
+// ForRefresh 获取刷新签发的令牌有效期
+// 优先沿用签发旧令牌时的策略（账户角色仍须满足），否则按不含客户端信息的请求重新匹配
+func (e *Engine) ForRefresh(policy string, req Request, expiration, refreshExpiration time.Duration) Lifetime {
+	if policy != "" {
+		for i := range e.policies {
+			if e.policies[i].Name == policy && matchesRole(&e.policies[i], req.Role) {
+				return lifetime(&e.policies[i], expiration, refreshExpiration)
+			}
+		}
+	}
+	req.Agent = nil
+	return e.ForAuthenticate(req, expiration, refreshExpiration)
+}
+
+// matches 检查策略是否匹配请求
+func matches(policy *config.TokenPolicy, req Request) bool {
+	if len(policy.Agents) > 0 || policy.MinAgentVersion > 0 || policy.MaxAgentVersion > 0 {
+		if req.Agent == nil {
+			return false
+		}
+		if len(policy.Agents) > 0 && !slices.ContainsFunc(policy.Agents, func(name string) bool {
+			return strings.EqualFold(name, req.Agent.Name)
+		}) {
+			return false
+		}
+		if req.Agent.Version < policy.MinAgentVersion {
+			return false
+		}
+		if policy.MaxAgentVersion > 0 && req.Agent.Version > policy.MaxAgentVersion {
+			return false
+		}
+	}
+
+	switch policy.ClientToken {
+	case config.ClientTokenProvided:
+		if req.Agent == nil || !req.ClientTokenProvided {
+			return false
+		}
+	case config.ClientTokenGenerated:
+		if req.Agent == nil || req.ClientTokenProvided {
+			return false
+		}
+	}
+
+	return matchesRole(policy, req.Role)
+}
+
+// matchesRole 检查账户角色是否满足策略
+func matchesRole(policy *config.TokenPolicy, role string) bool {
+	return len(policy.Roles) == 0 || slices.Contains(policy.Roles, role)
+}
+
+// lifetime 计算策略的有效期（policy为nil时使用默认值）
+func lifetime(policy *config.TokenPolicy, expiration, refreshExpiration time.Duration) Lifetime {
+	result := Lifetime{
+		Expiration:        expiration,
+		RefreshExpiration: refreshExpiration,
+		Sliding:           true,
+	}
+	if policy != nil {
+		result.Policy = policy.Name
+		if policy.TokenExpiration > 0 {
+			result.Expiration = policy.TokenExpiration
+		}
+		if policy.TokenRefreshExpiration > 0 {
+			result.RefreshExpiration = policy.TokenRefreshExpiration
+		}
+		if policy.Sliding != nil {
+			result.Sliding = *policy.Sliding
+		}
+	}
+	result.RefreshExpiration = max(result.RefreshExpiration, result.Expiration)
+	return result
+}
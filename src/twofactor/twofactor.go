@@ -0,0 +1,316 @@
+// Package twofactor 两步验证（TOTP验证码附加在密码后提交，兼容只发送用户名和密码的启动器）
+package twofactor
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"yggdrasil-api-go/src/config"
+	storage "yggdrasil-api-go/src/storage/interface"
+	"yggdrasil-api-go/src/utils"
+	"yggdrasil-api-go/src/yggdrasil"
+)
+
+// 默认配置（与TwoFactorConfig的说明一致）
+const (
+	defaultSkew          = 1
+	defaultRecoveryCodes = 10
+)
+
+// 两步验证错误
+var (
+	ErrCodeRequired       = errors.New("two-factor code required")
+	ErrInvalidCode        = errors.New("invalid two-factor code")
+	ErrEnrollmentRequired = errors.New("two-factor enrollment required")
+	ErrNotEnrolled        = errors.New("two-factor authentication not enrolled")
+	ErrAlreadyEnabled     = errors.New("two-factor authentication already enabled")
+	ErrDisableNotAllowed  = errors.New("two-factor authentication is required for this role")
+)
+
+// Enrollment 登记两步验证时返回给用户的信息
+type Enrollment struct {
+	Secret string // base32编码的TOTP密钥（手动输入验证器应用）
+	URI    string // otpauth链接（可转换为二维码）
+}
+
+// Service 两步验证服务
+type Service struct {
+	storage       storage.TwoFactorStorage
+	key           []byte
+	issuer        string
+	requiredRoles []string
+	skew          int
+	recoveryCodes int
+	mu            sync.Mutex // 串行化验证和保存，同一验证码或恢复码并发提交时只有一次成功
+}
+
+// New 创建两步验证服务（存储不支持两步验证时返回错误）
+func New(cfg *config.Config, store storage.Storage) (*Service, error) {
+	twoFactorStorage, ok := store.(storage.TwoFactorStorage)
+	if !ok {
+		return nil, fmt.Errorf("storage %s does not support two-factor authentication", store.GetStorageType())
+	}
+
+	tf := cfg.Auth.TwoFactor
+	var key []byte
+	if tf.EncryptionKey != "" {
+		decoded, err := base64.StdEncoding.DecodeString(tf.EncryptionKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid two-factor encryption key: %w", err)
+		}
+		key = decoded
+	} else {
+		// 由JWT密钥派生（修改jwt_secret后已登记的密钥无法解密）
+		sum := sha256.Sum256([]byte("yggdrasil-two-factor:" + cfg.Auth.JWTSecret))
+		key = sum[:]
+	}
+
+	issuer := tf.Issuer
+	if issuer == "" {
+		issuer = cfg.Yggdrasil.Meta.ServerName
+	}
+	skew := defaultSkew
+	if tf.Skew != nil {
+		skew = *tf.Skew
+	}
+	recoveryCodes := defaultRecoveryCodes
+	if tf.RecoveryCodes != nil {
+		recoveryCodes = *tf.RecoveryCodes
+	}
+
+	return &Service{
+		storage:       twoFactorStorage,
+		key:           key,
+		issuer:        issuer,
+		requiredRoles: tf.RequiredRoles,
+		skew:          skew,
+		recoveryCodes: recoveryCodes,
+	}, nil
+}
+
+// SplitPassword 拆分"密码:验证码"形式的密码（验证码为6位数字或恢复码），不含验证码时ok为false
+func SplitPassword(password string) (base, code string, ok bool) {
+	index := strings.LastIndexByte(password, ':')
+	if index <= 0 {
+		return password, "", false
+	}
+	code = strings.ToLower(password[index+1:])
+	if !utils.IsTOTPCode(code) && !utils.IsRecoveryCode(code) {
+		return password, "", false
+	}
+	return password[:index], code, true
+}
+
+// Authenticate 验证用户名和密码，返回用户和附加在密码后的验证码
+// 优先按"密码:验证码"验证，失败时按完整密码验证（密码本身可能以冒号加数字结尾）
+func Authenticate(store storage.UserStorage, username, password string) (*yggdrasil.User, string, error) {
+	if base, code, ok := SplitPassword(password); ok {
+		if user, err := store.AuthenticateUser(username, base); err == nil {
+			return user, code, nil
+		}
+	}
+
+	user, err := store.AuthenticateUser(username, password)
+	if err != nil {
+		return nil, "", err
+	}
+	return user, "", nil
+}
+
+// RoleRequired 账户角色是否必须启用两步验证
+func (s *Service) RoleRequired(user *yggdrasil.User) bool {
+	return slices.Contains(s.requiredRoles, user.Role())
+}
+
+// Verify 登录时验证两步验证码（未启用两步验证的用户忽略验证码）
+func (s *Service) Verify(user *yggdrasil.User, code string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, err := s.storage.GetTwoFactor(user.ID)
+	if err != nil && !errors.Is(err, storage.ErrTwoFactorNotFound) {
+		return err
+	}
+	if record == nil || !record.Enabled {
+		if s.RoleRequired(user) {
+			return ErrEnrollmentRequired
+		}
+		return nil
+	}
+
+	if code == "" {
+		return ErrCodeRequired
+	}
+	return s.consume(record, code)
+}
+
+// Enroll 登记两步验证（生成新密钥，提交验证码确认后才会启用）
+func (s *Service) Enroll(user *yggdrasil.User) (*Enrollment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, err := s.storage.GetTwoFactor(user.ID)
+	if err != nil && !errors.Is(err, storage.ErrTwoFactorNotFound) {
+		return nil, err
+	}
+	if record != nil && record.Enabled {
+		return nil, ErrAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := utils.EncryptSecret(s.key, secret, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.storage.SaveTwoFactor(&storage.TwoFactor{
+		UserID:    user.ID,
+		Secret:    encrypted,
+		UpdatedAt: time.Now(),
+	}); err != nil {
+		return nil, err
+	}
+
+	return &Enrollment{
+		Secret: secret,
+		URI:    utils.TOTPURI(s.issuer, user.Email, secret),
+	}, nil
+}
+
+// Confirm 使用验证器应用生成的验证码确认登记，启用两步验证并返回恢复码
+func (s *Service) Confirm(user *yggdrasil.User, code string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, err := s.storage.GetTwoFactor(user.ID)
+	if errors.Is(err, storage.ErrTwoFactorNotFound) {
+		return nil, ErrNotEnrolled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if record.Enabled {
+		return nil, ErrAlreadyEnabled
+	}
+
+	step, ok := s.match(record, code)
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+
+	codes, hashes, err := s.newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	record.Enabled = true
+	record.RecoveryCodes = hashes
+	record.LastStep = step
+	record.UpdatedAt = time.Now()
+	if err := s.storage.SaveTwoFactor(record); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码（之前的恢复码全部失效）
+func (s *Service) RegenerateRecoveryCodes(user *yggdrasil.User) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, err := s.enabledRecord(user)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := s.newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	record.RecoveryCodes = hashes
+	record.UpdatedAt = time.Now()
+	if err := s.storage.SaveTwoFactor(record); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable 停用两步验证（账户角色必须启用两步验证时不允许）
+func (s *Service) Disable(user *yggdrasil.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.RoleRequired(user) {
+		return ErrDisableNotAllowed
+	}
+	return s.storage.DeleteTwoFactor(user.ID)
+}
+
+// enabledRecord 获取已启用的两步验证信息（调用方需持有锁）
+func (s *Service) enabledRecord(user *yggdrasil.User) (*storage.TwoFactor, error) {
+	record, err := s.storage.GetTwoFactor(user.ID)
+	if errors.Is(err, storage.ErrTwoFactorNotFound) {
+		return nil, ErrNotEnrolled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !record.Enabled {
+		return nil, ErrNotEnrolled
+	}
+	return record, nil
+}
+
+// consume 验证并消耗验证码或恢复码（调用方需持有锁）
+func (s *Service) consume(record *storage.TwoFactor, code string) error {
+	if utils.IsRecoveryCode(code) {
+		hash := utils.HashRecoveryCode(code)
+		index := slices.Index(record.RecoveryCodes, hash)
+		if index < 0 {
+			return ErrInvalidCode
+		}
+		record.RecoveryCodes = slices.Delete(record.RecoveryCodes, index, index+1)
+	} else {
+		step, ok := s.match(record, code)
+		if !ok {
+			return ErrInvalidCode
+		}
+		record.LastStep = step
+	}
+
+	record.UpdatedAt = time.Now()
+	return s.storage.SaveTwoFactor(record)
+}
+
+// match 验证TOTP验证码（已使用过的时间步及之前的验证码无效）
+func (s *Service) match(record *storage.TwoFactor, code string) (int64, bool) {
+	secret, err := utils.DecryptSecret(s.key, record.Secret, record.UserID)
+	if err != nil {
+		return 0, false
+	}
+	step, ok := utils.MatchTOTP(secret, code, time.Now(), s.skew)
+	if !ok || step <= record.LastStep {
+		return 0, false
+	}
+	return step, true
+}
+
+// newRecoveryCodes 生成恢复码及其哈希
+func (s *Service) newRecoveryCodes() ([]string, []string, error) {
+	codes, err := utils.GenerateRecoveryCodes(s.recoveryCodes)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = utils.HashRecoveryCode(code)
+	}
+	return codes, hashes, nil
+}
@@ -0,0 +1,145 @@
+// Package utils 缓存后端操作统计
+package utils
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// cacheLatencyBuckets 延迟直方图的桶上限（毫秒），超出最后一个桶的计入+Inf
+var cacheLatencyBuckets = []float64{0.1, 0.5, 1, 5, 10, 50, 100, 500, 1000}
+
+// cacheOpStats 单个缓存操作的统计
+type cacheOpStats struct {
+	count     int64
+	errors    int64
+	misses    int64 // 仅查询操作：未找到（不计入errors）
+	totalTime int64 // 纳秒
+	maxTime   int64 // 纳秒
+	buckets   []int64
+}
+
+// CacheMetrics 缓存后端操作统计（按缓存种类、后端类型和操作分别统计）
+type CacheMetrics struct {
+	ops sync.Map // cacheOpKey -> *cacheOpStats
+}
+
+// cacheOpKey 统计键
+type cacheOpKey struct {
+	kind    string // token、session
+	backend string // GetCacheType()
+	op      string
+}
+
+// GlobalCacheMetrics 全局缓存后端操作统计
+var GlobalCacheMetrics = &CacheMetrics{}
+
+// Record 记录一次缓存操作
+func (m *CacheMetrics) Record(kind, backend, op string, duration time.Duration, err error) {
+	stats := m.stats(kind, backend, op)
+	stats.record(duration)
+	if err != nil {
+		atomic.AddInt64(&stats.errors, 1)
+	}
+}
+
+// RecordLookup 记录一次查询操作（未找到计为miss而不是错误）
+func (m *CacheMetrics) RecordLookup(kind, backend, op string, duration time.Duration, found bool) {
+	stats := m.stats(kind, backend, op)
+	stats.record(duration)
+	if !found {
+		atomic.AddInt64(&stats.misses, 1)
+	}
+}
+
+// stats 获取（或创建）操作统计
+func (m *CacheMetrics) stats(kind, backend, op string) *cacheOpStats {
+	key := cacheOpKey{kind: kind, backend: backend, op: op}
+	if stats, ok := m.ops.Load(key); ok {
+		return stats.(*cacheOpStats)
+	}
+	stats, _ := m.ops.LoadOrStore(key, &cacheOpStats{buckets: make([]int64, len(cacheLatencyBuckets)+1)})
+	return stats.(*cacheOpStats)
+}
+
+// record 记录耗时
+func (s *cacheOpStats) record(duration time.Duration) {
+	nanos := duration.Nanoseconds()
+	atomic.AddInt64(&s.count, 1)
+	atomic.AddInt64(&s.totalTime, nanos)
+
+	for {
+		current := atomic.LoadInt64(&s.maxTime)
+		if nanos <= current || atomic.CompareAndSwapInt64(&s.maxTime, current, nanos) {
+			break
+		}
+	}
+
+	ms := float64(nanos) / 1e6
+	bucket := len(cacheLatencyBuckets)
+	for i, limit := range cacheLatencyBuckets {
+		if ms <= limit {
+			bucket = i
+			break
+		}
+	}
+	atomic.AddInt64(&s.buckets[bucket], 1)
+}
+
+// GetStats 获取统计信息：{种类: {后端类型: {操作: 统计}}}，直方图为累计计数（le_ms）
+func (m *CacheMetrics) GetStats() map[string]any {
+	result := make(map[string]any)
+	m.ops.Range(func(k, v any) bool {
+		key := k.(cacheOpKey)
+		stats := v.(*cacheOpStats)
+
+		backends, ok := result[key.kind].(map[string]any)
+		if !ok {
+			backends = make(map[string]any)
+			result[key.kind] = backends
+		}
+		ops, ok := backends[key.backend].(map[string]any)
+		if !ok {
+			ops = make(map[string]any)
+			backends[key.backend] = ops
+		}
+
+		count := atomic.LoadInt64(&stats.count)
+		var avg float64
+		if count > 0 {
+			avg = float64(atomic.LoadInt64(&stats.totalTime)) / float64(count) / 1e6
+		}
+
+		histogram := make(map[string]int64, len(stats.buckets))
+		var cumulative int64
+		for i, limit := range cacheLatencyBuckets {
+			cumulative += atomic.LoadInt64(&stats.buckets[i])
+			histogram[strconv.FormatFloat(limit, 'f', -1, 64)] = cumulative
+		}
+		histogram["+Inf"] = cumulative + atomic.LoadInt64(&stats.buckets[len(cacheLatencyBuckets)])
+
+		opStats := map[string]any{
+			"count":         count,
+			"errors":        atomic.LoadInt64(&stats.errors),
+			"avg_time_ms":   avg,
+			"max_time_ms":   float64(atomic.LoadInt64(&stats.maxTime)) / 1e6,
+			"latency_le_ms": histogram,
+		}
+		if key.op == "get" {
+			opStats["misses"] = atomic.LoadInt64(&stats.misses)
+		}
+		ops[key.op] = opStats
+		return true
+	})
+	return result
+}
+
+// Reset 重置统计信息
+func (m *CacheMetrics) Reset() {
+	m.ops.Range(func(k, _ any) bool {
+		m.ops.Delete(k)
+		return true
+	})
+}
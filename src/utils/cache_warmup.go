@@ -81,6 +81,14 @@ func warmupAPIMetadata(cfg *config.Config, store storage.Storage) error {
 	}
 
 	for _, host := range commonHosts {
+		cacheHost, ok := APIMetadataCacheHost(cfg, host)
+		if !ok {
+			continue
+		}
+		if cacheHost != "" {
+			host = cacheHost
+		}
+
 		// 构建链接
 		links := make(map[string]string)
 		for key := range cfg.Yggdrasil.Meta.Links {
@@ -127,8 +135,10 @@ func warmupAPIMetadata(cfg *config.Config, store storage.Storage) error {
 
 		// 序列化并缓存
 		if jsonData, err := FastMarshal(metadata); err == nil {
-			cacheKey := "api_metadata_" + host
-			SetCachedResponse(cacheKey, jsonData)
+			SetCachedMetadataResponse(cacheHost, jsonData)
+		}
+		if cacheHost == "" {
+			break // 元数据与Host无关，只需缓存一份
 		}
 	}
 
@@ -159,14 +169,8 @@ func GetCacheStats() map[string]any {
 	stats["performance"] = GlobalMetrics.GetStats()
 
 	// 响应缓存统计
-	responseCount := 0
-	responseCache.Range(func(key, value any) bool {
-		responseCount++
-		return true
-	})
-	stats["response_cache"] = map[string]any{
-		"cached_responses": responseCount,
-	}
+	stats["response_cache"] = responseCache.Stats()
+	stats["metadata_cache"] = metadataCache.Stats()
 
 	// 错误响应缓存统计
 	stats["error_cache"] = map[string]any{
@@ -188,7 +192,13 @@ func PrintCacheStats() {
 	}
 
 	if respStats, ok := stats["response_cache"].(map[string]any); ok {
-		fmt.Printf("  Response Cache: %d cached responses\n", respStats["cached_responses"])
+		fmt.Printf("  Response Cache: %d/%d cached responses, hits=%d, misses=%d, evictions=%d\n",
+			respStats["cached_responses"], respStats["max_entries"],
+			respStats["hits"], respStats["misses"], respStats["evictions"])
+	}
+
+	if metaStats, ok := stats["metadata_cache"].(map[string]any); ok {
+		fmt.Printf("  API Metadata Cache: %d cached hosts\n", metaStats["cached_responses"])
 	}
 
 	if errStats, ok := stats["error_cache"].(map[string]any); ok {
@@ -1,10 +1,13 @@
 package utils
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
 	"crypto/x509"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/pem"
 	"fmt"
@@ -25,26 +28,48 @@ func SetJWTSecret(secret string) {
 	jwtSecret = []byte(secret)
 }
 
+// JWTSecretFingerprint 获取JWT密钥指纹（用于识别共用同一密钥的实例，不泄露密钥本身）
+func JWTSecretFingerprint() string {
+	sum := sha256.Sum256(jwtSecret)
+	return hex.EncodeToString(sum[:8])
+}
+
 // JWTClaims JWT声明
+// 令牌分两个阶段：yggv之前有效；yggv到exp之间暂时失效（validate/join失败，但仍可refresh）
 type JWTClaims struct {
-	UserID    string `json:"sub"`  // 用户ID
-	ProfileID string `json:"spr"`  // 选中的角色ID（可选）
-	TokenID   string `json:"yggt"` // 令牌ID
+	UserID     string           `json:"sub"`            // 用户ID
+	ProfileID  string           `json:"spr"`            // 选中的角色ID（可选）
+	TokenID    string           `json:"yggt"`           // 令牌ID
+	ValidUntil *jwt.NumericDate `json:"yggv,omitempty"` // 有效期限（缺省时与exp相同）
+	Policy     string           `json:"yggp,omitempty"` // 签发时使用的令牌策略（刷新时沿用）
 	jwt.RegisteredClaims
 }
 
-// GenerateJWT 生成JWT令牌
-func GenerateJWT(userID, profileID string, expiration time.Duration) (string, error) {
+// IsValid 检查令牌是否处于有效阶段（exp已由ValidateJWT校验）
+func (c *JWTClaims) IsValid() bool {
+	return c.ValidUntil == nil || time.Now().Before(c.ValidUntil.Time)
+}
+
+// GenerateJWT 生成JWT令牌（expiration为有效期，refreshExpiration为可刷新期限，即exp）
+func GenerateJWT(userID, profileID string, expiration, refreshExpiration time.Duration) (string, error) {
+	return GenerateJWTWithPolicy(userID, profileID, "", expiration, refreshExpiration)
+}
+
+// GenerateJWTWithPolicy 生成JWT令牌并记录签发时使用的令牌策略
+func GenerateJWTWithPolicy(userID, profileID, policy string, expiration, refreshExpiration time.Duration) (string, error) {
 	now := time.Now()
+	refreshExpiration = max(refreshExpiration, expiration)
 	claims := JWTClaims{
-		UserID:    userID,
-		ProfileID: profileID,
-		TokenID:   GenerateRandomUUID(),
+		UserID:     userID,
+		ProfileID:  profileID,
+		TokenID:    GenerateRandomUUID(),
+		ValidUntil: jwt.NewNumericDate(now.Add(expiration)),
+		Policy:     policy,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    "Yggdrasil-Auth",
 			Subject:   userID,
 			IssuedAt:  jwt.NewNumericDate(now),
-			ExpiresAt: jwt.NewNumericDate(now.Add(expiration)),
+			ExpiresAt: jwt.NewNumericDate(now.Add(refreshExpiration)),
 		},
 	}
 
@@ -84,6 +109,48 @@ func CheckPassword(password, hash string) bool {
 	return err == nil
 }
 
+// EncryptSecret 使用AES-256-GCM加密敏感数据（如TOTP密钥），context作为附加数据绑定到密文（如用户ID）
+func EncryptSecret(key []byte, plaintext, context string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), []byte(context))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptSecret 解密EncryptSecret加密的数据（context必须与加密时一致）
+func DecryptSecret(key []byte, ciphertext, context string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("invalid ciphertext")
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], []byte(context))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// newGCM 创建AES-GCM加密器（key为32字节）
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
 // GenerateRSAKeyPair 生成RSA密钥对
 func GenerateRSAKeyPair() (string, string, error) {
 	// 生成4096位RSA私钥
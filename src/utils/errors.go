@@ -23,6 +23,7 @@ const (
 	MsgInvalidToken           = "Invalid token."
 	MsgInvalidCredentials     = "Invalid credentials. Invalid username or password."
 	MsgTokenAlreadyHasProfile = "Access token already has a profile assigned."
+	MsgInvalidSelectedProfile = "Invalid selected profile. Profile id and name must match."
 	MsgPlayerNotExisted       = "Player not existed."
 	MsgUserNotExisted         = "User not existed."
 	MsgUserBanned             = "User has been banned."
@@ -31,6 +32,23 @@ const (
 	MsgUnsupportedMediaType   = "Unsupported Media Type"
 	MsgContentTypeRequired    = "Content-Type must be application/json"
 	MsgRateLimitExceeded      = "Rate limit exceeded. Please try again later."
+	MsgProfileNotOwned        = "Profile does not belong to user."
+	MsgInvalidPlayerName      = "Invalid player name."
+	MsgPlayerNameExists       = "Player name already exists."
+	MsgRenameDisabled         = "Profile rename is disabled."
+	MsgRenameCooldown         = "Profile was renamed recently. Please try again later."
+	MsgInvalidUserProperty    = "Invalid user property value."
+	MsgUserPropertyNotAllowed = "User property cannot be modified."
+
+	MsgTwoFactorRequired           = "Two-factor authentication code required. Append the code to your password, e.g. password:123456."
+	MsgInvalidTwoFactorCode        = "Invalid two-factor authentication code."
+	MsgTwoFactorEnrollmentRequired = "Two-factor authentication is required for this account. Please enroll first."
+	MsgTwoFactorNotEnrolled        = "Two-factor authentication is not enabled."
+	MsgTwoFactorAlreadyEnabled     = "Two-factor authentication is already enabled."
+	MsgTwoFactorDisableNotAllowed  = "Two-factor authentication cannot be disabled for this account."
+
+	MsgAccountLocked      = "Too many failed login attempts. Please try again later."
+	MsgPermissionRequired = "Administrator permission required."
 )
 
 // RespondError 返回错误响应
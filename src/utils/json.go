@@ -10,8 +10,6 @@ import (
 
 // 预编译的常用响应缓存
 var (
-	responseCache = sync.Map{}
-
 	// 预序列化的API元数据（在启动时设置）
 	cachedAPIMetadata []byte
 
@@ -53,19 +51,6 @@ func RespondJSONFast(c *gin.Context, data any) {
 	}
 }
 
-// GetCachedResponse 获取缓存的响应
-func GetCachedResponse(key string) ([]byte, bool) {
-	if cached, ok := responseCache.Load(key); ok {
-		return cached.([]byte), true
-	}
-	return nil, false
-}
-
-// SetCachedResponse 设置缓存的响应
-func SetCachedResponse(key string, data []byte) {
-	responseCache.Store(key, data)
-}
-
 // GetCachedAPIMetadata 获取缓存的API元数据
 func GetCachedAPIMetadata() []byte {
 	return cachedAPIMetadata
@@ -135,6 +135,7 @@ func (m *PerformanceMetrics) GetStats() map[string]any {
 		"cache_hit_rate":       cacheHitRate,
 		"cache_hits":           cacheHits,
 		"cache_misses":         cacheMisses,
+		"cache_backends":       GlobalCacheMetrics.GetStats(),
 		"memory": map[string]any{
 			"alloc_mb":       float64(memStats.Alloc) / 1024 / 1024,
 			"total_alloc_mb": float64(memStats.TotalAlloc) / 1024 / 1024,
@@ -155,6 +156,7 @@ func (m *PerformanceMetrics) ResetStats() {
 	atomic.StoreInt64(&m.TotalDBTime, 0)
 	atomic.StoreInt64(&m.CacheHits, 0)
 	atomic.StoreInt64(&m.CacheMisses, 0)
+	GlobalCacheMetrics.Reset()
 	m.StartTime = time.Now()
 }
 
@@ -0,0 +1,321 @@
+// Package utils 响应缓存（LRU + TTL，按标签失效）
+package utils
+
+import (
+	"container/list"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"yggdrasil-api-go/src/config"
+)
+
+// 响应缓存默认配置（与DefaultConfig一致，InitResponseCache之前使用）
+const (
+	defaultResponseCacheSize     = 1000
+	defaultResponseCacheDuration = 10 * time.Minute
+	maxMetadataHosts             = 16 // 按Host区分的API元数据最多缓存的Host数
+)
+
+// ResponseCache 序列化后的响应缓存
+// 超出容量时淘汰最久未使用的条目，条目在ttl后过期；写入时可以附加标签，按标签批量失效
+type ResponseCache struct {
+	entries    map[string]*list.Element
+	tags       map[string]map[string]struct{} // 标签 -> 缓存键
+	lru        *list.List                     // 最近使用的在前
+	maxEntries int
+	ttl        time.Duration
+	mu         sync.Mutex
+
+	hits      atomic.Int64
+	misses    atomic.Int64
+	evictions atomic.Int64
+}
+
+// responseEntry 响应缓存条目
+type responseEntry struct {
+	key       string
+	data      []byte
+	tags      []string
+	expiresAt time.Time
+}
+
+// NewResponseCache 创建响应缓存（maxEntries<=0时不缓存）
+func NewResponseCache(maxEntries int, ttl time.Duration) *ResponseCache {
+	return &ResponseCache{
+		entries:    make(map[string]*list.Element),
+		tags:       make(map[string]map[string]struct{}),
+		lru:        list.New(),
+		maxEntries: maxEntries,
+		ttl:        ttl,
+	}
+}
+
+// Get 获取响应（过期的条目视为未命中并移除）
+func (rc *ResponseCache) Get(key string) ([]byte, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	element, exists := rc.entries[key]
+	if !exists {
+		rc.misses.Add(1)
+		return nil, false
+	}
+	entry := element.Value.(*responseEntry)
+	if time.Now().After(entry.expiresAt) {
+		rc.remove(element)
+		rc.misses.Add(1)
+		return nil, false
+	}
+
+	rc.lru.MoveToFront(element)
+	rc.hits.Add(1)
+	return entry.data, true
+}
+
+// Set 缓存响应，tags用于之后按标签失效（如角色UUID）
+func (rc *ResponseCache) Set(key string, data []byte, tags ...string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if rc.maxEntries <= 0 || rc.ttl <= 0 {
+		return
+	}
+	if element, exists := rc.entries[key]; exists {
+		rc.remove(element)
+	}
+
+	entry := &responseEntry{key: key, data: data, tags: tags, expiresAt: time.Now().Add(rc.ttl)}
+	rc.entries[key] = rc.lru.PushFront(entry)
+	for _, tag := range tags {
+		keys, exists := rc.tags[tag]
+		if !exists {
+			keys = make(map[string]struct{})
+			rc.tags[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+
+	for rc.lru.Len() > rc.maxEntries {
+		rc.remove(rc.lru.Back())
+		rc.evictions.Add(1)
+	}
+}
+
+// Invalidate 移除带有任一指定标签的条目
+func (rc *ResponseCache) Invalidate(tags ...string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	for _, tag := range tags {
+		for key := range rc.tags[tag] {
+			if element, exists := rc.entries[key]; exists {
+				rc.remove(element)
+			}
+		}
+	}
+}
+
+// Clear 清空缓存
+func (rc *ResponseCache) Clear() {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.entries = make(map[string]*list.Element)
+	rc.tags = make(map[string]map[string]struct{})
+	rc.lru.Init()
+}
+
+// Configure 修改容量和有效期（缩小容量时立即淘汰多余的条目）
+func (rc *ResponseCache) Configure(maxEntries int, ttl time.Duration) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.maxEntries = maxEntries
+	rc.ttl = ttl
+	for rc.lru.Len() > max(rc.maxEntries, 0) {
+		rc.remove(rc.lru.Back())
+	}
+}
+
+// Len 获取条目数量
+func (rc *ResponseCache) Len() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.lru.Len()
+}
+
+// Stats 获取统计信息
+func (rc *ResponseCache) Stats() map[string]any {
+	rc.mu.Lock()
+	entries, maxEntries, ttl := rc.lru.Len(), rc.maxEntries, rc.ttl
+	rc.mu.Unlock()
+
+	return map[string]any{
+		"cached_responses": entries,
+		"max_entries":      maxEntries,
+		"ttl_seconds":      ttl.Seconds(),
+		"hits":             rc.hits.Load(),
+		"misses":           rc.misses.Load(),
+		"evictions":        rc.evictions.Load(),
+	}
+}
+
+// remove 移除条目及其标签索引（调用方需持有锁）
+func (rc *ResponseCache) remove(element *list.Element) {
+	entry := element.Value.(*responseEntry)
+	rc.lru.Remove(element)
+	delete(rc.entries, entry.key)
+	for _, tag := range entry.tags {
+		if keys, exists := rc.tags[tag]; exists {
+			delete(keys, entry.key)
+			if len(keys) == 0 {
+				delete(rc.tags, tag)
+			}
+		}
+	}
+}
+
+// 全局响应缓存
+var (
+	responseCache = NewResponseCache(defaultResponseCacheSize, defaultResponseCacheDuration)
+	// metadataCache 按Host区分的API元数据（单独限制数量，任意Host头不会挤占其他响应）
+	metadataCache = NewResponseCache(maxMetadataHosts, defaultResponseCacheDuration)
+
+	responseCacheConfig = config.ResponseCacheConfig{
+		Enabled:          true,
+		APIMetadata:      true,
+		ErrorResponses:   true,
+		ProfileResponses: true,
+	}
+	responseCacheConfigMu sync.RWMutex
+)
+
+// InitResponseCache 按配置初始化响应缓存（未启用时所有响应都不缓存，容量或有效期未设置时使用默认值）
+func InitResponseCache(cfg config.ResponseCacheConfig) {
+	responseCacheConfigMu.Lock()
+	responseCacheConfig = cfg
+	responseCacheConfigMu.Unlock()
+
+	maxEntries, ttl := cfg.MaxCacheSize, cfg.CacheDuration
+	if maxEntries == 0 {
+		maxEntries = defaultResponseCacheSize
+	}
+	if ttl == 0 {
+		ttl = defaultResponseCacheDuration
+	}
+	if !cfg.Enabled {
+		maxEntries = 0
+	}
+	responseCache.Configure(maxEntries, ttl)
+	metadataCache.Configure(min(maxEntries, maxMetadataHosts), ttl)
+	ClearCachedResponses()
+}
+
+// currentResponseCacheConfig 获取当前响应缓存配置
+func currentResponseCacheConfig() config.ResponseCacheConfig {
+	responseCacheConfigMu.RLock()
+	defer responseCacheConfigMu.RUnlock()
+	return responseCacheConfig
+}
+
+// GetCachedResponse 获取缓存的响应
+func GetCachedResponse(key string) ([]byte, bool) {
+	return responseCache.Get(key)
+}
+
+// SetCachedResponse 设置缓存的响应（tags用于按标签失效）
+func SetCachedResponse(key string, data []byte, tags ...string) {
+	responseCache.Set(key, data, tags...)
+}
+
+// InvalidateCachedResponses 使带有指定标签的响应失效
+func InvalidateCachedResponses(tags ...string) {
+	responseCache.Invalidate(tags...)
+}
+
+// ClearCachedResponses 清空响应缓存（数据源变更后调用）
+func ClearCachedResponses() {
+	responseCache.Clear()
+	metadataCache.Clear()
+}
+
+// ProfileResponsesCached 角色响应是否可以缓存
+func ProfileResponsesCached() bool {
+	cfg := currentResponseCacheConfig()
+	return cfg.Enabled && cfg.ProfileResponses
+}
+
+// ProfileResponseTag 角色响应的失效标签（材质或名称变更时使用）
+func ProfileResponseTag(profileID string) string {
+	return "profile:" + strings.ToLower(RemoveUUIDHyphens(profileID))
+}
+
+// ProfileNameResponseTag 按角色名查询的响应的失效标签
+func ProfileNameResponseTag(name string) string {
+	return "name:" + strings.ToLower(name)
+}
+
+// APIMetadataCacheHost 获取API元数据缓存所用的Host
+// 所有链接都已配置时元数据与Host无关，返回空字符串；否则返回规范化的Host，Host格式无效时不应缓存（ok为false）
+func APIMetadataCacheHost(cfg *config.Config, host string) (cacheHost string, ok bool) {
+	if !cfg.Cache.Response.Enabled || !cfg.Cache.Response.APIMetadata {
+		return "", false
+	}
+	if linksConfigured(cfg) {
+		return "", true
+	}
+	return normalizeHost(host)
+}
+
+// linksConfigured 元数据中的链接是否都已配置（未配置的链接根据Host生成）
+func linksConfigured(cfg *config.Config) bool {
+	links := cfg.Yggdrasil.Meta.Links
+	if links["homepage"] == "" || links["register"] == "" {
+		return false
+	}
+	for _, url := range links {
+		if url == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// GetCachedMetadataResponse 获取缓存的API元数据（host为APIMetadataCacheHost的结果）
+func GetCachedMetadataResponse(host string) ([]byte, bool) {
+	return metadataCache.Get(host)
+}
+
+// SetCachedMetadataResponse 缓存API元数据（host为APIMetadataCacheHost的结果）
+func SetCachedMetadataResponse(host string, data []byte) {
+	metadataCache.Set(host, data)
+}
+
+// normalizeHost 规范化Host头（小写，只接受主机名或IP加可选端口）
+func normalizeHost(host string) (string, bool) {
+	host = strings.ToLower(host)
+	if host == "" || len(host) > 255 {
+		return "", false
+	}
+
+	name := host
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		if port == "" || len(port) > 5 || strings.Trim(port, "0123456789") != "" {
+			return "", false
+		}
+		name = h
+	}
+	if net.ParseIP(strings.Trim(name, "[]")) != nil {
+		return host, true
+	}
+
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 || strings.Trim(label, "abcdefghijklmnopqrstuvwxyz0123456789-") != "" {
+			return "", false
+		}
+	}
+	return host, true
+}
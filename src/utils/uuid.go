@@ -54,3 +54,12 @@ func FormatUUID(uuidStr string) string {
 func RemoveUUIDHyphens(uuidStr string) string {
 	return strings.ReplaceAll(uuidStr, "-", "")
 }
+
+// GenerateProfileUUIDWithAlgorithm 按指定算法生成角色UUID
+// v3: 离线模式兼容（基于角色名），v4: 随机生成
+func GenerateProfileUUIDWithAlgorithm(playerName, algorithm string) string {
+	if algorithm == "v4" {
+		return GenerateRandomUUID()
+	}
+	return GenerateProfileUUID(playerName)
+}
@@ -22,6 +22,9 @@ var (
 
 	// 材质类型验证
 	textureTypeRegex = regexp.MustCompile(`^(skin|cape)$`)
+
+	// 语言代码验证（如en、zh_CN、zh-Hant-TW）
+	localeRegex = regexp.MustCompile(`^[a-zA-Z]{2,3}([_-][a-zA-Z0-9]{2,8})*$`)
 )
 
 // IsValidEmail 验证邮箱格式
@@ -117,3 +120,8 @@ func BatchValidatePlayerNames(names []string) bool {
 
 	return true
 }
+
+// IsValidLocale 验证语言代码格式
+func IsValidLocale(locale string) bool {
+	return len(locale) <= 35 && localeRegex.MatchString(locale)
+}
@@ -3,17 +3,48 @@ package yggdrasil
 
 import (
 	"encoding/base64"
+	"errors"
+	"sync/atomic"
 	"time"
 
 	"github.com/bytedance/sonic"
 )
 
+// 缓存查询未命中（不存在、已过期或令牌无效）时返回的错误，用于与后端故障区分
+var (
+	ErrTokenNotFound   = errors.New("token not found in cache")
+	ErrSessionNotFound = errors.New("session not found")
+)
+
 // User 用户模型
 type User struct {
-	ID       string    `json:"id"`       // 用户UUID
-	Email    string    `json:"email"`    // 邮箱
-	Password string    `json:"-"`        // 密码（不序列化）
-	Profiles []Profile `json:"profiles"` // 用户拥有的角色列表
+	ID         string    `json:"id"`       // 用户UUID
+	Email      string    `json:"email"`    // 邮箱
+	Password   string    `json:"-"`        // 密码（不序列化）
+	Profiles   []Profile `json:"profiles"` // 用户拥有的角色列表
+	Permission int       `json:"-"`        // 权限（与BlessingSkin一致：-1封禁，0普通用户，1管理员，2超级管理员）
+}
+
+// 账户角色（由权限换算，用于令牌策略）
+const (
+	RoleBanned     = "banned"
+	RoleNormal     = "normal"
+	RoleAdmin      = "admin"
+	RoleSuperAdmin = "super_admin"
+)
+
+// Role 获取账户角色
+func (u *User) Role() string {
+	switch {
+	case u.Permission < 0:
+		return RoleBanned
+	case u.Permission == 1:
+		return RoleAdmin
+	case u.Permission >= 2:
+		return RoleSuperAdmin
+	default:
+		return RoleNormal
+	}
 }
 
 // Profile 角色模型
@@ -23,6 +54,29 @@ type Profile struct {
 	Properties []ProfileProperty `json:"properties"` // 角色属性
 }
 
+// NameHistory 角色名称历史记录（Mojang格式）
+type NameHistory struct {
+	Name        string `json:"name"`                  // 角色名称
+	ChangedToAt int64  `json:"changedToAt,omitempty"` // 改为该名称的时间（毫秒时间戳，初始名称为空）
+}
+
+// NameAt 返回指定时间点角色使用的名称（history需按时间升序排列）
+// since为角色确定已存在的最早时间（零值表示不限制），早于since的时间点返回空
+func NameAt(history []NameHistory, since, at time.Time) string {
+	if !since.IsZero() && at.Before(since) {
+		return ""
+	}
+
+	name := ""
+	for _, entry := range history {
+		if entry.ChangedToAt > at.UnixMilli() {
+			break
+		}
+		name = entry.Name
+	}
+	return name
+}
+
 // ProfileProperty 角色属性
 type ProfileProperty struct {
 	Name      string `json:"name"`                // 属性名称
@@ -32,19 +86,33 @@ type ProfileProperty struct {
 
 // Token 令牌模型
 type Token struct {
-	AccessToken string    `json:"accessToken"` // 访问令牌
-	ClientToken string    `json:"clientToken"` // 客户端令牌
-	ProfileID   string    `json:"profileId"`   // 绑定的角色ID
-	Owner       string    `json:"owner"`       // 令牌所有者（用户ID）
-	CreatedAt   time.Time `json:"createdAt"`   // 创建时间
-	ExpiresAt   time.Time `json:"expiresAt"`   // 过期时间
+	AccessToken      string    `json:"accessToken"`      // 访问令牌
+	ClientToken      string    `json:"clientToken"`      // 客户端令牌
+	ProfileID        string    `json:"profileId"`        // 绑定的角色ID
+	Owner            string    `json:"owner"`            // 令牌所有者（用户ID）
+	CreatedAt        time.Time `json:"createdAt"`        // 创建时间
+	ExpiresAt        time.Time `json:"expiresAt"`        // 过期时间（之后令牌暂时失效，仍可刷新）
+	RefreshExpiresAt time.Time `json:"refreshExpiresAt"` // 刷新期限（之后令牌彻底失效，零值表示与ExpiresAt相同）
 }
 
-// IsValid 检查令牌是否有效
+// IsValid 检查令牌是否有效（可用于validate、join等操作）
 func (t *Token) IsValid() bool {
 	return time.Now().Before(t.ExpiresAt)
 }
 
+// IsRefreshable 检查令牌是否仍可刷新（有效或暂时失效）
+func (t *Token) IsRefreshable() bool {
+	return time.Now().Before(t.RefreshDeadline())
+}
+
+// RefreshDeadline 获取令牌彻底失效的时间（缓存应保留令牌至此时）
+func (t *Token) RefreshDeadline() time.Time {
+	if t.RefreshExpiresAt.After(t.ExpiresAt) {
+		return t.RefreshExpiresAt
+	}
+	return t.ExpiresAt
+}
+
 // AuthenticateRequest 登录请求
 type AuthenticateRequest struct {
 	Username    string `json:"username" binding:"required"` // 用户名/邮箱
@@ -75,6 +143,49 @@ type UserInfo struct {
 	Properties []ProfileProperty `json:"properties"` // 用户属性
 }
 
+// 用户属性名称
+const (
+	UserPropertyPreferredLanguage = "preferredLanguage" // 首选语言（如zh_CN）
+)
+
+// UpdateUserPropertiesRequest 更新用户属性请求（属性名到属性值，值为空时删除该属性）
+type UpdateUserPropertiesRequest struct {
+	Properties map[string]string `json:"properties" binding:"required"`
+}
+
+// TwoFactorRequest 两步验证管理请求（已启用两步验证时密码需附加验证码，如password:123456）
+type TwoFactorRequest struct {
+	Username string `json:"username" binding:"required"` // 用户名/邮箱
+	Password string `json:"password" binding:"required"` // 密码
+}
+
+// TwoFactorConfirmRequest 确认登记两步验证请求
+type TwoFactorConfirmRequest struct {
+	Username string `json:"username" binding:"required"` // 用户名/邮箱
+	Password string `json:"password" binding:"required"` // 密码
+	Code     string `json:"code"`                        // 验证器应用生成的验证码（也可附加在密码后）
+}
+
+// TwoFactorEnrollResponse 登记两步验证响应
+type TwoFactorEnrollResponse struct {
+	Secret string `json:"secret"` // base32编码的TOTP密钥
+	URI    string `json:"uri"`    // otpauth链接（可转换为二维码）
+}
+
+// TwoFactorRecoveryCodesResponse 恢复码响应（恢复码只返回一次，服务端只保存哈希）
+type TwoFactorRecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// LockoutStatusResponse 账户登录失败锁定状态响应（管理员查询）
+type LockoutStatusResponse struct {
+	Key         string `json:"key"`                   // 失败记录的键（账户存在时为user:{用户ID}，邮箱和各个角色名共用）
+	Failures    int    `json:"failures"`              // 连续失败次数
+	LastFailure int64  `json:"lastFailure,omitempty"` // 最近一次失败时间（毫秒时间戳）
+	Locked      bool   `json:"locked"`                // 是否处于锁定状态
+	LockedUntil int64  `json:"lockedUntil,omitempty"` // 锁定截止时间（毫秒时间戳）
+}
+
 // RefreshRequest 刷新令牌请求
 type RefreshRequest struct {
 	AccessToken     string   `json:"accessToken" binding:"required"` // 访问令牌
@@ -91,6 +202,11 @@ type RefreshResponse struct {
 	User            *UserInfo `json:"user,omitempty"`            // 用户信息（可选）
 }
 
+// RenameProfileRequest 角色改名请求
+type RenameProfileRequest struct {
+	Name string `json:"name" binding:"required"` // 新角色名称
+}
+
 // ValidateRequest 验证令牌请求
 type ValidateRequest struct {
 	AccessToken string `json:"accessToken" binding:"required"` // 访问令牌
@@ -118,16 +234,58 @@ type JoinRequest struct {
 
 // Session 会话信息
 type Session struct {
-	ServerID    string    `json:"serverId"`    // 服务器ID
-	AccessToken string    `json:"accessToken"` // 访问令牌
-	ProfileID   string    `json:"profileId"`   // 角色ID
-	ClientIP    string    `json:"clientIp"`    // 客户端IP
-	CreatedAt   time.Time `json:"createdAt"`   // 创建时间
+	ServerID    string    `json:"serverId"`            // 服务器ID
+	AccessToken string    `json:"accessToken"`         // 访问令牌
+	TokenID     string    `json:"tokenId,omitempty"`   // 令牌ID（JWT.yggt，非本服务签发的令牌为空）
+	ProfileID   string    `json:"profileId"`           // 角色ID
+	ClientIP    string    `json:"clientIp"`            // 客户端IP
+	UserAgent   string    `json:"userAgent,omitempty"` // 客户端User-Agent（启动器）
+	CreatedAt   time.Time `json:"createdAt"`           // 创建时间
 }
 
-// IsValid 检查会话是否有效（30秒内）
+// DefaultSessionTTL 默认会话有效期（自创建起30秒，与Yggdrasil标准一致）
+const DefaultSessionTTL = 30 * time.Second
+
+// MaxUserAgentLength 会话中保存的User-Agent最大长度（字节）
+const MaxUserAgentLength = 255
+
+// sessionTTL 当前会话有效期（纳秒，由SetSessionTTL设置）
+var sessionTTL atomic.Int64
+
+func init() {
+	sessionTTL.Store(int64(DefaultSessionTTL))
+}
+
+// SetSessionTTL 设置会话有效期（所有Session缓存后端和hasJoined检查共用，ttl<=0时恢复默认值）
+func SetSessionTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = DefaultSessionTTL
+	}
+	sessionTTL.Store(int64(ttl))
+}
+
+// SessionTTL 获取会话有效期
+func SessionTTL() time.Duration {
+	return time.Duration(sessionTTL.Load())
+}
+
+// IsValid 检查会话是否有效（创建后SessionTTL内）
 func (s *Session) IsValid() bool {
-	return time.Since(s.CreatedAt) < 30*time.Second
+	return time.Since(s.CreatedAt) < SessionTTL()
+}
+
+// ExpiresAt 获取会话过期时间（未设置创建时间时从当前时间起算）
+func (s *Session) ExpiresAt() time.Time {
+	if s.CreatedAt.IsZero() {
+		return time.Now().Add(SessionTTL())
+	}
+	return s.CreatedAt.Add(SessionTTL())
+}
+
+// LoginAttempts 账户的登录失败记录（按规范化的用户名记录，用于暴力破解防护）
+type LoginAttempts struct {
+	Failures    int       `json:"failures"`    // 连续失败次数
+	LastFailure time.Time `json:"lastFailure"` // 最近一次失败时间
 }
 
 // APIMetadata API元数据
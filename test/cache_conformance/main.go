@@ -0,0 +1,38 @@
+// 缓存后端一致性测试 - 使用本地替身（miniredis、SQLite）对所有内置缓存后端运行 cachetest 套件
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"yggdrasil-api-go/src/cache/cachetest"
+)
+
+func main() {
+	dir, err := os.MkdirTemp("", "yggdrasil-cachetest-")
+	if err != nil {
+		fmt.Printf("❌ 创建临时目录失败: %v\n", err)
+		os.Exit(1)
+	}
+	defer os.RemoveAll(dir)
+
+	suites, cleanup, err := standInSuites(dir)
+	if err != nil {
+		fmt.Printf("❌ 启动本地替身失败: %v\n", err)
+		os.Exit(1)
+	}
+	defer cleanup()
+
+	failed := 0
+	for _, suite := range suites {
+		fmt.Printf("\n🧪 %s\n", suite.Name)
+		failed += cachetest.RunStandalone(suite, os.Stdout)
+	}
+
+	if failed > 0 {
+		fmt.Printf("\n❌ %d 个用例失败\n", failed)
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+	fmt.Println("\n✅ 所有缓存后端通过一致性测试")
+}
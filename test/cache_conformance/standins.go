@@ -0,0 +1,339 @@
+// 内置缓存后端的本地替身（miniredis代替Redis，SQLite代替MySQL）
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"yggdrasil-api-go/src/cache"
+	"yggdrasil-api-go/src/cache/bolt"
+	"yggdrasil-api-go/src/cache/cachetest"
+	"yggdrasil-api-go/src/cache/database"
+	"yggdrasil-api-go/src/cache/file"
+	"yggdrasil-api-go/src/cache/memory"
+	"yggdrasil-api-go/src/cache/redis"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// memorySuite 内存缓存
+func memorySuite() *cachetest.Suite {
+	return &cachetest.Suite{
+		Name: "memory",
+		NewTokenCache: func() (cache.TokenCache, error) {
+			return memory.NewTokenCache(nil)
+		},
+		NewSessionCache: func() (cache.SessionCache, error) {
+			return memory.NewSessionCache(nil)
+		},
+		NewLoginAttemptCache: func() (cache.LoginAttemptCache, error) {
+			return memory.NewLoginAttemptCache(nil)
+		},
+	}
+}
+
+// redisSuite Redis缓存（使用miniredis，每个用例前清空数据），返回的函数用于关闭miniredis
+func redisSuite() (*cachetest.Suite, func(), error) {
+	server, err := miniredis.Run()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	options := map[string]any{"redis_url": "redis://" + server.Addr()}
+	suite := &cachetest.Suite{
+		Name: "redis",
+		NewTokenCache: func() (cache.TokenCache, error) {
+			server.FlushAll()
+			return redis.NewTokenCache(options)
+		},
+		NewSessionCache: func() (cache.SessionCache, error) {
+			server.FlushAll()
+			return redis.NewSessionCache(options)
+		},
+		NewLoginAttemptCache: func() (cache.LoginAttemptCache, error) {
+			server.FlushAll()
+			return redis.NewLoginAttemptCache(options)
+		},
+		// miniredis的TTL不会随真实时间减少
+		FastForward: server.FastForward,
+		NewPeerTokenCache: func() (cache.TokenCache, error) {
+			return redis.NewTokenCache(options)
+		},
+	}
+
+	return suite, server.Close, nil
+}
+
+// redisSharedSuite 通过缓存工厂共用命名连接的Redis缓存（使用键名前缀和Cluster键名布局，miniredis不支持Cluster）
+func redisSharedSuite() (*cachetest.Suite, func(), error) {
+	server, err := miniredis.Run()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	factory := cache.NewCacheFactory()
+	factory.RegisterRedisConnection("shared", map[string]any{
+		"redis_url":  "redis://" + server.Addr(),
+		"hash_tags":  true,
+		"key_prefix": "cachetest:",
+	})
+
+	options := map[string]any{"connection": "shared"}
+	suite := &cachetest.Suite{
+		Name: "redis-shared",
+		NewTokenCache: func() (cache.TokenCache, error) {
+			server.FlushAll()
+			return factory.CreateTokenCache("redis", options)
+		},
+		NewSessionCache: func() (cache.SessionCache, error) {
+			server.FlushAll()
+			return factory.CreateSessionCache("redis", options)
+		},
+		NewLoginAttemptCache: func() (cache.LoginAttemptCache, error) {
+			server.FlushAll()
+			return factory.CreateLoginAttemptCache("redis", options)
+		},
+		FastForward: server.FastForward,
+		NewPeerTokenCache: func() (cache.TokenCache, error) {
+			return factory.CreateTokenCache("redis", options)
+		},
+	}
+
+	cleanup := func() {
+		factory.Close()
+		server.Close()
+	}
+	return suite, cleanup, nil
+}
+
+// fileSuite 文件缓存（每个用例使用dir下的新目录）
+func fileSuite(dir string) *cachetest.Suite {
+	return newFileSuite("file", dir, nil)
+}
+
+// cappedFileSuite 设置了容量限制的文件缓存（限制足够大，用例不会触发淘汰，用于覆盖索引的维护）
+func cappedFileSuite(dir string) *cachetest.Suite {
+	return newFileSuite("file-capped", dir, map[string]any{"max_entries": 10000, "max_bytes": 64 * 1024 * 1024})
+}
+
+// newFileSuite 文件缓存（extra为附加选项）
+func newFileSuite(name, dir string, extra map[string]any) *cachetest.Suite {
+	options := func(cacheDir string) map[string]any {
+		options := map[string]any{"cache_dir": cacheDir}
+		for k, v := range extra {
+			options[k] = v
+		}
+		return options
+	}
+
+	return &cachetest.Suite{
+		Name: name,
+		NewTokenCache: func() (cache.TokenCache, error) {
+			cacheDir, err := os.MkdirTemp(dir, "file-token-")
+			if err != nil {
+				return nil, err
+			}
+			return file.NewTokenCache(options(cacheDir))
+		},
+		NewSessionCache: func() (cache.SessionCache, error) {
+			cacheDir, err := os.MkdirTemp(dir, "file-session-")
+			if err != nil {
+				return nil, err
+			}
+			return file.NewSessionCache(options(cacheDir))
+		},
+		NewLoginAttemptCache: func() (cache.LoginAttemptCache, error) {
+			cacheDir, err := os.MkdirTemp(dir, "file-login-")
+			if err != nil {
+				return nil, err
+			}
+			return file.NewLoginAttemptCache(options(cacheDir))
+		},
+	}
+}
+
+// databaseSuite 数据库缓存（使用SQLite，每个用例使用dir下的新数据库文件）
+func databaseSuite(dir string) *cachetest.Suite {
+	var tokenDSN string
+	return &cachetest.Suite{
+		Name: "database",
+		NewTokenCache: func() (cache.TokenCache, error) {
+			dsn, err := sqliteDSN(dir)
+			if err != nil {
+				return nil, err
+			}
+			tokenDSN = dsn
+			return database.NewTokenCache(map[string]any{"dsn": dsn})
+		},
+		NewPeerTokenCache: func() (cache.TokenCache, error) {
+			return database.NewTokenCache(map[string]any{"dsn": tokenDSN})
+		},
+		NewSessionCache: func() (cache.SessionCache, error) {
+			dsn, err := sqliteDSN(dir)
+			if err != nil {
+				return nil, err
+			}
+			return database.NewSessionCache(map[string]any{"dsn": dsn})
+		},
+		NewLoginAttemptCache: func() (cache.LoginAttemptCache, error) {
+			dsn, err := sqliteDSN(dir)
+			if err != nil {
+				return nil, err
+			}
+			return database.NewLoginAttemptCache(map[string]any{"dsn": dsn})
+		},
+	}
+}
+
+// databaseSharedSuite 通过缓存工厂共用命名连接的数据库缓存（SQLite，清理批大小为2以覆盖分批删除）
+func databaseSharedSuite(dir string) (*cachetest.Suite, func(), error) {
+	dsn, err := sqliteDSN(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	factory := cache.NewCacheFactory()
+	factory.RegisterDatabaseConnection("shared", map[string]any{
+		"driver":         "sqlite",
+		"dsn":            dsn,
+		"max_open_conns": 4,
+	})
+
+	// 每个用例使用新的表前缀，共用同一个连接
+	var tablePrefix string
+	cases := 0
+	options := func(newCase bool) map[string]any {
+		if newCase {
+			cases++
+			tablePrefix = fmt.Sprintf("case%d_", cases)
+		}
+		return map[string]any{"connection": "shared", "table_prefix": tablePrefix, "cleanup_batch_size": 2}
+	}
+
+	suite := &cachetest.Suite{
+		Name: "database-shared",
+		NewTokenCache: func() (cache.TokenCache, error) {
+			return factory.CreateTokenCache("database", options(true))
+		},
+		NewPeerTokenCache: func() (cache.TokenCache, error) {
+			return factory.CreateTokenCache("database", options(false))
+		},
+		NewSessionCache: func() (cache.SessionCache, error) {
+			return factory.CreateSessionCache("database", options(true))
+		},
+		NewLoginAttemptCache: func() (cache.LoginAttemptCache, error) {
+			return factory.CreateLoginAttemptCache("database", options(true))
+		},
+	}
+	return suite, func() { factory.Close() }, nil
+}
+
+// boltSuite bbolt缓存（每个用例使用dir下的新数据库文件）
+func boltSuite(dir string) *cachetest.Suite {
+	newPath := func() (string, error) {
+		dbDir, err := os.MkdirTemp(dir, "bolt-")
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dbDir, "cache.db"), nil
+	}
+
+	return &cachetest.Suite{
+		Name: "bolt",
+		NewTokenCache: func() (cache.TokenCache, error) {
+			path, err := newPath()
+			if err != nil {
+				return nil, err
+			}
+			return bolt.NewTokenCache(map[string]any{"path": path})
+		},
+		NewSessionCache: func() (cache.SessionCache, error) {
+			path, err := newPath()
+			if err != nil {
+				return nil, err
+			}
+			return bolt.NewSessionCache(map[string]any{"path": path})
+		},
+		NewLoginAttemptCache: func() (cache.LoginAttemptCache, error) {
+			path, err := newPath()
+			if err != nil {
+				return nil, err
+			}
+			return bolt.NewLoginAttemptCache(map[string]any{"path": path})
+		},
+	}
+}
+
+// tieredSuite 在suite的后端前增加进程内L1缓存（每个实例各自持有L1，peer用于验证跨实例失效）
+func tieredSuite(suite *cachetest.Suite) *cachetest.Suite {
+	options := map[string]any{"l1_size": 100, "l1_ttl": "1m"}
+	wrap := func(newCache func() (cache.TokenCache, error)) func() (cache.TokenCache, error) {
+		if newCache == nil {
+			return nil
+		}
+		return func() (cache.TokenCache, error) {
+			l2, err := newCache()
+			if err != nil {
+				return nil, err
+			}
+			return cache.NewTieredTokenCache(l2, options)
+		}
+	}
+
+	return &cachetest.Suite{
+		Name:              suite.Name + "+l1",
+		NewTokenCache:     wrap(suite.NewTokenCache),
+		NewPeerTokenCache: wrap(suite.NewPeerTokenCache),
+		NewSessionCache:   suite.NewSessionCache,
+		FastForward:       suite.FastForward,
+	}
+}
+
+// standInSuites 所有内置后端（使用本地替身），返回的函数用于释放资源
+func standInSuites(dir string) ([]*cachetest.Suite, func(), error) {
+	plainSuite, closeRedis, err := redisSuite()
+	if err != nil {
+		return nil, nil, err
+	}
+	sharedSuite, closeShared, err := redisSharedSuite()
+	if err != nil {
+		closeRedis()
+		return nil, nil, err
+	}
+	databaseSharedSuite, closeDatabaseShared, err := databaseSharedSuite(dir)
+	if err != nil {
+		closeRedis()
+		closeShared()
+		return nil, nil, err
+	}
+
+	suites := []*cachetest.Suite{
+		memorySuite(),
+		plainSuite,
+		sharedSuite,
+		fileSuite(dir),
+		cappedFileSuite(dir),
+		databaseSuite(dir),
+		databaseSharedSuite,
+		boltSuite(dir),
+		tieredSuite(memorySuite()),
+		tieredSuite(plainSuite),
+		tieredSuite(databaseSuite(dir)),
+	}
+	cleanup := func() {
+		closeRedis()
+		closeShared()
+		closeDatabaseShared()
+	}
+	return suites, cleanup, nil
+}
+
+// sqliteDSN 在dir下创建新的SQLite数据库文件路径
+func sqliteDSN(dir string) (string, error) {
+	dbDir, err := os.MkdirTemp(dir, "database-")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dbDir, "cache.db"), nil
+}
@@ -0,0 +1,114 @@
+// 角色名称历史回归测试 - 改名后旧名称被新角色使用时，按时间点查询应返回当时使用该名称的角色
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"yggdrasil-api-go/src/storage/file"
+	"yggdrasil-api-go/src/yggdrasil"
+
+	"github.com/bytedance/sonic"
+)
+
+const (
+	uuidA = "0a000000000000000000000000000001" // 原名Steve，改名为Alex
+	uuidB = "0b000000000000000000000000000002" // 改名之后注册为Steve
+)
+
+func main() {
+	dir, err := os.MkdirTemp("", "yggdrasil-namehistory-")
+	if err != nil {
+		fmt.Printf("❌ 创建临时目录失败: %v\n", err)
+		os.Exit(1)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Now().Truncate(time.Second)
+	createdA := now.Add(-72 * time.Hour)
+	renamedAt := now.Add(-48 * time.Hour)
+	createdB := now.Add(-24 * time.Hour)
+
+	if err := writeFixtures(dir, createdA, renamedAt, createdB); err != nil {
+		fmt.Printf("❌ 写入测试数据失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	store, err := file.NewStorage(map[string]any{"data_dir": dir}, nil)
+	if err != nil {
+		fmt.Printf("❌ 创建文件存储失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	cases := []struct {
+		name string
+		at   time.Time
+		want string // 空表示应查询不到
+	}{
+		{"Steve", renamedAt.Add(-time.Hour), uuidA},
+		{"Steve", createdB.Add(-time.Hour), ""},
+		{"Steve", now, uuidB},
+		{"Alex", renamedAt.Add(-time.Hour), ""},
+		{"Alex", now, uuidA},
+		{"steve", renamedAt.Add(-time.Hour), uuidA},
+	}
+
+	failed := 0
+	for _, c := range cases {
+		// 角色保存在map中，多次查询以暴露依赖遍历顺序的结果
+		for range 20 {
+			got := ""
+			if profile, err := store.GetProfileByNameAt(c.name, c.at); err == nil {
+				got = profile.ID
+			}
+			if got != c.want {
+				failed++
+				fmt.Printf("❌ %s at %s: got %q, want %q\n", c.name, c.at.Format(time.DateTime), got, c.want)
+				break
+			}
+		}
+	}
+
+	// NameAt：早于since的时间点没有名称
+	history := []yggdrasil.NameHistory{{Name: "Steve"}}
+	if name := yggdrasil.NameAt(history, createdB, createdB.Add(-time.Hour)); name != "" {
+		failed++
+		fmt.Printf("❌ NameAt before since: got %q, want \"\"\n", name)
+	}
+	if name := yggdrasil.NameAt(history, createdB, now); name != "Steve" {
+		failed++
+		fmt.Printf("❌ NameAt after since: got %q, want \"Steve\"\n", name)
+	}
+
+	if failed > 0 {
+		fmt.Printf("\n❌ %d 个用例失败\n", failed)
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+	fmt.Println("✅ 角色名称历史回归测试通过")
+}
+
+// writeFixtures 写入角色和名称历史：A在renamedAt从Steve改名为Alex，B在createdB注册为Steve
+func writeFixtures(dir string, createdA, renamedAt, createdB time.Time) error {
+	players := []map[string]any{
+		{"pid": 1, "uid": 1, "name": "Alex", "uuid": uuidA, "tid_skin": 0, "tid_cape": 0, "last_modified": renamedAt.Format(time.DateTime)},
+		{"pid": 2, "uid": 1, "name": "Steve", "uuid": uuidB, "tid_skin": 0, "tid_cape": 0, "last_modified": createdB.Format(time.DateTime)},
+	}
+	history := []map[string]any{
+		{"uuid": uuidA, "name": "Steve", "changed_to_at": 0},
+		{"uuid": uuidA, "name": "Alex", "changed_to_at": renamedAt.UnixMilli()},
+	}
+
+	for name, value := range map[string]any{"players.json": players, "name_history.json": history} {
+		data, err := sonic.Marshal(value)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}